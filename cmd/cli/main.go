@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/nanochip/multi-agent/pkg/api"
+	"github.com/nanochip/multi-agent/pkg/events"
 	"github.com/nanochip/multi-agent/pkg/orchestrator"
 	"github.com/nanochip/multi-agent/pkg/policies"
+	"github.com/nanochip/multi-agent/pkg/store"
 	"github.com/nanochip/multi-agent/pkg/types"
 	"github.com/nanochip/multi-agent/pkg/workspace"
 )
@@ -16,16 +25,63 @@ import (
 func main() {
 	planCmd := flag.NewFlagSet("plan", flag.ExitOnError)
 	planObj := planCmd.String("objective", "", "Objective to plan")
+	planRepo := planCmd.String("repo", ".", "Path to git repository")
+	planWait := planCmd.Bool("wait", true, "Wait until the whole run finishes")
+	planPriority := planCmd.String("priority", "normal", "Run priority: urgent, high, normal or low")
+	planTimeout := planCmd.Duration("timeout", 30*time.Minute, "Maximum time to wait for the run (0 = no limit)")
 
 	statusCmd := flag.NewFlagSet("status", flag.ExitOnError)
 	taskID := statusCmd.String("task", "", "Task ID to check")
+	statusRepo := statusCmd.String("repo", ".", "Path to git repository")
 
-	repoPath := flag.String("repo", ".", "Path to git repository")
+	explainCmd := flag.NewFlagSet("explain", flag.ExitOnError)
+	explainRepo := explainCmd.String("repo", ".", "Path to git repository")
+
+	historyCmd := flag.NewFlagSet("history", flag.ExitOnError)
+	historyRepo := historyCmd.String("repo", ".", "Path to git repository")
+
+	graphCmd := flag.NewFlagSet("graph", flag.ExitOnError)
+	graphRun := graphCmd.String("run", "", "Run ID to render (default: all tasks)")
+	graphFormat := graphCmd.String("format", "dot", "Output format: dot or mermaid")
+	graphRepo := graphCmd.String("repo", ".", "Path to git repository")
+
+	runsCmd := flag.NewFlagSet("runs", flag.ExitOnError)
+	runsRepo := runsCmd.String("repo", ".", "Path to git repository")
+
+	logsCmd := flag.NewFlagSet("logs", flag.ExitOnError)
+	logsRepo := logsCmd.String("repo", ".", "Path to git repository")
+	logsFollow := logsCmd.Bool("follow", true, "Keep streaming events until the run finishes")
+
+	controlCmd := flag.NewFlagSet("control", flag.ExitOnError)
+	controlRepo := controlCmd.String("repo", ".", "Path to git repository")
+
+	decisionCmd := flag.NewFlagSet("approve", flag.ExitOnError)
+	decisionRepo := decisionCmd.String("repo", ".", "Path to git repository")
+	decisionComment := decisionCmd.String("comment", "", "Why the change is approved or rejected (required to reject)")
+
+	deadCmd := flag.NewFlagSet("deadletter", flag.ExitOnError)
+	deadRepo := deadCmd.String("repo", ".", "Path to git repository")
+
+	scheduleCmd := flag.NewFlagSet("schedule", flag.ExitOnError)
+	scheduleRepo := scheduleCmd.String("repo", ".", "Path to git repository")
 
 	if len(os.Args) < 2 {
 		fmt.Println("Usage:")
-		fmt.Println("  plan  - Create a plan for an objective")
-		fmt.Println("  status - Check task status")
+		fmt.Println("  plan    - Submit an objective to the daemon and wait for its run")
+		fmt.Println("  status  - Check task status")
+		fmt.Println("  explain - Show the policy verdicts of a task")
+		fmt.Println("  history - List recorded tasks")
+		fmt.Println("  graph   - Render the task DAG (DOT/Mermaid)")
+		fmt.Println("  runs    - List runs (runs list) or show one (runs show <id>)")
+		fmt.Println("  logs    - Show a run's tasks and stream its events (needs the daemon)")
+		fmt.Println("  cancel  - Cancel a task (and its descendants) or a whole run")
+		fmt.Println("  pause   - Stop starting new tasks of a task or run")
+		fmt.Println("  resume  - Resume a paused task or run")
+		fmt.Println("  approve - Approve the high-risk changes a run is waiting on")
+		fmt.Println("  reject  - Reject the high-risk changes a run is waiting on")
+		fmt.Println("  deadletter - List, inspect, requeue or discard tasks that failed for good")
+		fmt.Println("  schedule   - Add, list or remove recurring (cron) objectives")
+		fmt.Println("  policy     - Test a policy file against fixtures (policy test)")
 		os.Exit(1)
 	}
 
@@ -35,14 +91,79 @@ func main() {
 		if *planObj == "" {
 			log.Fatal("--objective is required")
 		}
-		handlePlan(*repoPath, *planObj)
+		if types.Priority(*planPriority).Rank() < 0 {
+			log.Fatalf("unknown priority %q (expected urgent, high, normal or low)", *planPriority)
+		}
+		handlePlan(*planRepo, *planObj, types.Priority(*planPriority), *planWait, *planTimeout)
 
 	case "status":
 		statusCmd.Parse(os.Args[2:])
 		if *taskID == "" {
 			log.Fatal("--task is required")
 		}
-		handleStatus(*repoPath, *taskID)
+		handleStatus(*statusRepo, *taskID)
+
+	case "explain":
+		explainCmd.Parse(os.Args[2:])
+		if explainCmd.NArg() != 1 {
+			log.Fatal("usage: explain [--repo path] <task-id>")
+		}
+		handleExplain(*explainRepo, explainCmd.Arg(0))
+
+	case "history":
+		historyCmd.Parse(os.Args[2:])
+		handleHistory(*historyRepo)
+
+	case "graph":
+		graphCmd.Parse(os.Args[2:])
+		handleGraph(*graphRepo, *graphRun, *graphFormat)
+
+	case "runs":
+		runsCmd.Parse(os.Args[2:])
+		args := runsCmd.Args()
+		switch {
+		case len(args) == 1 && args[0] == "list":
+			handleRunsList(*runsRepo)
+		case len(args) == 2 && args[0] == "show":
+			handleRunsShow(*runsRepo, args[1])
+		default:
+			log.Fatal("usage: runs [--repo path] list | show <run-id>")
+		}
+
+	case "logs":
+		logsCmd.Parse(os.Args[2:])
+		if logsCmd.NArg() != 1 {
+			log.Fatal("usage: logs [--repo path] [--follow=false] <run-id>")
+		}
+		handleLogs(*logsRepo, logsCmd.Arg(0), *logsFollow)
+
+	case "cancel", "pause", "resume":
+		controlCmd.Parse(os.Args[2:])
+		if controlCmd.NArg() != 1 {
+			log.Fatalf("usage: %s [--repo path] <task-id|run-id>", os.Args[1])
+		}
+		handleControl(*controlRepo, os.Args[1], controlCmd.Arg(0))
+
+	case "approve", "reject":
+		decisionCmd.Parse(os.Args[2:])
+		if decisionCmd.NArg() != 1 {
+			log.Fatalf("usage: %s [--repo path] [--comment text] <run-id|task-id>", os.Args[1])
+		}
+		if os.Args[1] == "reject" && strings.TrimSpace(*decisionComment) == "" {
+			log.Fatal("--comment is required to reject")
+		}
+		handleDecision(*decisionRepo, os.Args[1], decisionCmd.Arg(0), *decisionComment)
+
+	case "deadletter":
+		deadCmd.Parse(os.Args[2:])
+		handleDeadLetter(*deadRepo, deadCmd.Args())
+
+	case "schedule":
+		scheduleCmd.Parse(os.Args[2:])
+		handleSchedule(*scheduleRepo, scheduleCmd.Args())
+
+	case "policy":
+		handlePolicy(os.Args[2:])
 
 	default:
 		fmt.Printf("Unknown command: %s\n", os.Args[1])
@@ -50,45 +171,84 @@ func main() {
 	}
 }
 
-func handlePlan(repoPath, objective string) {
+// newOrchestrator crea un orchestrator respaldado por el store del repositorio
+func newOrchestrator(repoPath string) *orchestrator.Orchestrator {
 	ws, err := workspace.NewManager(repoPath)
 	if err != nil {
 		log.Fatalf("Failed to create workspace manager: %v", err)
 	}
 
+	st, err := store.Open(repoPath)
+	if err != nil {
+		log.Fatalf("Failed to open task store: %v", err)
+	}
+
 	policy := policies.NewEngine()
-	orch := orchestrator.New(ws, policy)
-	orch.Start()
+	return orchestrator.NewWithStore(ws, policy, st)
+}
 
-	task := &types.Task{
-		Type:        types.TaskPlan,
-		Objective:   objective,
-		Inputs:      make(map[string]interface{}),
-		Constraints: make(map[string]interface{}),
-		MaxRetries:  3,
-	}
+func handlePlan(repoPath, objective string, priority types.Priority, wait bool, timeout time.Duration) {
+	client := requireDaemon(repoPath)
 
-	if err := orch.SubmitTask(task); err != nil {
+	ctx := context.Background()
+	submitted, err := client.Submit(ctx, api.SubmitRequest{Objective: objective, Priority: priority})
+	if err != nil {
 		log.Fatalf("Failed to submit task: %v", err)
 	}
 
-	fmt.Printf("Plan task submitted: %s\n", task.ID)
+	fmt.Printf("Plan task submitted: %s\n", submitted.TaskID)
+	fmt.Printf("Run: %s\n", submitted.RunID)
 	fmt.Printf("Objective: %s\n", objective)
 
-	// Esperar un poco para que se procese
-	// En producción, usarías un mecanismo de espera más robusto
-}
+	if !wait {
+		return
+	}
 
-func handleStatus(repoPath, taskID string) {
-	ws, err := workspace.NewManager(repoPath)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	result, err := client.Follow(ctx, submitted.RunID, func(event events.Event) error {
+		if event.Type == events.ApprovalRequired {
+			fmt.Printf("\nTask %s is waiting for approval: %s\n", event.TaskID, event.Message)
+			fmt.Printf("Approve or reject it with: cli approve|reject --comment \"...\" %s\n", submitted.RunID)
+		}
+		return nil
+	})
 	if err != nil {
-		log.Fatalf("Failed to create workspace manager: %v", err)
+		log.Fatalf("Failed waiting for run %s: %v", submitted.RunID, err)
 	}
 
-	policy := policies.NewEngine()
-	orch := orchestrator.New(ws, policy)
+	fmt.Printf("\nRun %s finished: %s\n", result.Run.ID, result.Run.State)
+	if result.Run.Reason != "" {
+		fmt.Printf("Reason: %s\n", result.Run.Reason)
+	}
+	for _, t := range result.Tasks {
+		line := fmt.Sprintf("  %s [%s] %s", t.ID, t.Type, t.State)
+		if r := result.Results[t.ID]; r != nil && r.Error != "" {
+			line += ": " + r.Error
+		}
+		fmt.Println(line)
+	}
+	if !result.Success() {
+		os.Exit(1)
+	}
+}
 
-	task, result := orch.GetTaskState(taskID)
+func handleStatus(repoPath, taskID string) {
+	var task *types.Task
+	var result *types.TaskResult
+	if client := daemonClient(repoPath); client != nil {
+		resp, err := client.Task(context.Background(), taskID)
+		if err != nil {
+			log.Fatalf("Failed to get task %s: %v", taskID, err)
+		}
+		task, result = resp.Task, resp.Result
+	} else {
+		task, result = newOrchestrator(repoPath).GetTaskState(taskID)
+	}
 	if task == nil {
 		log.Fatalf("Task %s not found", taskID)
 	}
@@ -113,3 +273,622 @@ func handleStatus(repoPath, taskID string) {
 		}
 	}
 }
+
+func handleExplain(repoPath, taskID string) {
+	var task *types.Task
+	var result *types.TaskResult
+	if client := daemonClient(repoPath); client != nil {
+		resp, err := client.Task(context.Background(), taskID)
+		if err != nil {
+			log.Fatalf("Failed to get task %s: %v", taskID, err)
+		}
+		task, result = resp.Task, resp.Result
+	} else {
+		task, result = newOrchestrator(repoPath).GetTaskState(taskID)
+	}
+	if task == nil {
+		log.Fatalf("Task %s not found", taskID)
+	}
+
+	fmt.Printf("Task %s [%s] %s: %s\n", task.ID, task.Type, task.State, task.Objective)
+	verdicts := policies.VerdictsFromResult(result)
+	if len(verdicts) == 0 {
+		// Las tareas sin terminar aún no tienen resultado, y las anteriores
+		// a los veredictos no los guardan
+		fmt.Println("No policy verdicts recorded for this task")
+		return
+	}
+	for _, verdict := range verdicts {
+		fmt.Println()
+		printVerdict(verdict, "")
+	}
+}
+
+// printVerdict muestra un veredicto con todas sus comprobaciones
+func printVerdict(verdict *policies.Verdict, indent string) {
+	decision := "allowed"
+	if !verdict.Allowed {
+		decision = "denied"
+	}
+	switch verdict.Phase {
+	case policies.PhaseTask:
+		fmt.Printf("%sBefore running (agent %s): %s\n", indent, verdict.Agent, decision)
+	case policies.PhaseResult:
+		fmt.Printf("%sResult (agent %s): %s\n", indent, verdict.Agent, decision)
+	default:
+		fmt.Printf("%s%s (agent %s): %s\n", indent, verdict.Phase, verdict.Agent, decision)
+	}
+	if len(verdict.Checks) == 0 {
+		fmt.Printf("%s  no policies loaded\n", indent)
+	}
+	for _, check := range verdict.Checks {
+		name := check.Name()
+		if check.Kind == policies.CheckGate {
+			name = check.ID
+		}
+		fmt.Printf("%s  %-4s  %-10s %-32s %s\n", indent, check.Outcome, check.Kind, name, check.Reason)
+	}
+}
+
+const policyUsage = "usage: policy test --policy file [--verbose] <fixture file or directory>..."
+
+func handlePolicy(args []string) {
+	if len(args) == 0 {
+		log.Fatal(policyUsage)
+	}
+
+	sub := flag.NewFlagSet("policy "+args[0], flag.ExitOnError)
+	policyPath := sub.String("policy", "", "Policy file to test (YAML, see policies.example.yaml)")
+	verbose := sub.Bool("verbose", false, "Show the verdicts of every fixture, not only of the failing ones")
+	sub.Parse(args[1:])
+
+	switch {
+	case args[0] == "test" && sub.NArg() > 0:
+		if *policyPath == "" {
+			log.Fatal("--policy is required")
+		}
+		handlePolicyTest(*policyPath, sub.Args(), *verbose)
+	default:
+		log.Fatal(policyUsage)
+	}
+}
+
+func handlePolicyTest(policyPath string, paths []string, verbose bool) {
+	engine, err := policies.LoadFile(policyPath)
+	if err != nil {
+		log.Fatalf("Failed to load policies: %v", err)
+	}
+	files, err := fixtureFiles(paths)
+	if err != nil {
+		log.Fatalf("Failed to find fixtures: %v", err)
+	}
+
+	total, failed := 0, 0
+	for _, file := range files {
+		fixtures, err := policies.LoadFixtures(file)
+		if err != nil {
+			log.Fatalf("Failed to load fixtures: %v", err)
+		}
+		for _, fixture := range fixtures {
+			total++
+			result := engine.TestFixture(fixture)
+			name := fmt.Sprintf("%s:%d", fixture.File, fixture.Line)
+			if fixture.Name != "" && result.Passed() {
+				name = fixture.Name
+			} else if fixture.Name != "" {
+				name = fmt.Sprintf("%s (%s)", fixture.Name, name)
+			}
+			if result.Passed() {
+				fmt.Printf("ok    %s (%s)\n", name, result.Got)
+			} else {
+				failed++
+				fmt.Printf("FAIL  %s: %s\n", name, result.Mismatch)
+			}
+			if verbose || !result.Passed() {
+				for _, verdict := range result.Verdicts {
+					printVerdict(verdict, "      ")
+				}
+			}
+		}
+	}
+
+	fmt.Printf("\n%d fixture(s), %d failed\n", total, failed)
+	if total == 0 {
+		log.Fatalf("No fixtures found in %s", strings.Join(paths, ", "))
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// fixtureFiles retorna los archivos indicados y los .yaml, .yml y .json de
+// los directorios indicados (recursivamente, en orden alfabético)
+func fixtureFiles(paths []string) ([]string, error) {
+	files := make([]string, 0)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			switch filepath.Ext(file) {
+			case ".yaml", ".yml", ".json":
+				if !entry.IsDir() {
+					files = append(files, file)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func handleHistory(repoPath string) {
+	orch := newOrchestrator(repoPath)
+
+	tasks, err := orch.ListTasks()
+	if err != nil {
+		log.Fatalf("Failed to list tasks: %v", err)
+	}
+
+	if len(tasks) == 0 {
+		fmt.Println("No tasks recorded")
+		return
+	}
+
+	for _, task := range tasks {
+		fmt.Printf("%-26s %-9s %-10s %s  %s\n",
+			task.ID, task.Type, task.State, task.CreatedAt.Format("2006-01-02 15:04:05"), task.Objective)
+	}
+}
+
+func handleGraph(repoPath, runID, format string) {
+	orch := newOrchestrator(repoPath)
+
+	tasks, err := orch.ListTasks()
+	if err != nil {
+		log.Fatalf("Failed to list tasks: %v", err)
+	}
+
+	if runID != "" {
+		filtered := make([]*types.Task, 0)
+		for _, task := range tasks {
+			if task.RunID == runID {
+				filtered = append(filtered, task)
+			}
+		}
+		tasks = filtered
+	}
+
+	out, err := orchestrator.RenderGraph(tasks, format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(out)
+}
+
+func handleRunsList(repoPath string) {
+	orch := newOrchestrator(repoPath)
+
+	runs, err := orch.ListRuns()
+	if err != nil {
+		log.Fatalf("Failed to list runs: %v", err)
+	}
+
+	if len(runs) == 0 {
+		fmt.Println("No runs recorded")
+		return
+	}
+
+	for _, run := range runs {
+		fmt.Printf("%-26s %-10s %s  %s\n",
+			run.ID, run.State, run.StartedAt.Format("2006-01-02 15:04:05"), run.Objective)
+	}
+}
+
+func handleRunsShow(repoPath, runID string) {
+	orch := newOrchestrator(repoPath)
+
+	run, tasks, err := orch.GetRun(runID)
+	if err != nil {
+		log.Fatalf("Failed to load run: %v", err)
+	}
+
+	fmt.Printf("Run ID: %s\n", run.ID)
+	fmt.Printf("Objective: %s\n", run.Objective)
+	fmt.Printf("State: %s\n", run.State)
+	if run.Reason != "" {
+		fmt.Printf("Reason: %s\n", run.Reason)
+	}
+	fmt.Printf("Started: %s\n", run.StartedAt.Format("2006-01-02 15:04:05"))
+	if run.CompletedAt != nil {
+		fmt.Printf("Completed: %s (%v)\n",
+			run.CompletedAt.Format("2006-01-02 15:04:05"), run.CompletedAt.Sub(run.StartedAt).Round(time.Millisecond))
+	}
+	if len(run.Summary) > 0 {
+		fmt.Printf("Tasks:")
+		for _, state := range []types.TaskState{
+			types.StateSuccess, types.StateFailed, types.StateSkipped, types.StateCancelled, types.StateAbandoned,
+		} {
+			if n := run.Summary[state]; n > 0 {
+				fmt.Printf(" %d %s", n, state)
+			}
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("\nTask tree:\n")
+	for _, node := range orchestrator.TaskTree(tasks) {
+		printTaskNode(node, "  ")
+	}
+}
+
+func handleControl(repoPath, action, target string) {
+	// Sin daemon, la orden queda en el store para el proceso que ejecuta la tarea
+	var err error
+	if client := daemonClient(repoPath); client != nil {
+		err = client.Control(context.Background(), action, target)
+	} else {
+		err = newOrchestrator(repoPath).RequestControl(action, target)
+	}
+	if err != nil {
+		log.Fatalf("Failed to %s %s: %v", action, target, err)
+	}
+	fmt.Printf("Requested %s of %s\n", action, target)
+}
+
+func handleDecision(repoPath, action, target, comment string) {
+	// El daemon firma la decisión con el usuario que se conecta al socket. Sin
+	// daemon, queda en el store a nombre del usuario que ejecuta la CLI para el
+	// proceso que retiene el cambio.
+	var err error
+	approver := currentUser()
+	if client := daemonClient(repoPath); client != nil {
+		var resp *api.DecisionResponse
+		if resp, err = client.Decide(context.Background(), action, target, api.ApprovalRequest{Comment: comment}); err == nil {
+			approver = resp.Approver
+		}
+	} else {
+		err = newOrchestrator(repoPath).RequestDecision(action, target, approver, comment)
+	}
+	if err != nil {
+		log.Fatalf("Failed to %s %s: %v", action, target, err)
+	}
+	if action == orchestrator.ControlApprove {
+		fmt.Printf("Approved %s as %s\n", target, approver)
+	} else {
+		fmt.Printf("Rejected %s as %s\n", target, approver)
+	}
+}
+
+func handleLogs(repoPath, runID string, follow bool) {
+	client := requireDaemon(repoPath)
+	ctx := context.Background()
+
+	resp, err := client.Run(ctx, runID)
+	if err != nil {
+		log.Fatalf("Failed to get run %s: %v", runID, err)
+	}
+	fmt.Printf("Run %s (%s): %s\n", resp.Run.ID, resp.Run.State, resp.Run.Objective)
+	for _, task := range resp.Tasks {
+		fmt.Printf("%s  %-10s %s [%s] %s\n",
+			task.CreatedAt.Format("15:04:05"), task.State, task.ID, task.Type, task.Objective)
+	}
+	if !follow || resp.Run.CompletedAt != nil {
+		return
+	}
+
+	err = client.Events(ctx, runID, func(event events.Event) error {
+		line := fmt.Sprintf("%s  %-18s", event.Time.Format("15:04:05"), event.Type)
+		if event.TaskID != "" {
+			line += fmt.Sprintf(" %s [%s]", event.TaskID, event.TaskType)
+		}
+		if event.State != "" {
+			line += " " + string(event.State)
+		}
+		if event.Message != "" {
+			line += ": " + event.Message
+		}
+		fmt.Println(line)
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to follow run %s: %v", runID, err)
+	}
+}
+
+// daemonClient retorna un cliente del daemon del repositorio, o nil si no hay
+// ninguno en ejecución
+func daemonClient(repoPath string) *api.Client {
+	client, err := api.DialSocket(api.SocketPath(repoPath))
+	if err != nil {
+		return nil
+	}
+	return client
+}
+
+// currentUser retorna el nombre del usuario que ejecuta la CLI, o su UID si
+// no tiene nombre. Sale del proceso, no del entorno: $USER puede ser cualquiera.
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return fmt.Sprintf("uid:%d", os.Getuid())
+}
+
+// requireDaemon retorna un cliente del daemon del repositorio o termina
+func requireDaemon(repoPath string) *api.Client {
+	client := daemonClient(repoPath)
+	if client == nil {
+		log.Fatalf("No orchestrator daemon running for %s; start one with: orchestrator daemon --repo %s", repoPath, repoPath)
+	}
+	return client
+}
+
+const deadLetterUsage = "usage: deadletter [--repo path] list | show [--json] <task-id> | requeue [--input key=value ...] <task-id> | wontfix --comment text <task-id>"
+
+func handleDeadLetter(repoPath string, args []string) {
+	if len(args) == 0 {
+		log.Fatal(deadLetterUsage)
+	}
+
+	sub := flag.NewFlagSet("deadletter "+args[0], flag.ExitOnError)
+	asJSON := sub.Bool("json", false, "Print the whole entry as JSON (show)")
+	comment := sub.String("comment", "", "Why the task will not be fixed (wontfix)")
+	inputs := inputFlags{}
+	sub.Var(inputs, "input", "Input to override as key=value, repeatable; JSON values are decoded (requeue)")
+	sub.Parse(args[1:])
+
+	switch {
+	case args[0] == "list" && sub.NArg() == 0:
+		handleDeadLetterList(repoPath)
+	case args[0] == "show" && sub.NArg() == 1:
+		handleDeadLetterShow(repoPath, sub.Arg(0), *asJSON)
+	case args[0] == "requeue" && sub.NArg() == 1:
+		handleRequeue(repoPath, sub.Arg(0), inputs)
+	case args[0] == "wontfix" && sub.NArg() == 1:
+		if strings.TrimSpace(*comment) == "" {
+			log.Fatal("--comment is required")
+		}
+		handleWontFix(repoPath, sub.Arg(0), *comment)
+	default:
+		log.Fatal(deadLetterUsage)
+	}
+}
+
+func handleDeadLetterList(repoPath string) {
+	var letters []*types.DeadLetter
+	var err error
+	if client := daemonClient(repoPath); client != nil {
+		letters, err = client.DeadLetters(context.Background())
+	} else {
+		letters, err = newOrchestrator(repoPath).ListDeadLetters()
+	}
+	if err != nil {
+		log.Fatalf("Failed to list dead letters: %v", err)
+	}
+
+	if len(letters) == 0 {
+		fmt.Println("No dead letters")
+		return
+	}
+
+	for _, letter := range letters {
+		fmt.Printf("%-26s %-9s %-8s %s  %s\n",
+			letter.Task.ID, letter.Status, letter.Task.Type, letter.CreatedAt.Format("2006-01-02 15:04:05"), letter.Result.Error)
+	}
+}
+
+func handleDeadLetterShow(repoPath, taskID string, asJSON bool) {
+	var letter *types.DeadLetter
+	var err error
+	if client := daemonClient(repoPath); client != nil {
+		letter, err = client.DeadLetter(context.Background(), taskID)
+	} else {
+		letter, err = newOrchestrator(repoPath).GetDeadLetter(taskID)
+	}
+	if err != nil {
+		log.Fatalf("Failed to get dead letter %s: %v", taskID, err)
+	}
+
+	if asJSON {
+		out, _ := json.MarshalIndent(letter, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	task := letter.Task
+	fmt.Printf("Task ID: %s\n", task.ID)
+	fmt.Printf("Run ID: %s\n", task.RunID)
+	fmt.Printf("Type: %s\n", task.Type)
+	fmt.Printf("Objective: %s\n", task.Objective)
+	fmt.Printf("Attempts: %d\n", task.RetryCount+1)
+	fmt.Printf("Status: %s\n", letter.Status)
+	switch letter.Status {
+	case types.DeadLetterRequeued:
+		fmt.Printf("Requeued as: %s\n", letter.RequeuedAs)
+	case types.DeadLetterWontFix:
+		fmt.Printf("Comment: %s\n", letter.Comment)
+	}
+	fmt.Printf("Error: %s\n", letter.Result.Error)
+
+	if len(task.Inputs) > 0 {
+		fmt.Printf("\nInputs:\n")
+		inputJSON, _ := json.MarshalIndent(task.Inputs, "  ", "  ")
+		fmt.Printf("  %s\n", inputJSON)
+	}
+
+	if len(letter.Classifications) > 0 {
+		fmt.Printf("\nClassifications:\n")
+		for _, class := range letter.Classifications {
+			fmt.Printf("  %s (%s, %s)", class.Pattern, class.Category, class.Severity)
+			if class.Remediation != "" {
+				fmt.Printf(": %s", class.Remediation)
+			}
+			fmt.Println()
+		}
+	}
+
+	if len(letter.Decisions) > 0 {
+		fmt.Printf("\nDecisions:\n")
+		for _, decision := range letter.Decisions {
+			fmt.Printf("  %s  %s %s [%s]: %s\n",
+				decision.Timestamp.Format("15:04:05"), decision.TaskID, decision.Agent, decision.Action, decision.Reason)
+		}
+	}
+
+	if len(letter.Evidence) > 0 {
+		fmt.Printf("\nEvidence (use --json for the content):\n")
+		for _, evidence := range letter.Evidence {
+			fmt.Printf("  %-7s %s", evidence.Type, evidence.Source)
+			if evidence.Description != "" {
+				fmt.Printf(": %s", evidence.Description)
+			}
+			fmt.Println()
+		}
+	}
+}
+
+func handleRequeue(repoPath, taskID string, inputs inputFlags) {
+	// La nueva tarea se ejecuta en el daemon, que es quien mantiene las ejecuciones
+	client := requireDaemon(repoPath)
+	resp, err := client.Requeue(context.Background(), taskID, inputs)
+	if err != nil {
+		log.Fatalf("Failed to requeue %s: %v", taskID, err)
+	}
+	fmt.Printf("Requeued %s as %s in run %s\n", taskID, resp.TaskID, resp.RunID)
+}
+
+func handleWontFix(repoPath, taskID, comment string) {
+	var err error
+	if client := daemonClient(repoPath); client != nil {
+		_, err = client.WontFix(context.Background(), taskID, comment)
+	} else {
+		err = newOrchestrator(repoPath).WontFix(taskID, comment)
+	}
+	if err != nil {
+		log.Fatalf("Failed to mark %s as won't fix: %v", taskID, err)
+	}
+	fmt.Printf("Marked %s as won't fix\n", taskID)
+}
+
+const scheduleUsage = "usage: schedule [--repo path] add --cron expr --objective template [--id name] [--priority p] [--constraint key=value ...] | list | remove <id>"
+
+func handleSchedule(repoPath string, args []string) {
+	if len(args) == 0 {
+		log.Fatal(scheduleUsage)
+	}
+
+	sub := flag.NewFlagSet("schedule "+args[0], flag.ExitOnError)
+	id := sub.String("id", "", "Schedule ID (default: generated)")
+	expr := sub.String("cron", "", "Cron expression: minute hour day-of-month month day-of-week, or @daily, @weekly...")
+	objective := sub.String("objective", "", "Objective template; {{.Date}}, {{.Time}} and {{.Schedule}} are available")
+	priority := sub.String("priority", "normal", "Priority of the submitted runs: urgent, high, normal or low")
+	constraints := inputFlags{}
+	sub.Var(constraints, "constraint", "Constraint for the submitted runs as key=value, repeatable; JSON values are decoded")
+	sub.Parse(args[1:])
+
+	switch {
+	case args[0] == "add" && sub.NArg() == 0:
+		if *expr == "" || *objective == "" {
+			log.Fatal("--cron and --objective are required")
+		}
+		schedule := &types.Schedule{
+			ID:          *id,
+			Cron:        *expr,
+			Objective:   *objective,
+			Constraints: constraints,
+			Priority:    types.Priority(*priority),
+		}
+		handleScheduleAdd(repoPath, schedule)
+	case args[0] == "list" && sub.NArg() == 0:
+		handleScheduleList(repoPath)
+	case args[0] == "remove" && sub.NArg() == 1:
+		handleScheduleRemove(repoPath, sub.Arg(0))
+	default:
+		log.Fatal(scheduleUsage)
+	}
+}
+
+func handleScheduleAdd(repoPath string, schedule *types.Schedule) {
+	// El daemon lee los objetivos recurrentes del store en su siguiente revisión
+	if err := newOrchestrator(repoPath).AddSchedule(schedule); err != nil {
+		log.Fatalf("Failed to add schedule: %v", err)
+	}
+	next, _ := orchestrator.NextFire(schedule)
+	fmt.Printf("Added schedule %s (next run %s)\n", schedule.ID, next.Format("2006-01-02 15:04"))
+	if daemonClient(repoPath) == nil {
+		fmt.Println("No daemon is running; schedules fire only while `orchestrator daemon` runs")
+	}
+}
+
+func handleScheduleList(repoPath string) {
+	schedules, err := newOrchestrator(repoPath).ListSchedules()
+	if err != nil {
+		log.Fatalf("Failed to list schedules: %v", err)
+	}
+
+	if len(schedules) == 0 {
+		fmt.Println("No schedules")
+		return
+	}
+
+	for _, schedule := range schedules {
+		next := "never"
+		if t, err := orchestrator.NextFire(schedule); err == nil && !t.IsZero() {
+			next = t.Format("2006-01-02 15:04")
+		}
+		last := "-"
+		if schedule.LastRunID != "" {
+			last = schedule.LastRunID
+		}
+		fmt.Printf("%-24s %-16s next %-16s last %-26s skipped %d  %s\n",
+			schedule.ID, schedule.Cron, next, last, schedule.Skipped, schedule.Objective)
+	}
+}
+
+func handleScheduleRemove(repoPath, id string) {
+	if err := newOrchestrator(repoPath).RemoveSchedule(id); err != nil {
+		log.Fatalf("Failed to remove schedule: %v", err)
+	}
+	fmt.Printf("Removed schedule %s\n", id)
+}
+
+// inputFlags acumula flags key=value repetibles (--input, --constraint)
+type inputFlags map[string]interface{}
+
+func (f inputFlags) String() string {
+	return ""
+}
+
+func (f inputFlags) Set(value string) error {
+	key, raw, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+		decoded = raw
+	}
+	f[key] = decoded
+	return nil
+}
+
+// printTaskNode imprime un nodo del árbol de tareas con sus hijos indentados
+func printTaskNode(node *orchestrator.TaskNode, indent string) {
+	task := node.Task
+	fmt.Printf("%s%s [%s] %s: %s\n", indent, task.ID, task.Type, task.State, task.Objective)
+	for _, child := range node.Children {
+		printTaskNode(child, indent+"  ")
+	}
+}
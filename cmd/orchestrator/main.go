@@ -1,75 +1,490 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/nanochip/multi-agent/pkg/api"
+	"github.com/nanochip/multi-agent/pkg/events"
 	"github.com/nanochip/multi-agent/pkg/orchestrator"
+	"github.com/nanochip/multi-agent/pkg/pipeline"
 	"github.com/nanochip/multi-agent/pkg/policies"
+	"github.com/nanochip/multi-agent/pkg/store"
 	"github.com/nanochip/multi-agent/pkg/types"
 	"github.com/nanochip/multi-agent/pkg/workspace"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "resume" {
+		runResume(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		runServe(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "daemon" {
+		runDaemon(os.Args[2:])
+		return
+	}
+
 	taskObj := flag.String("task", "", "Task objective to execute")
-	repoPath := flag.String("repo", ".", "Path to git repository")
+	priority := flag.String("priority", "normal", "Task priority: urgent, high, normal or low")
+	recoverMode := flag.String("recover", "abandon", "Unfinished work from previous processes: abandon, resume or ignore")
+	dryRun := flag.Bool("dry-run", false, "Print the expected task graph and every command with its policy verdict, without executing anything")
+	opts := addCommonFlags(flag.CommandLine)
 	flag.Parse()
 
 	if *taskObj == "" {
 		log.Fatal("--task is required")
 	}
+	if types.Priority(*priority).Rank() < 0 {
+		log.Fatalf("unknown priority %q (expected urgent, high, normal or low)", *priority)
+	}
 
-	// Crear workspace manager
-	ws, err := workspace.NewManager(*repoPath)
+	mode, err := orchestrator.ParseRecoveryMode(*recoverMode)
 	if err != nil {
-		log.Fatalf("Failed to create workspace manager: %v", err)
+		log.Fatal(err)
 	}
 
-	// Crear policy engine
-	policy := policies.NewEngine()
+	// Tarea inicial
+	task := &types.Task{
+		Type:        types.TaskPlan,
+		Objective:   *taskObj,
+		Inputs:      make(map[string]interface{}),
+		Constraints: make(map[string]interface{}),
+		MaxRetries:  3,
+		Priority:    types.Priority(*priority),
+	}
 
-	// Configurar políticas por defecto para agentes
-	setupDefaultPolicies(policy)
+	opts.dryRun = *dryRun
+	ws, orch := setupOrchestrator(opts)
+	if *dryRun {
+		// El workspace es de solo lectura: no hay nada que limpiar
+		printDryRun(orch.DryRun(task))
+		return
+	}
 
-	// Crear orchestrator
-	orch := orchestrator.New(ws, policy)
+	// Procesar trabajo inconcluso de procesos anteriores
+	recovered, err := orch.Recover("", mode)
+	if err != nil {
+		log.Fatalf("Failed to recover unfinished tasks: %v", err)
+	}
+	if len(recovered) > 0 {
+		fmt.Printf("Recovered %d unfinished task(s) (%s)\n", len(recovered), mode)
+	}
 
 	// Iniciar orchestrator
 	if err := orch.Start(); err != nil {
 		log.Fatalf("Failed to start orchestrator: %v", err)
 	}
 
-	// Manejar señales para shutdown graceful
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
 	// Enviar tarea inicial
-	task := &types.Task{
-		Type:        types.TaskPlan,
-		Objective:   *taskObj,
-		Inputs:      make(map[string]interface{}),
-		Constraints: make(map[string]interface{}),
-		MaxRetries:  3,
-	}
-
 	if err := orch.SubmitTask(task); err != nil {
 		log.Fatalf("Failed to submit task: %v", err)
 	}
 
 	fmt.Printf("Task submitted: %s\n", task.ID)
+	fmt.Printf("Run: %s\n", task.RunID)
 	fmt.Printf("Objective: %s\n", *taskObj)
 
+	waitForShutdown(ws, orch)
+}
+
+// runResume re-encola las tareas inconclusas de una ejecución anterior
+func runResume(args []string) {
+	resumeCmd := flag.NewFlagSet("resume", flag.ExitOnError)
+	runID := resumeCmd.String("run", "", "Run ID to resume")
+	opts := addCommonFlags(resumeCmd)
+	resumeCmd.Parse(args)
+
+	if *runID == "" {
+		log.Fatal("--run is required")
+	}
+
+	ws, orch := setupOrchestrator(opts)
+
+	recovered, err := orch.Recover(*runID, orchestrator.RecoverResume)
+	if err != nil {
+		log.Fatalf("Failed to resume run %s: %v", *runID, err)
+	}
+	if len(recovered) == 0 {
+		fmt.Printf("Nothing to resume for run %s\n", *runID)
+		return
+	}
+
+	if err := orch.Start(); err != nil {
+		log.Fatalf("Failed to start orchestrator: %v", err)
+	}
+
+	fmt.Printf("Resumed run %s:\n", *runID)
+	for _, task := range recovered {
+		fmt.Printf("  %s (%s) %s\n", task.ID, task.Type, task.Objective)
+	}
+
+	waitForShutdown(ws, orch)
+}
+
+// runServe expone el orchestrator como API HTTP/JSON hasta recibir una señal
+func runServe(args []string) {
+	serveCmd := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := serveCmd.String("addr", "127.0.0.1:8080", "Address to listen on")
+	tokenFile := serveCmd.String("token-file", "", "File with the accepted API tokens, one \"<owner> <token>\" per line")
+	recoverMode := serveCmd.String("recover", "abandon", "Unfinished work from previous processes: abandon, resume or ignore")
+	schedules := serveCmd.Bool("schedules", false, "Submit scheduled objectives (leave it to the daemon if one runs on the same repository)")
+	opts := addCommonFlags(serveCmd)
+	serveCmd.Parse(args)
+
+	if *tokenFile == "" {
+		log.Fatal("--token-file is required")
+	}
+	tokens, err := api.LoadTokens(*tokenFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mode, err := orchestrator.ParseRecoveryMode(*recoverMode)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ws, orch := setupOrchestrator(opts)
+	if *schedules {
+		orch.EnableScheduler()
+	}
+	if _, err := orch.Recover("", mode); err != nil {
+		log.Fatalf("Failed to recover unfinished tasks: %v", err)
+	}
+	if err := orch.Start(); err != nil {
+		log.Fatalf("Failed to start orchestrator: %v", err)
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           api.NewServer(orch, tokens),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("API server failed: %v", err)
+		}
+	}()
+	fmt.Printf("Serving API on %s\n", *addr)
+
+	waitForShutdown(ws, orch, server)
+}
+
+// runDaemon deja el orchestrator escuchando en el socket del repositorio
+// (.multi-agent/orchestrator.sock) para que cmd/cli le envíe trabajo
+func runDaemon(args []string) {
+	daemonCmd := flag.NewFlagSet("daemon", flag.ExitOnError)
+	recoverMode := daemonCmd.String("recover", "abandon", "Unfinished work from previous processes: abandon, resume or ignore")
+	schedules := daemonCmd.Bool("schedules", true, "Submit scheduled objectives (see cli schedule)")
+	opts := addCommonFlags(daemonCmd)
+	daemonCmd.Parse(args)
+
+	mode, err := orchestrator.ParseRecoveryMode(*recoverMode)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Escuchar antes de recuperar: dos daemons no deben tocar el mismo store
+	socket := api.SocketPath(opts.repoPath)
+	listener, err := api.ListenSocket(socket)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ws, orch := setupOrchestrator(opts)
+	if *schedules {
+		orch.EnableScheduler()
+	}
+	recovered, err := orch.Recover("", mode)
+	if err != nil {
+		log.Fatalf("Failed to recover unfinished tasks: %v", err)
+	}
+	if len(recovered) > 0 {
+		fmt.Printf("Recovered %d unfinished task(s) (%s)\n", len(recovered), mode)
+	}
+	if err := orch.Start(); err != nil {
+		log.Fatalf("Failed to start orchestrator: %v", err)
+	}
+
+	// Sin tokens: las aprobaciones quedan a nombre del usuario que se conecta
+	server := &http.Server{
+		Handler:           api.NewServer(orch, nil),
+		ConnContext:       api.ConnContext,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Daemon server failed: %v", err)
+		}
+	}()
+	fmt.Printf("Daemon listening on %s\n", socket)
+
+	waitForShutdown(ws, orch, server)
+}
+
+// options agrupa los flags comunes a todos los modos del orchestrator
+type options struct {
+	repoPath     string
+	pipelinePath string
+	policyPath   string
+	workers      int
+	agentLimits  string
+	queueLimit   int
+	shared       bool
+	eventsPath   string
+	budget       orchestrator.Budget
+	taskTimeout  time.Duration
+	timeouts     string
+	approvalWait time.Duration
+	dryRun       bool // workspace de solo lectura, sin store ni sink de eventos: nada se escribe
+}
+
+// addCommonFlags registra los flags comunes en un FlagSet
+func addCommonFlags(fs *flag.FlagSet) *options {
+	opts := &options{}
+	fs.StringVar(&opts.repoPath, "repo", ".", "Path to git repository")
+	fs.StringVar(&opts.pipelinePath, "pipeline", "", "Pipeline definition file (YAML or JSON); defaults to the built-in pipeline")
+	fs.StringVar(&opts.policyPath, "policy", "", "Policy file (YAML, see policies.example.yaml); defaults to the built-in policies")
+	fs.IntVar(&opts.workers, "workers", orchestrator.DefaultPoolConfig().Workers, "Maximum number of tasks running at once")
+	fs.StringVar(&opts.agentLimits, "agent-limits", "", "Per-agent concurrency caps, e.g. coder=1,auditor=2")
+	fs.IntVar(&opts.queueLimit, "queue-limit", orchestrator.DefaultPoolConfig().QueueLimit, "Queued tasks above which new objectives wait (0 = no limit)")
+	defaults := orchestrator.DefaultBudget()
+	fs.IntVar(&opts.budget.MaxRepairs, "max-repairs", defaults.MaxRepairs, "Maximum repair iterations per run (0 = no limit)")
+	fs.IntVar(&opts.budget.MaxTasks, "max-tasks", defaults.MaxTasks, "Maximum tasks per run (0 = no limit)")
+	fs.DurationVar(&opts.budget.Deadline, "deadline", defaults.Deadline, "Wall-clock limit per run (0 = no limit)")
+	fs.IntVar(&opts.budget.MaxSameFailures, "max-same-failure", defaults.MaxSameFailures, "Stop a run when the same failure repeats this many times (0 = never)")
+	fs.DurationVar(&opts.taskTimeout, "task-timeout", orchestrator.DefaultTaskTimeout, "Execution time limit per task (0 = no limit)")
+	fs.StringVar(&opts.timeouts, "timeouts", "", "Per-task-type time limits, e.g. test=10m,code=5m")
+	fs.DurationVar(&opts.approvalWait, "approval-timeout", orchestrator.DefaultApprovalTimeout, "How long a high-risk change waits for approval before it is rejected (0 = forever)")
+	fs.StringVar(&opts.eventsPath, "events", "", "Append lifecycle events as NDJSON to this file")
+	fs.BoolVar(&opts.shared, "shared-workspace", false, "Run every task in the repository checkout instead of a git worktree per run")
+	return opts
+}
+
+// parseAgentLimits convierte "coder=1,auditor=2" en un mapa de límites
+func parseAgentLimits(s string) (map[string]int, error) {
+	limits := make(map[string]int)
+	if strings.TrimSpace(s) == "" {
+		return limits, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid agent limit %q (expected agent=N)", pair)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid agent limit %q (expected agent=N)", pair)
+		}
+		limits[name] = n
+	}
+	return limits, nil
+}
+
+// parseTimeouts convierte "test=10m,code=5m" en límites por tipo de tarea
+func parseTimeouts(s string) (map[types.TaskType]time.Duration, error) {
+	timeouts := make(map[types.TaskType]time.Duration)
+	if strings.TrimSpace(s) == "" {
+		return timeouts, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid timeout %q (expected type=duration)", pair)
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid timeout %q (expected type=duration)", pair)
+		}
+		if _, ok := pipeline.DefaultAgents[types.TaskType(name)]; !ok {
+			return nil, fmt.Errorf("invalid timeout %q: unknown task type %q", pair, name)
+		}
+		timeouts[types.TaskType(name)] = d
+	}
+	return timeouts, nil
+}
+
+// setupOrchestrator crea el workspace, las políticas y el orchestrator persistente
+func setupOrchestrator(opts *options) (*workspace.Manager, *orchestrator.Orchestrator) {
+	repoPath := opts.repoPath
+
+	// Crear workspace manager; el de un dry run no crea .multi-agent/
+	open := workspace.NewManager
+	if opts.dryRun {
+		open = workspace.OpenReadOnly
+	}
+	ws, err := open(repoPath)
+	if err != nil {
+		log.Fatalf("Failed to create workspace manager: %v", err)
+	}
+
+	// Crear policy engine desde el archivo indicado o con las políticas por defecto
+	var policy *policies.Engine
+	if opts.policyPath != "" {
+		if policy, err = policies.LoadFile(opts.policyPath); err != nil {
+			log.Fatalf("Failed to load policies:\n%v", err)
+		}
+	} else {
+		policy = policies.NewEngine()
+		setupDefaultPolicies(policy)
+	}
+
+	// Abrir store persistente (.multi-agent/)
+	var st store.Store = store.NewMemoryStore()
+	if !opts.dryRun {
+		if st, err = store.Open(repoPath); err != nil {
+			log.Fatalf("Failed to open task store: %v", err)
+		}
+	}
+
+	// Crear orchestrator
+	orch := orchestrator.NewWithStore(ws, policy, st)
+
+	// Configurar el pool de workers
+	limits, err := parseAgentLimits(opts.agentLimits)
+	if err != nil {
+		log.Fatal(err)
+	}
+	poolConfig := orchestrator.DefaultPoolConfig()
+	poolConfig.Workers = opts.workers
+	poolConfig.AgentLimits = limits
+	poolConfig.QueueLimit = opts.queueLimit
+	orch.SetPoolConfig(poolConfig)
+	orch.SetIsolation(!opts.shared)
+	orch.SetBudget(opts.budget)
+
+	// Tiempo máximo por tarea
+	timeouts, err := parseTimeouts(opts.timeouts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	orch.SetDefaultTimeout(opts.taskTimeout)
+	for taskType, d := range timeouts {
+		orch.SetTimeout(taskType, d)
+	}
+	orch.SetApprovalTimeout(opts.approvalWait)
+
+	// Registrar eventos en un archivo NDJSON si se indicó
+	if opts.eventsPath != "" && !opts.dryRun {
+		sink, err := events.OpenNDJSONFile(opts.eventsPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		orch.Events().AddSink(sink)
+	}
+
+	// Cargar pipeline declarativo si se indicó
+	if opts.pipelinePath != "" {
+		p, err := pipeline.LoadFile(opts.pipelinePath)
+		if err != nil {
+			log.Fatalf("Failed to load pipeline: %v", err)
+		}
+		if err := orch.SetPipeline(p); err != nil {
+			log.Fatalf("Failed to set pipeline: %v", err)
+		}
+	}
+
+	return ws, orch
+}
+
+// waitForShutdown espera una señal y detiene el orchestrator (y el servidor
+// HTTP, si lo hay)
+func waitForShutdown(ws *workspace.Manager, orch *orchestrator.Orchestrator, servers ...*http.Server) {
+	// Manejar señales para shutdown graceful
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
 	// Esperar señal de shutdown
 	<-sigChan
 	fmt.Println("\nShutting down...")
+	for _, server := range servers {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		server.Shutdown(ctx)
+		cancel()
+	}
 	orch.Stop()
 	ws.Cleanup()
 }
 
+// printDryRun muestra el grafo de tareas esperado de un dry run
+func printDryRun(report *orchestrator.DryRunReport) {
+	fmt.Printf("Dry run: %s\n", report.Root.Task.Objective)
+	fmt.Printf("Pipeline: %s\n", report.Pipeline)
+	fmt.Printf("Policies: %s\n", strings.Join(report.Policies, ", "))
+	fmt.Printf("Required gates: %s\n\n", strings.Join(report.Gates, ", "))
+
+	var tasks, commands, denied int
+	var walk func(node *orchestrator.DryRunTask, indent string)
+	walk = func(node *orchestrator.DryRunTask, indent string) {
+		task := node.Task
+		tasks++
+		fmt.Printf("%s%s %s [%s] %s: %s\n", indent, verdict(node.Allowed), task.ID, node.Agent, task.Stage, task.Objective)
+		if len(task.DependsOn) > 0 {
+			fmt.Printf("%s    after %s\n", indent, strings.Join(task.DependsOn, ", "))
+		}
+		if node.Revisits {
+			fmt.Printf("%s    stage %s already visited on this branch, not expanded\n", indent, task.Stage)
+			return
+		}
+		if !node.Allowed {
+			denied++
+		}
+		if node.Reason != "" {
+			fmt.Printf("%s    %s\n", indent, node.Reason)
+		}
+		for _, warning := range node.Warnings {
+			fmt.Printf("%s    warn: %s\n", indent, warning)
+		}
+		for _, step := range node.Steps {
+			action := "write " + step.Path
+			if step.Command != "" {
+				commands++
+				action = "$ " + strings.Join(append([]string{step.Command}, step.Args...), " ")
+			}
+			if step.When != "" {
+				action += " (if " + step.When + ")"
+			}
+			if !step.Allowed {
+				denied++
+				action += ": " + step.Reason
+			}
+			fmt.Printf("%s    %s %s\n", indent, verdict(step.Allowed), action)
+		}
+		if node.Approvals > 0 {
+			fmt.Printf("%s    awaits %d approval(s): %s\n", indent, node.Approvals, strings.Join(node.Risk, "; "))
+		}
+		for _, t := range node.Otherwise {
+			fmt.Printf("%s    %s\n", indent, t)
+		}
+		for _, child := range node.Children {
+			walk(child, indent+"  ")
+		}
+	}
+	walk(report.Root, "")
+
+	fmt.Printf("\n%d task(s), %d command(s), %d denied. Nothing was executed.\n", tasks, commands, denied)
+}
+
+// verdict retorna la etiqueta de un veredicto de política
+func verdict(allowed bool) string {
+	if allowed {
+		return "allow"
+	}
+	return "deny "
+}
+
 func setupDefaultPolicies(policy *policies.Engine) {
 	// Política para Coder
 	coderPolicy := types.Policy{
@@ -96,7 +511,7 @@ func setupDefaultPolicies(policy *policies.Engine) {
 		Metadata: map[string]interface{}{
 			"agent_id":     "tester",
 			"allowed_paths": []interface{}{"**/*_test.go"},
-			"forbidden_paths": []interface{}{"**/*.go"},
+			"forbidden_paths": []interface{}{"**/*.go", "!**/*_test.go"},
 		},
 	}
 	policy.AddPolicy(testerPolicy)
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nanochip/multi-agent/pkg/policies"
+)
+
+func TestDefaultPolicyPaths(t *testing.T) {
+	engine := policies.NewEngine()
+	setupDefaultPolicies(engine)
+
+	tests := []struct {
+		agent string
+		path  string
+		want  bool
+	}{
+		{"coder", "pkg/orchestrator/orchestrator.go", true},
+		{"coder", "cmd/cli/main.go", true},
+		{"coder", "src/lib.go", true},
+		{"coder", "internal/x/y.go", true},
+		{"coder", "pkg/orchestrator/orchestrator_test.go", false},
+		{"coder", "vendor/gopkg.in/yaml.v3/yaml.go", false},
+		{"coder", "docs/USAGE.md", false},
+
+		{"tester", "pkg/orchestrator/orchestrator_test.go", true},
+		{"tester", "main_test.go", true},
+		{"tester", "pkg/orchestrator/orchestrator.go", false},
+		{"tester", "docs/USAGE.md", false},
+	}
+	for _, tt := range tests {
+		policy := engine.GetPolicyForAgent(tt.agent)
+		if policy == nil {
+			t.Fatalf("no default policy for %s", tt.agent)
+		}
+		if got := engine.ValidatePath(tt.agent, tt.path, *policy); got != tt.want {
+			t.Errorf("%s: ValidatePath(%q) = %v, want %v", tt.agent, tt.path, got, tt.want)
+		}
+	}
+}
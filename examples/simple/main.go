@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/nanochip/multi-agent/pkg/orchestrator"
 	"github.com/nanochip/multi-agent/pkg/policies"
@@ -59,5 +61,14 @@ func main() {
 	fmt.Printf("\n  5. Repair if needed")
 	fmt.Printf("\n  6. Optimize if possible\n")
 	
-	// En producción, aquí esperarías a que se complete o usarías un mecanismo de notificación
+	// 7. Esperar a que termine todo el árbol de tareas
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	
+	result, err := orch.Wait(ctx, task.RunID)
+	if err != nil {
+		log.Fatalf("Failed waiting for run: %v", err)
+	}
+	
+	fmt.Printf("\nRun %s finished: %s (%d tasks)\n", result.Run.ID, result.Run.State, len(result.Tasks))
 }
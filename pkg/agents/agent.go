@@ -3,6 +3,7 @@ package agents
 import (
 	"context"
 
+	"github.com/nanochip/multi-agent/pkg/glob"
 	"github.com/nanochip/multi-agent/pkg/policies"
 	"github.com/nanochip/multi-agent/pkg/types"
 	"github.com/nanochip/multi-agent/pkg/workspace"
@@ -14,15 +15,59 @@ type Agent interface {
 	GetContract() types.AgentContract
 }
 
+// Simulator lo implementan los agentes que pueden describir lo que harían
+// con una tarea sin ejecutar comandos ni escribir archivos (dry run)
+type Simulator interface {
+	// Simulate retorna el resultado esperado y los pasos que se darían
+	Simulate(task *types.Task) (*types.TaskResult, []Step)
+}
+
+// Step es un comando que un agente ejecutaría o un archivo que escribiría
+type Step struct {
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	Path    string   `json:"path,omitempty"` // archivo que se escribiría
+	When    string   `json:"when,omitempty"` // condición para que se dé el paso
+}
+
+// run describe un comando de un paso simulado
+func run(command string, args ...string) Step {
+	return Step{Command: command, Args: args}
+}
+
+// write describe la escritura de un archivo en un paso simulado
+func write(path string) Step {
+	return Step{Path: path}
+}
+
+// when añade a un paso la condición para que se dé
+func (s Step) when(condition string) Step {
+	s.When = condition
+	return s
+}
+
+// simulated retorna el resultado exitoso esperado de una tarea simulada
+func simulated(task *types.Task, outputs map[string]interface{}) *types.TaskResult {
+	if outputs == nil {
+		outputs = make(map[string]interface{})
+	}
+	return &types.TaskResult{
+		TaskID:  task.ID,
+		State:   types.StateSuccess,
+		Success: true,
+		Outputs: outputs,
+	}
+}
+
 // BaseAgent proporciona funcionalidad común a todos los agentes
 type BaseAgent struct {
-	workspace workspace.Manager
-	policy    policies.Engine
+	workspace *workspace.Manager
+	policy    *policies.Engine
 	contract  types.AgentContract
 }
 
 // NewBaseAgent crea un nuevo agente base
-func NewBaseAgent(ws workspace.Manager, policy policies.Engine, contract types.AgentContract) *BaseAgent {
+func NewBaseAgent(ws *workspace.Manager, policy *policies.Engine, contract types.AgentContract) *BaseAgent {
 	return &BaseAgent{
 		workspace: ws,
 		policy:    policy,
@@ -30,42 +75,33 @@ func NewBaseAgent(ws workspace.Manager, policy policies.Engine, contract types.A
 	}
 }
 
-// ValidatePath verifica si una ruta está permitida según el contrato
+// ValidatePath verifica si una ruta está permitida según el contrato. Las
+// rutas absolutas se toman relativas a la raíz del repositorio; las que
+// quedan fuera de él no están permitidas.
 func (b *BaseAgent) ValidatePath(path string) bool {
+	root := ""
+	if b.workspace != nil {
+		root = b.workspace.GetRepoPath()
+	}
+	path = glob.Normalize(root, path)
+	if !glob.Inside(path) {
+		return false
+	}
+	
 	// Verificar rutas prohibidas primero
-	for _, forbidden := range b.contract.ForbiddenPaths {
-		if matched, _ := pathMatches(path, forbidden); matched {
-			return false
-		}
+	if matched, err := glob.MatchAny(b.contract.ForbiddenPaths, path); err != nil || matched {
+		return false
 	}
 	
 	// Si hay rutas permitidas, verificar que esté en la lista
 	if len(b.contract.AllowedPaths) > 0 {
-		allowed := false
-		for _, allowedPath := range b.contract.AllowedPaths {
-			if matched, _ := pathMatches(path, allowedPath); matched {
-				allowed = true
-				break
-			}
-		}
-		return allowed
+		matched, err := glob.MatchAny(b.contract.AllowedPaths, path)
+		return err == nil && matched
 	}
 	
 	return true
 }
 
-// pathMatches verifica si un path coincide con un patrón
-func pathMatches(path, pattern string) (bool, error) {
-	if pattern == "*" {
-		return true, nil
-	}
-	if pattern == path {
-		return true, nil
-	}
-	// Implementación más completa de glob matching
-	return false, nil
-}
-
 // GetContract retorna el contrato del agente
 func (b *BaseAgent) GetContract() types.AgentContract {
 	return b.contract
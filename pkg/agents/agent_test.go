@@ -0,0 +1,133 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/nanochip/multi-agent/pkg/types"
+	"github.com/nanochip/multi-agent/pkg/workspace"
+)
+
+// pathAgent es un agente con contrato de rutas
+type pathAgent interface {
+	Agent
+	ValidatePath(path string) bool
+}
+
+func TestContractPaths(t *testing.T) {
+	repo := t.TempDir()
+	if _, err := git.PlainInit(repo, false); err != nil {
+		t.Fatal(err)
+	}
+	ws, err := workspace.NewManager(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agents := map[string]pathAgent{
+		"coder":     NewCoder(ws, nil),
+		"tester":    NewTester(ws, nil),
+		"auditor":   NewAuditor(ws, nil),
+		"repairer":  NewRepairer(ws, nil),
+		"optimizer": NewOptimizer(ws, nil),
+		"release":   NewRelease(ws, nil),
+		"releaser":  NewReleaser(ws, nil),
+		"planner":   NewPlanner(ws, nil),
+	}
+
+	tests := []struct {
+		agent string
+		path  string
+		want  bool
+	}{
+		{"coder", "src/main.go", true},
+		{"coder", "cmd/orchestrator/main.go", true},
+		{"coder", "internal/store/file.go", true},
+		{"coder", "pkg/policies/engine.go", true},
+		{"coder", "./pkg/policies/engine.go", true},
+		{"coder", filepath.Join(repo, "pkg/policies/engine.go"), true},
+		{"coder", "pkg/policies/engine_test.go", false},
+		{"coder", "engine_test.go", false},
+		{"coder", "vendor/github.com/x/y.go", false},
+		{"coder", "pkg/vendor/y.go", true},
+		{"coder", "main.go", false},
+		{"coder", "go.mod", false},
+		{"coder", "docs/USAGE.md", false},
+		{"coder", "pkg/../../outside/a.go", false},
+		{"coder", "/etc/passwd", false},
+
+		{"tester", "pkg/policies/engine_test.go", true},
+		{"tester", "main_test.go", true},
+		{"tester", filepath.Join(repo, "cmd/cli/main_test.go"), true},
+		{"tester", "pkg/policies/engine.go", false},
+		{"tester", "main.go", false},
+		{"tester", "go.mod", false},
+		{"tester", "testdata/fixture.json", false},
+
+		{"auditor", "pkg/policies/engine.go", false},
+		{"auditor", "README.md", false},
+
+		{"repairer", "pkg/policies/engine.go", true},
+		{"repairer", "pkg/policies/engine_test.go", true},
+		{"repairer", "docs/USAGE.md", false},
+
+		{"optimizer", "internal/cache/lru.go", true},
+		{"optimizer", "Makefile", false},
+
+		{"release", "Dockerfile", true},
+		{"release", "deploy/api/Dockerfile", true},
+		{"release", "Makefile", true},
+		{"release", "go.mod", true},
+		{"release", "tools/go.mod", true},
+		{"release", "deploy/k8s/service.yaml", true},
+		{"release", ".github/workflows/ci.yml", true},
+		{"release", "go.sum", false},
+		{"release", "cmd/orchestrator/main.go", false},
+		{"release", "main.go", false},
+		{"release", "README.md", false},
+
+		{"releaser", "README.md", true},
+		{"releaser", "cmd/orchestrator/main.go", true},
+		{"releaser", "CHANGELOG.md", true},
+		{"releaser", "../CHANGELOG.md", false},
+
+		// Sin rutas en el contrato se permite cualquiera dentro del repositorio
+		{"planner", "docs/plan.md", true},
+		{"planner", "../plan.md", false},
+	}
+	for _, tt := range tests {
+		if got := agents[tt.agent].ValidatePath(tt.path); got != tt.want {
+			t.Errorf("%s.ValidatePath(%q) = %v, want %v", tt.agent, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestReleaseCommandsUseTaskContext(t *testing.T) {
+	repo := t.TempDir()
+	if _, err := git.PlainInit(repo, false); err != nil {
+		t.Fatal(err)
+	}
+	ws, err := workspace.NewManager(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Con la tarea ya cancelada ningún comando llega a ejecutarse
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	task := &types.Task{ID: "task-release", Type: types.TaskRelease, Objective: "rollback release"}
+
+	if result := NewReleaser(ws, nil).Execute(ctx, task); result.Success || !strings.Contains(result.Error, "interrupted") {
+		t.Errorf("Releaser.Execute() = %v %q, want the version lookup interrupted", result.State, result.Error)
+	}
+	if err := NewReleaser(ws, nil).Rollback(ctx, "v0.1.0"); err == nil || !strings.Contains(err.Error(), "interrupted") {
+		t.Errorf("Releaser.Rollback() = %v, want an interrupted checkout", err)
+	}
+	if result := NewRelease(ws, nil).rollbackDeployment(ctx, task); !strings.Contains(fmt.Sprint(result["error"]), "interrupted") {
+		t.Errorf("Release.rollbackDeployment() = %v, want the tag lookup interrupted", result)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/nanochip/multi-agent/pkg/glob"
 	"github.com/nanochip/multi-agent/pkg/policies"
 	"github.com/nanochip/multi-agent/pkg/types"
 	"github.com/nanochip/multi-agent/pkg/workspace"
@@ -18,7 +19,7 @@ type Coder struct {
 }
 
 // NewCoder crea un nuevo agente codificador
-func NewCoder(ws workspace.Manager, policy policies.Engine) *Coder {
+func NewCoder(ws *workspace.Manager, policy *policies.Engine) *Coder {
 	contract := types.AgentContract{
 		ID:           "coder",
 		Name:         "Coder",
@@ -63,7 +64,6 @@ func (c *Coder) Execute(ctx context.Context, task *types.Task) *types.TaskResult
 	
 	for _, file := range filesToModify {
 		// Validar que el archivo está permitido
-		fullPath := filepath.Join(c.workspace.GetRepoPath(), file)
 		if !c.ValidatePath(file) {
 			continue
 		}
@@ -83,11 +83,11 @@ func (c *Coder) Execute(ctx context.Context, task *types.Task) *types.TaskResult
 	}
 	
 	// Ejecutar fmt
-	if output, err := c.workspace.RunCommand("go", "fmt", "./..."); err != nil {
+	if output, err := c.workspace.RunCommandContext(ctx, "go", "fmt", "./..."); err != nil {
 		evidence = append(evidence, types.Evidence{
 			Type:        "log",
 			Source:      "go fmt",
-			Content:     []byte(output),
+			Content:     types.TextContent(output),
 			Timestamp:   time.Now(),
 		})
 	}
@@ -109,6 +109,33 @@ func (c *Coder) Execute(ctx context.Context, task *types.Task) *types.TaskResult
 	}
 }
 
+// Simulate describe la rama, los archivos que se modificarían y el formateo
+func (c *Coder) Simulate(task *types.Task) (*types.TaskResult, []Step) {
+	branchName := fmt.Sprintf("agent-code-%d", time.Now().Unix())
+	steps := []Step{run("git", "checkout", "-b", branchName)}
+	
+	changes := make([]string, 0)
+	for _, file := range c.analyzeObjective(task.Objective, task.Inputs) {
+		steps = append(steps, write(file))
+		if c.ValidatePath(file) {
+			changes = append(changes, file)
+		}
+	}
+	steps = append(steps, run("go", "fmt", "./..."))
+	
+	result := simulated(task, map[string]interface{}{
+		"files_changed": changes,
+		"branch":        branchName,
+	})
+	// Como en Execute, sin archivos permitidos que cambiar la tarea falla
+	if len(changes) == 0 {
+		result.State = mapState(false)
+		result.Success = false
+		result.Error = "no allowed files to change"
+	}
+	return result, steps
+}
+
 // analyzeObjective determina qué archivos deben modificarse
 func (c *Coder) analyzeObjective(objective string, inputs map[string]interface{}) []string {
 	files := make([]string, 0)
@@ -162,7 +189,7 @@ func (c *Coder) findRelevantFiles(searchPath string, objective string) []string
 		if !info.IsDir() && filepath.Ext(path) == ".go" {
 			// Simplificado: devolver algunos archivos
 			if len(files) < 3 {
-				files = append(files, path)
+				files = append(files, c.relativePath(path))
 			}
 		}
 		return nil
@@ -171,6 +198,12 @@ func (c *Coder) findRelevantFiles(searchPath string, objective string) []string
 	return files
 }
 
+// relativePath convierte una ruta del workspace en relativa a la raíz del
+// repositorio, la forma que esperan los contratos y applyChange
+func (c *Coder) relativePath(path string) string {
+	return glob.Normalize(c.workspace.GetRepoPath(), path)
+}
+
 // findGoFiles encuentra archivos Go en un directorio
 func (c *Coder) findGoFiles(dir string) []string {
 	files := make([]string, 0)
@@ -179,8 +212,7 @@ func (c *Coder) findGoFiles(dir string) []string {
 			return nil
 		}
 		if !info.IsDir() && filepath.Ext(path) == ".go" {
-			relPath, _ := filepath.Rel(dir, path)
-			files = append(files, relPath)
+			files = append(files, c.relativePath(path))
 		}
 		return nil
 	})
@@ -196,11 +228,3 @@ func (c *Coder) applyChange(file, objective string) string {
 	}
 	return ""
 }
-
-// mapState convierte un bool a TaskState
-func mapState(success bool) types.TaskState {
-	if success {
-		return types.StateSuccess
-	}
-	return types.StateFailed
-}
@@ -2,7 +2,6 @@ package agents
 
 import (
 	"context"
-	"fmt"
 	"strings"
 	"time"
 
@@ -17,7 +16,7 @@ type Optimizer struct {
 }
 
 // NewOptimizer crea un nuevo agente optimizador
-func NewOptimizer(ws workspace.Manager, policy policies.Engine) *Optimizer {
+func NewOptimizer(ws *workspace.Manager, policy *policies.Engine) *Optimizer {
 	contract := types.AgentContract{
 		ID:           "optimizer",
 		Name:         "Optimizer",
@@ -42,7 +41,7 @@ func (o *Optimizer) Execute(ctx context.Context, task *types.Task) *types.TaskRe
 	}
 	
 	// Ejecutar benchmark si está disponible
-	benchmarkResult := o.runBenchmarks()
+	benchmarkResult := o.runBenchmarks(ctx)
 	
 	// Identificar optimizaciones potenciales
 	optimizations := o.identifyOptimizations(task.Objective)
@@ -50,19 +49,19 @@ func (o *Optimizer) Execute(ctx context.Context, task *types.Task) *types.TaskRe
 	// Aplicar optimizaciones (solo si son seguras)
 	appliedOpts := make([]string, 0)
 	for _, opt := range optimizations {
-		if o.isSafeOptimization(opt) {
-			if o.applyOptimization(opt) {
+		if o.isSafeOptimization(ctx, opt) {
+			if o.applyOptimization(ctx, opt) {
 				appliedOpts = append(appliedOpts, opt)
 			}
 		}
 	}
 	
 	// Validar que los tests aún pasan después de optimizar
-	testOutput, _ := o.workspace.RunCommand("go", "test", "./...")
+	testOutput, _ := o.workspace.RunCommandContext(ctx, "go", "test", "./...")
 	testsStillPass := !strings.Contains(testOutput, "FAIL")
 	
 	// Comparar benchmark antes/después
-	benchmarkAfter := o.runBenchmarks()
+	benchmarkAfter := o.runBenchmarks(ctx)
 	improvement := o.compareBenchmarks(benchmarkResult, benchmarkAfter)
 	
 	outputs := map[string]interface{}{
@@ -85,10 +84,37 @@ func (o *Optimizer) Execute(ctx context.Context, task *types.Task) *types.TaskRe
 	}
 }
 
+// Simulate describe los benchmarks, las optimizaciones y la verificación
+func (o *Optimizer) Simulate(task *types.Task) (*types.TaskResult, []Step) {
+	steps := []Step{run("go", "test", "-bench=.", "-benchmem", "./...")}
+	
+	applied := make([]string, 0)
+	for _, opt := range o.identifyOptimizations(task.Objective) {
+		switch opt {
+		case "remove_unused_imports":
+			steps = append(steps, run("goimports", "-w", "."))
+		case "simplify_expressions":
+			steps = append(steps, run("go", "fmt", "./..."))
+		case "optimize_loops", "cache_results":
+			steps = append(steps, run("go", "test", "-list", ".", "./...").when(opt+" needs tests"))
+			continue
+		default:
+			continue
+		}
+		applied = append(applied, opt)
+	}
+	
+	steps = append(steps,
+		run("go", "test", "./..."),
+		run("go", "test", "-bench=.", "-benchmem", "./..."),
+	)
+	return simulated(task, map[string]interface{}{"optimizations": applied}), steps
+}
+
 // runBenchmarks ejecuta benchmarks
-func (o *Optimizer) runBenchmarks() map[string]interface{} {
+func (o *Optimizer) runBenchmarks(ctx context.Context) map[string]interface{} {
 	// Ejecutar go test -bench
-	benchOutput, _ := o.workspace.RunCommand("go", "test", "-bench=.", "-benchmem", "./...")
+	benchOutput, _ := o.workspace.RunCommandContext(ctx, "go", "test", "-bench=.", "-benchmem", "./...")
 	
 	result := map[string]interface{}{
 		"output": benchOutput,
@@ -116,7 +142,7 @@ func (o *Optimizer) identifyOptimizations(objective string) []string {
 }
 
 // isSafeOptimization verifica si una optimización es segura
-func (o *Optimizer) isSafeOptimization(opt string) bool {
+func (o *Optimizer) isSafeOptimization(ctx context.Context, opt string) bool {
 	// Optimizaciones que no cambian comportamiento
 	safeOpts := []string{
 		"remove_unused_imports",
@@ -139,7 +165,7 @@ func (o *Optimizer) isSafeOptimization(opt string) bool {
 	for _, testOpt := range testRequiredOpts {
 		if opt == testOpt {
 			// Verificar que hay tests disponibles
-			testOutput, _ := o.workspace.RunCommand("go", "test", "-list", ".", "./...")
+			testOutput, _ := o.workspace.RunCommandContext(ctx, "go", "test", "-list", ".", "./...")
 			return strings.Contains(testOutput, "Test")
 		}
 	}
@@ -148,15 +174,15 @@ func (o *Optimizer) isSafeOptimization(opt string) bool {
 }
 
 // applyOptimization aplica una optimización específica
-func (o *Optimizer) applyOptimization(opt string) bool {
+func (o *Optimizer) applyOptimization(ctx context.Context, opt string) bool {
 	switch opt {
 	case "remove_unused_imports":
 		// goimports lo hace automáticamente
-		o.workspace.RunCommand("goimports", "-w", ".")
+		o.workspace.RunCommandContext(ctx, "goimports", "-w", ".")
 		return true
 	case "simplify_expressions":
 		// gofmt simplifica algunas expresiones
-		o.workspace.RunCommand("go", "fmt", "./...")
+		o.workspace.RunCommandContext(ctx, "go", "fmt", "./...")
 		return true
 	default:
 		// Optimizaciones más complejas requerirían análisis de AST
@@ -169,11 +195,3 @@ func (o *Optimizer) compareBenchmarks(before, after map[string]interface{}) stri
 	// Por ahora simplificado
 	return "benchmarks_compared"
 }
-
-// mapState convierte un bool a TaskState
-func mapState(success bool) types.TaskState {
-	if success {
-		return types.StateSuccess
-	}
-	return types.StateFailed
-}
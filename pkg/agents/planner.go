@@ -16,7 +16,7 @@ type Planner struct {
 }
 
 // NewPlanner crea un nuevo agente planificador
-func NewPlanner(ws workspace.Manager, policy policies.Engine) *Planner {
+func NewPlanner(ws *workspace.Manager, policy *policies.Engine) *Planner {
 	contract := types.AgentContract{
 		ID:           "planner",
 		Name:         "Planner",
@@ -42,7 +42,7 @@ func (p *Planner) Execute(ctx context.Context, task *types.Task) *types.TaskResu
 	objective := task.Objective
 	
 	// Análisis básico del objetivo para crear subtareas
-	subtasks := p.createSubtasks(objective, task.Inputs)
+	subtasks := p.createSubtasks(task.ID, objective, task.Inputs)
 	
 	outputs := map[string]interface{}{
 		"subtasks":   subtasks,
@@ -58,8 +58,15 @@ func (p *Planner) Execute(ctx context.Context, task *types.Task) *types.TaskResu
 	}
 }
 
-// createSubtasks crea subtareas basadas en el objetivo
-func (p *Planner) createSubtasks(objective string, inputs map[string]interface{}) []*types.Task {
+// Simulate planifica de verdad: el planner no ejecuta comandos ni escribe archivos
+func (p *Planner) Simulate(task *types.Task) (*types.TaskResult, []Step) {
+	return p.Execute(context.Background(), task), nil
+}
+
+// createSubtasks crea subtareas basadas en el objetivo.
+// Las subtareas forman un DAG: optimize depende del código, test de todo lo
+// anterior y audit de test. Los IDs derivan del ID de la tarea de plan.
+func (p *Planner) createSubtasks(planID, objective string, inputs map[string]interface{}) []*types.Task {
 	subtasks := make([]*types.Task, 0)
 	
 	// Análisis simple: si el objetivo contiene "fix", "bug", "repair"
@@ -112,6 +119,24 @@ func (p *Planner) createSubtasks(objective string, inputs map[string]interface{}
 		MaxRetries:  1,
 	})
 	
+	// Asignar IDs y dependencias
+	codeIDs := make([]string, 0)
+	previousIDs := make([]string, 0)
+	for i, subtask := range subtasks {
+		subtask.ID = fmt.Sprintf("%s.%d", planID, i+1)
+		switch subtask.Type {
+		case types.TaskCode:
+			codeIDs = append(codeIDs, subtask.ID)
+		case types.TaskOptimize:
+			subtask.DependsOn = append([]string{}, codeIDs...)
+		case types.TaskTest:
+			subtask.DependsOn = append([]string{}, previousIDs...)
+		case types.TaskAudit:
+			subtask.DependsOn = []string{subtasks[i-1].ID}
+		}
+		previousIDs = append(previousIDs, subtask.ID)
+	}
+	
 	return subtasks
 }
 
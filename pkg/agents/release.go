@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -22,7 +21,7 @@ type Release struct {
 }
 
 // NewRelease crea un nuevo agente de release
-func NewRelease(ws workspace.Manager, policy policies.Engine) *Release {
+func NewRelease(ws *workspace.Manager, policy *policies.Engine) *Release {
 	contract := types.AgentContract{
 		ID:           "release",
 		Name:         "Release/SRE",
@@ -57,7 +56,7 @@ func (r *Release) Execute(ctx context.Context, task *types.Task) *types.TaskResu
 	
 	switch action {
 	case "package":
-		result := r.packageArtifacts(task)
+		result := r.packageArtifacts(ctx, task)
 		outputs["package"] = result
 		evidence = append(evidence, types.Evidence{
 			Type:        "report",
@@ -67,7 +66,7 @@ func (r *Release) Execute(ctx context.Context, task *types.Task) *types.TaskResu
 		})
 		
 	case "version":
-		version, err := r.versionArtifacts(task)
+		version, err := r.versionArtifacts(ctx, task)
 		if err != nil {
 			return &types.TaskResult{
 				TaskID:    task.ID,
@@ -87,7 +86,7 @@ func (r *Release) Execute(ctx context.Context, task *types.Task) *types.TaskResu
 		})
 		
 	case "deploy":
-		result := r.deployArtifacts(task)
+		result := r.deployArtifacts(ctx, task)
 		outputs["deploy"] = result
 		evidence = append(evidence, types.Evidence{
 			Type:        "report",
@@ -97,7 +96,7 @@ func (r *Release) Execute(ctx context.Context, task *types.Task) *types.TaskResu
 		})
 		
 	case "rollback":
-		result := r.rollbackDeployment(task)
+		result := r.rollbackDeployment(ctx, task)
 		outputs["rollback"] = result
 		evidence = append(evidence, types.Evidence{
 			Type:        "report",
@@ -108,15 +107,15 @@ func (r *Release) Execute(ctx context.Context, task *types.Task) *types.TaskResu
 		
 	default:
 		// Release completo: package → version → deploy
-		packageResult := r.packageArtifacts(task)
+		packageResult := r.packageArtifacts(ctx, task)
 		outputs["package"] = packageResult
 		
-		version, err := r.versionArtifacts(task)
+		version, err := r.versionArtifacts(ctx, task)
 		if err == nil {
 			outputs["version"] = version
 		}
 		
-		deployResult := r.deployArtifacts(task)
+		deployResult := r.deployArtifacts(ctx, task)
 		outputs["deploy"] = deployResult
 	}
 	
@@ -136,6 +135,50 @@ func (r *Release) Execute(ctx context.Context, task *types.Task) *types.TaskResu
 	}
 }
 
+// Simulate describe los pasos de la acción de release que indica el objetivo
+func (r *Release) Simulate(task *types.Task) (*types.TaskResult, []Step) {
+	repoPath := r.workspace.GetRepoPath()
+	k8s := fileExists(filepath.Join(repoPath, "k8s"))
+	version := r.incrementVersion(r.currentVersion(), "patch")
+	
+	packageSteps := []Step{run("go", "build", "-o", "bin/app", "./cmd/...")}
+	if fileExists(filepath.Join(repoPath, "Dockerfile")) {
+		packageSteps = append(packageSteps, run("docker", "build", "-t", r.getImageName(), "."))
+	}
+	versionSteps := []Step{write("VERSION"), run("git", "tag", "v"+version)}
+	deploySteps := make([]Step, 0)
+	if k8s {
+		deploySteps = append(deploySteps, run("kubectl", "apply", "-f", filepath.Join(repoPath, "k8s")))
+	}
+	
+	outputs := make(map[string]interface{})
+	var steps []Step
+	switch r.determineAction(task.Objective) {
+	case "package":
+		steps = packageSteps
+	case "version":
+		steps = versionSteps
+		outputs["version"] = version
+	case "deploy":
+		steps = deploySteps
+	case "rollback":
+		steps = []Step{run("sh", "-c", "git tag --sort=-version:refname | head -2")}
+		if k8s {
+			steps = append(steps, run("kubectl", "rollout", "undo", "deployment/app"))
+		}
+	default:
+		steps = append(append(packageSteps, versionSteps...), deploySteps...)
+		outputs["version"] = version
+	}
+	return simulated(task, outputs), steps
+}
+
+// fileExists indica si existe la ruta
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 // determineAction determina qué acción realizar
 func (r *Release) determineAction(objective string) string {
 	objective = strings.ToLower(objective)
@@ -156,13 +199,13 @@ func (r *Release) determineAction(objective string) string {
 }
 
 // packageArtifacts empaqueta los artefactos
-func (r *Release) packageArtifacts(task *types.Task) map[string]interface{} {
+func (r *Release) packageArtifacts(ctx context.Context, task *types.Task) map[string]interface{} {
 	result := make(map[string]interface{})
 	
 	repoPath := r.workspace.GetRepoPath()
 	
 	// Build Go binary
-	output, err := r.workspace.RunCommand("go", "build", "-o", "bin/app", "./cmd/...")
+	output, err := r.workspace.RunCommandContext(ctx, "go", "build", "-o", "bin/app", "./cmd/...")
 	if err != nil {
 		result["error"] = fmt.Sprintf("build failed: %v", err)
 		return result
@@ -175,7 +218,7 @@ func (r *Release) packageArtifacts(task *types.Task) map[string]interface{} {
 	dockerfilePath := filepath.Join(repoPath, "Dockerfile")
 	if _, err := os.Stat(dockerfilePath); err == nil {
 		imageName := r.getImageName()
-		dockerOutput, err := r.workspace.RunCommand("docker", "build", "-t", imageName, ".")
+		dockerOutput, err := r.workspace.RunCommandContext(ctx, "docker", "build", "-t", imageName, ".")
 		if err != nil {
 			result["docker_error"] = fmt.Sprintf("docker build failed: %v", err)
 		} else {
@@ -188,28 +231,11 @@ func (r *Release) packageArtifacts(task *types.Task) map[string]interface{} {
 }
 
 // versionArtifacts versiona los artefactos
-func (r *Release) versionArtifacts(task *types.Task) (string, error) {
-	// Leer versión actual de go.mod o VERSION file
-	repoPath := r.workspace.GetRepoPath()
-	versionFile := filepath.Join(repoPath, "VERSION")
-	
-	var currentVersion string
-	if data, err := os.ReadFile(versionFile); err == nil {
-		currentVersion = strings.TrimSpace(string(data))
-	} else {
-		// Intentar leer de go.mod
-		goModPath := filepath.Join(repoPath, "go.mod")
-		if data, err := os.ReadFile(goModPath); err == nil {
-			re := regexp.MustCompile(`module\s+\S+\s+v?(\d+\.\d+\.\d+)`)
-			matches := re.FindStringSubmatch(string(data))
-			if len(matches) > 1 {
-				currentVersion = matches[1]
-			}
-		}
-	}
+func (r *Release) versionArtifacts(ctx context.Context, task *types.Task) (string, error) {
+	versionFile := filepath.Join(r.workspace.GetRepoPath(), "VERSION")
 	
 	// Incrementar versión (patch por defecto)
-	newVersion := r.incrementVersion(currentVersion, "patch")
+	newVersion := r.incrementVersion(r.currentVersion(), "patch")
 	
 	// Escribir nueva versión
 	if err := os.WriteFile(versionFile, []byte(newVersion), 0644); err != nil {
@@ -218,13 +244,30 @@ func (r *Release) versionArtifacts(task *types.Task) (string, error) {
 	
 	// Crear tag git
 	tagName := fmt.Sprintf("v%s", newVersion)
-	if _, err := r.workspace.RunCommand("git", "tag", tagName); err != nil {
+	if _, err := r.workspace.RunCommandContext(ctx, "git", "tag", tagName); err != nil {
 		return newVersion, fmt.Errorf("failed to create tag: %w", err)
 	}
 	
 	return newVersion, nil
 }
 
+// currentVersion lee la versión actual del archivo VERSION o de go.mod
+func (r *Release) currentVersion() string {
+	repoPath := r.workspace.GetRepoPath()
+	if data, err := os.ReadFile(filepath.Join(repoPath, "VERSION")); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	
+	// Intentar leer de go.mod
+	if data, err := os.ReadFile(filepath.Join(repoPath, "go.mod")); err == nil {
+		re := regexp.MustCompile(`module\s+\S+\s+v?(\d+\.\d+\.\d+)`)
+		if matches := re.FindStringSubmatch(string(data)); len(matches) > 1 {
+			return matches[1]
+		}
+	}
+	return ""
+}
+
 // incrementVersion incrementa una versión semántica
 func (r *Release) incrementVersion(version, level string) string {
 	if version == "" {
@@ -262,7 +305,7 @@ func parseInt(s string) int {
 }
 
 // deployArtifacts despliega los artefactos
-func (r *Release) deployArtifacts(task *types.Task) map[string]interface{} {
+func (r *Release) deployArtifacts(ctx context.Context, task *types.Task) map[string]interface{} {
 	result := make(map[string]interface{})
 	
 	// Verificar si hay configuración de Kubernetes
@@ -271,7 +314,7 @@ func (r *Release) deployArtifacts(task *types.Task) map[string]interface{} {
 	
 	if _, err := os.Stat(k8sPath); err == nil {
 		// Desplegar con kubectl
-		output, err := r.workspace.RunCommand("kubectl", "apply", "-f", k8sPath)
+		output, err := r.workspace.RunCommandContext(ctx, "kubectl", "apply", "-f", k8sPath)
 		if err != nil {
 			result["error"] = fmt.Sprintf("kubectl apply failed: %v", err)
 			result["output"] = output
@@ -289,13 +332,11 @@ func (r *Release) deployArtifacts(task *types.Task) map[string]interface{} {
 }
 
 // rollbackDeployment hace rollback del despliegue
-func (r *Release) rollbackDeployment(task *types.Task) map[string]interface{} {
+func (r *Release) rollbackDeployment(ctx context.Context, task *types.Task) map[string]interface{} {
 	result := make(map[string]interface{})
 	
 	// Obtener versión anterior del tag usando shell
-	cmd := exec.Command("sh", "-c", "git tag --sort=-version:refname | head -2")
-	cmd.Dir = r.workspace.GetRepoPath()
-	output, err := cmd.CombinedOutput()
+	output, err := r.workspace.RunCommandContext(ctx, "sh", "-c", "git tag --sort=-version:refname | head -2")
 	if err != nil {
 		result["error"] = fmt.Sprintf("failed to get previous version: %v", err)
 		return result
@@ -303,6 +344,7 @@ func (r *Release) rollbackDeployment(task *types.Task) map[string]interface{} {
 	
 	// Parsear tags y hacer checkout a la versión anterior
 	// Por ahora simplificado
+	_ = output
 	result["rollback_version"] = "previous"
 	result["message"] = "Rollback initiated"
 	
@@ -310,7 +352,7 @@ func (r *Release) rollbackDeployment(task *types.Task) map[string]interface{} {
 	repoPath := r.workspace.GetRepoPath()
 	k8sPath := filepath.Join(repoPath, "k8s")
 	if _, err := os.Stat(k8sPath); err == nil {
-		k8sOutput, err := r.workspace.RunCommand("kubectl", "rollout", "undo", "deployment/app")
+		k8sOutput, err := r.workspace.RunCommandContext(ctx, "kubectl", "rollout", "undo", "deployment/app")
 		if err != nil {
 			result["k8s_error"] = fmt.Sprintf("kubectl rollout failed: %v", err)
 		} else {
@@ -328,11 +370,3 @@ func (r *Release) getImageName() string {
 	repoName := filepath.Base(repoPath)
 	return fmt.Sprintf("%s:latest", repoName)
 }
-
-// mapState convierte un bool a TaskState
-func mapState(success bool) types.TaskState {
-	if success {
-		return types.StateSuccess
-	}
-	return types.StateFailed
-}
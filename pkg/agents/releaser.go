@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -20,7 +19,7 @@ type Releaser struct {
 }
 
 // NewReleaser crea un nuevo agente releaser
-func NewReleaser(ws workspace.Manager, policy policies.Engine) *Releaser {
+func NewReleaser(ws *workspace.Manager, policy *policies.Engine) *Releaser {
 	contract := types.AgentContract{
 		ID:           "releaser",
 		Name:         "Releaser",
@@ -47,7 +46,7 @@ func (r *Releaser) Execute(ctx context.Context, task *types.Task) *types.TaskRes
 	evidence := make([]types.Evidence, 0)
 	
 	// 1. Versionar
-	version, err := r.version()
+	version, err := r.version(ctx)
 	if err != nil {
 		return &types.TaskResult{
 			TaskID:    task.ID,
@@ -61,13 +60,13 @@ func (r *Releaser) Execute(ctx context.Context, task *types.Task) *types.TaskRes
 	evidence = append(evidence, types.Evidence{
 		Type:        "metric",
 		Source:      "version",
-		Content:     []byte(version),
+		Content:     types.TextContent(version),
 		Timestamp:   time.Now(),
 		Description: fmt.Sprintf("New version: %s", version),
 	})
 	
 	// 2. Empaquetar
-	packagePath, err := r.packageArtifacts(version)
+	packagePath, err := r.packageArtifacts(ctx, version)
 	if err != nil {
 		return &types.TaskResult{
 			TaskID:    task.ID,
@@ -81,29 +80,29 @@ func (r *Releaser) Execute(ctx context.Context, task *types.Task) *types.TaskRes
 	evidence = append(evidence, types.Evidence{
 		Type:        "report",
 		Source:      "package",
-		Content:     []byte(packagePath),
+		Content:     types.TextContent(packagePath),
 		Timestamp:   time.Now(),
 		Description: fmt.Sprintf("Package created at: %s", packagePath),
 	})
 	
 	// 3. Crear tag de git
-	if err := r.createTag(version); err != nil {
+	if err := r.createTag(ctx, version); err != nil {
 		// No crítico, solo log
 		evidence = append(evidence, types.Evidence{
 			Type:        "log",
 			Source:      "git tag",
-			Content:     []byte(err.Error()),
+			Content:     types.TextContent(err.Error()),
 			Timestamp:   time.Now(),
 		})
 	}
 	
 	// 4. Desplegar (si está configurado)
-	deployResult := r.deploy(version, task.Inputs)
+	deployResult := r.deploy(ctx, version, task.Inputs)
 	if deployResult != "" {
 		evidence = append(evidence, types.Evidence{
 			Type:        "report",
 			Source:      "deploy",
-			Content:     []byte(deployResult),
+			Content:     types.TextContent(deployResult),
 			Timestamp:   time.Now(),
 		})
 	}
@@ -124,16 +123,39 @@ func (r *Releaser) Execute(ctx context.Context, task *types.Task) *types.TaskRes
 	}
 }
 
-// version genera una nueva versión
-func (r *Releaser) version() (string, error) {
-	// Obtener última versión de git tags
-	repoPath := r.workspace.GetRepoPath()
+// Simulate describe el versionado, el empaquetado, el tag y el despliegue.
+// La versión sale de los tags de git, que no se consultan en un dry run.
+func (r *Releaser) Simulate(task *types.Task) (*types.TaskResult, []Step) {
+	const version = "<next version>"
+	artifactsDir := filepath.Join("artifacts", version)
+	steps := []Step{
+		run("git", "describe", "--tags", "--abbrev=0"),
+		run("go", "build", "-o", filepath.Join(artifactsDir, "app"), "./cmd/orchestrator"),
+		write(filepath.Join(artifactsDir, fmt.Sprintf("app-%s.tar.gz", version))),
+		run("git", "tag", "-a", version, "-m", fmt.Sprintf("Release %s", version)),
+	}
 	
-	// Intentar obtener último tag
-	cmd := exec.Command("git", "describe", "--tags", "--abbrev=0")
-	cmd.Dir = repoPath
-	lastTagOutput, _ := cmd.Output()
-	lastTag := strings.TrimSpace(string(lastTagOutput))
+	deployTarget, _ := task.Inputs["deploy_target"].(string)
+	switch deployTarget {
+	case "docker":
+		steps = append(steps, run("docker", "build", "-t", "app:"+version, "."))
+	case "kubernetes":
+		steps = append(steps, run("kubectl", "apply", "-f", "k8s/"))
+	}
+	return simulated(task, map[string]interface{}{"version": version}), steps
+}
+
+// version genera una nueva versión
+func (r *Releaser) version(ctx context.Context) (string, error) {
+	// Intentar obtener último tag; sin tags git describe falla
+	lastTagOutput, err := r.workspace.RunCommandContext(ctx, "git", "describe", "--tags", "--abbrev=0")
+	if ctx.Err() != nil {
+		return "", err
+	}
+	lastTag := ""
+	if err == nil {
+		lastTag = strings.TrimSpace(lastTagOutput)
+	}
 	
 	// Si no hay tags, empezar en v0.1.0
 	if lastTag == "" {
@@ -161,7 +183,7 @@ func increment(s string) string {
 }
 
 // packageArtifacts empaqueta los artefactos
-func (r *Releaser) packageArtifacts(version string) (string, error) {
+func (r *Releaser) packageArtifacts(ctx context.Context, version string) (string, error) {
 	repoPath := r.workspace.GetRepoPath()
 	artifactsDir := filepath.Join(repoPath, "artifacts", version)
 	
@@ -170,7 +192,7 @@ func (r *Releaser) packageArtifacts(version string) (string, error) {
 	}
 	
 	// Construir binarios
-	buildOutput, err := r.workspace.RunCommand("go", "build", "-o", filepath.Join(artifactsDir, "app"), "./cmd/orchestrator")
+	buildOutput, err := r.workspace.RunCommandContext(ctx, "go", "build", "-o", filepath.Join(artifactsDir, "app"), "./cmd/orchestrator")
 	if err != nil {
 		return "", fmt.Errorf("build failed: %w", err)
 	}
@@ -192,11 +214,9 @@ func (r *Releaser) packageArtifacts(version string) (string, error) {
 }
 
 // createTag crea un tag de git
-func (r *Releaser) createTag(version string) error {
-	repoPath := r.workspace.GetRepoPath()
-	
+func (r *Releaser) createTag(ctx context.Context, version string) error {
 	// Crear tag
-	_, err := r.workspace.RunCommand("git", "tag", "-a", version, "-m", fmt.Sprintf("Release %s", version))
+	_, err := r.workspace.RunCommandContext(ctx, "git", "tag", "-a", version, "-m", fmt.Sprintf("Release %s", version))
 	if err != nil {
 		return err
 	}
@@ -205,7 +225,7 @@ func (r *Releaser) createTag(version string) error {
 }
 
 // deploy despliega la versión
-func (r *Releaser) deploy(version string, inputs map[string]interface{}) string {
+func (r *Releaser) deploy(ctx context.Context, version string, inputs map[string]interface{}) string {
 	// Verificar si hay configuración de deploy
 	deployTarget, ok := inputs["deploy_target"].(string)
 	if !ok || deployTarget == "" {
@@ -216,19 +236,19 @@ func (r *Releaser) deploy(version string, inputs map[string]interface{}) string
 	
 	switch deployTarget {
 	case "docker":
-		return r.deployDocker(version, repoPath)
+		return r.deployDocker(ctx, version, repoPath)
 	case "kubernetes":
-		return r.deployKubernetes(version, repoPath)
+		return r.deployKubernetes(ctx, version, repoPath)
 	default:
 		return fmt.Sprintf("Unknown deploy target: %s", deployTarget)
 	}
 }
 
 // deployDocker despliega usando Docker
-func (r *Releaser) deployDocker(version string, repoPath string) string {
+func (r *Releaser) deployDocker(ctx context.Context, version string, repoPath string) string {
 	// Construir imagen Docker
 	imageName := fmt.Sprintf("app:%s", version)
-	output, err := r.workspace.RunCommand("docker", "build", "-t", imageName, ".")
+	output, err := r.workspace.RunCommandContext(ctx, "docker", "build", "-t", imageName, ".")
 	if err != nil {
 		return fmt.Sprintf("Docker build failed: %v", err)
 	}
@@ -239,9 +259,9 @@ func (r *Releaser) deployDocker(version string, repoPath string) string {
 }
 
 // deployKubernetes despliega usando Kubernetes
-func (r *Releaser) deployKubernetes(version string, repoPath string) string {
+func (r *Releaser) deployKubernetes(ctx context.Context, version string, repoPath string) string {
 	// Aplicar manifests de k8s
-	output, err := r.workspace.RunCommand("kubectl", "apply", "-f", "k8s/")
+	output, err := r.workspace.RunCommandContext(ctx, "kubectl", "apply", "-f", "k8s/")
 	if err != nil {
 		return fmt.Sprintf("Kubernetes deploy failed: %v", err)
 	}
@@ -250,9 +270,9 @@ func (r *Releaser) deployKubernetes(version string, repoPath string) string {
 }
 
 // Rollback ejecuta un rollback a una versión anterior
-func (r *Releaser) Rollback(targetVersion string) error {
+func (r *Releaser) Rollback(ctx context.Context, targetVersion string) error {
 	// Checkout a la versión anterior
-	_, err := r.workspace.RunCommand("git", "checkout", targetVersion)
+	_, err := r.workspace.RunCommandContext(ctx, "git", "checkout", targetVersion)
 	if err != nil {
 		return fmt.Errorf("failed to checkout version: %w", err)
 	}
@@ -2,6 +2,7 @@ package agents
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -16,7 +17,7 @@ type Repairer struct {
 }
 
 // NewRepairer crea un nuevo agente reparador
-func NewRepairer(ws workspace.Manager, policy policies.Engine) *Repairer {
+func NewRepairer(ws *workspace.Manager, policy *policies.Engine) *Repairer {
 	contract := types.AgentContract{
 		ID:           "repairer",
 		Name:         "Repairer",
@@ -45,8 +46,8 @@ func (r *Repairer) Execute(ctx context.Context, task *types.Task) *types.TaskRes
 	var fixes []string
 	
 	// Si viene de test failure
-	if testResult, ok := task.Inputs["test_result"].(*types.TaskResult); ok {
-		if tr, ok := testResult.Outputs["test_result"].(*types.TestResult); ok {
+	if testResult, ok := resultInput(task, "test_result"); ok {
+		if tr, ok := testOutput(testResult); ok {
 			strategy, fixesList := r.analyzeTestFailures(tr)
 			repairStrategy = strategy
 			fixes = fixesList
@@ -54,8 +55,8 @@ func (r *Repairer) Execute(ctx context.Context, task *types.Task) *types.TaskRes
 	}
 	
 	// Si viene de audit failure
-	if auditResult, ok := task.Inputs["audit_result"].(*types.TaskResult); ok {
-		if findings, ok := auditResult.Outputs["critical_findings"].([]types.AuditFinding); ok {
+	if auditResult, ok := resultInput(task, "audit_result"); ok {
+		if findings, ok := findingsOutput(auditResult, "critical_findings"); ok {
 			strategy, fixesList := r.analyzeAuditFailures(findings)
 			repairStrategy = strategy
 			fixes = append(fixes, fixesList...)
@@ -71,10 +72,10 @@ func (r *Repairer) Execute(ctx context.Context, task *types.Task) *types.TaskRes
 	}
 	
 	// Ejecutar go fmt automáticamente
-	r.workspace.RunCommand("go", "fmt", "./...")
+	r.workspace.RunCommandContext(ctx, "go", "fmt", "./...")
 	
 	// Ejecutar go fix para correcciones automáticas
-	r.workspace.RunCommand("go", "fix", "./...")
+	r.workspace.RunCommandContext(ctx, "go", "fix", "./...")
 	
 	outputs := map[string]interface{}{
 		"strategy":      repairStrategy,
@@ -92,6 +93,15 @@ func (r *Repairer) Execute(ctx context.Context, task *types.Task) *types.TaskRes
 	}
 }
 
+// Simulate describe las correcciones automáticas. Los fixes concretos
+// dependen del fallo que se repare y no se conocen de antemano.
+func (r *Repairer) Simulate(task *types.Task) (*types.TaskResult, []Step) {
+	return simulated(task, nil), []Step{
+		run("go", "fmt", "./..."),
+		run("go", "fix", "./..."),
+	}
+}
+
 // analyzeTestFailures analiza fallos de tests y propone fixes
 func (r *Repairer) analyzeTestFailures(testResult *types.TestResult) (string, []string) {
 	strategies := make([]string, 0)
@@ -153,30 +163,47 @@ func (r *Repairer) applyFix(fix string) bool {
 	return true
 }
 
-// contains verifica si un string contiene un substring
-func contains(s, substr string) bool {
-	if len(substr) > len(s) {
-		return false
+// resultInput retorna el resultado que la etapa anterior pasó en el input
+// key. Las tareas retomadas del journal o re-encoladas desde la dead-letter
+// queue lo traen decodificado de JSON (mapas en lugar de tipos), así que se
+// aceptan las dos formas.
+func resultInput(task *types.Task, key string) (*types.TaskResult, bool) {
+	switch v := task.Inputs[key].(type) {
+	case *types.TaskResult:
+		return v, v != nil
+	case nil:
+		return nil, false
 	}
-	for i := 0; i <= len(s)-len(substr); i++ {
-		match := true
-		for j := 0; j < len(substr); j++ {
-			if s[i+j] != substr[j] {
-				match = false
-				break
-			}
-		}
-		if match {
-			return true
-		}
+	var result types.TaskResult
+	return &result, decodeValue(task.Inputs[key], &result)
+}
+
+// testOutput retorna el test_result de un resultado, tipado o decodificado
+func testOutput(result *types.TaskResult) (*types.TestResult, bool) {
+	switch v := result.Outputs["test_result"].(type) {
+	case *types.TestResult:
+		return v, v != nil
+	case nil:
+		return nil, false
 	}
-	return false
+	var testResult types.TestResult
+	return &testResult, decodeValue(result.Outputs["test_result"], &testResult)
 }
 
-// mapState convierte un bool a TaskState
-func mapState(success bool) types.TaskState {
-	if success {
-		return types.StateSuccess
+// findingsOutput retorna los hallazgos de un resultado, tipados o decodificados
+func findingsOutput(result *types.TaskResult, key string) ([]types.AuditFinding, bool) {
+	switch v := result.Outputs[key].(type) {
+	case []types.AuditFinding:
+		return v, true
+	case nil:
+		return nil, false
 	}
-	return types.StateFailed
+	var findings []types.AuditFinding
+	return findings, decodeValue(result.Outputs[key], &findings)
+}
+
+// decodeValue convierte un valor decodificado de JSON al tipo de target
+func decodeValue(v interface{}, target interface{}) bool {
+	data, err := json.Marshal(v)
+	return err == nil && json.Unmarshal(data, target) == nil
 }
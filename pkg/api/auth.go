@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// LoadTokens lee un archivo de tokens y retorna el dueño de cada uno. Cada
+// línea es "<dueño> <token>" o solo "<token>"; las líneas vacías y los
+// comentarios (#) se ignoran. Un token sin dueño sirve para todo salvo para
+// aprobar o rechazar cambios, que quedan a nombre del dueño.
+func LoadTokens(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token file: %w", err)
+	}
+	defer f.Close()
+
+	tokens := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		owner, token := "", line
+		if fields := strings.Fields(line); len(fields) == 2 {
+			owner, token = fields[0], fields[1]
+		} else if len(fields) > 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<owner> <token>\" or \"<token>\"", path, n)
+		}
+		if _, ok := tokens[token]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate token", path, n)
+		}
+		tokens[token] = owner
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("token file %s has no tokens", path)
+	}
+	return tokens, nil
+}
+
+// authorized verifica el token "Authorization: Bearer <token>" de la petición
+// y retorna su dueño. Los navegadores no pueden enviar cabeceras con
+// EventSource, por lo que los streams de eventos también aceptan
+// ?access_token=. Solo ellos: un token en la URL queda en los logs de accesos
+// y de proxies.
+func (s *Server) authorized(r *http.Request) (string, bool) {
+	token := ""
+	if isStream(r) {
+		token = r.URL.Query().Get("access_token")
+	}
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, value, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return "", false
+		}
+		token = strings.TrimSpace(value)
+	}
+	if token == "" {
+		return "", false
+	}
+
+	owner, valid := "", false
+	for known, name := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(known)) == 1 {
+			owner, valid = name, true
+		}
+	}
+	return owner, valid
+}
+
+// callerKey guarda en el contexto de la petición quién la hace
+type callerKey struct{}
+
+// withCaller retorna ctx con el usuario que hace la petición
+func withCaller(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, callerKey{}, name)
+}
+
+// ConnContext identifica al usuario al otro lado de una conexión al socket
+// del daemon por su UID. Se usa como http.Server.ConnContext: el socket no
+// lleva tokens, así que es lo único que firma las aprobaciones.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	uid, err := peerUID(c)
+	if err != nil {
+		log.Printf("api: failed to identify the socket peer: %v", err)
+		return ctx
+	}
+	id := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(id); err == nil && u.Username != "" {
+		return withCaller(ctx, u.Username)
+	}
+	return withCaller(ctx, "uid:"+id)
+}
+
+// caller retorna el usuario autenticado de la petición, o "" si no se conoce
+func caller(ctx context.Context) string {
+	name, _ := ctx.Value(callerKey{}).(string)
+	return name
+}
+
+// isStream indica si la petición abre un stream de eventos (SSE)
+func isStream(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	if strings.Trim(r.URL.Path, "/") == "v1/events" {
+		return true
+	}
+	_, sub, ok := splitPath(r.URL.Path, "/v1/runs/")
+	return ok && strings.HasPrefix(r.URL.Path, "/v1/runs/") && sub == "events"
+}
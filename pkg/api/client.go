@@ -0,0 +1,368 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/events"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// ErrNoDaemon se retorna cuando no hay un daemon escuchando en el socket
+var ErrNoDaemon = errors.New("orchestrator daemon is not running")
+
+// Error es un error retornado por la API
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.Status)
+}
+
+// SocketPath retorna la ruta del socket del daemon de un repositorio
+func SocketPath(repoPath string) string {
+	return filepath.Join(repoPath, ".multi-agent", "orchestrator.sock")
+}
+
+// ListenSocket escucha en el socket del daemon. Un socket abandonado por un
+// daemon que terminó mal se reemplaza; si otro daemon responde, falla.
+func ListenSocket(path string) (net.Listener, error) {
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another daemon is already listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	// Solo el usuario del repositorio puede hablar con el daemon
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+// Client es un cliente de la API, sobre HTTP o sobre el socket del daemon
+type Client struct {
+	http  *http.Client
+	base  string
+	token string
+}
+
+// NewClient crea un cliente para la API en baseURL (p. ej. http://host:8080)
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		http:  &http.Client{},
+		base:  strings.TrimRight(baseURL, "/"),
+		token: token,
+	}
+}
+
+// DialSocket crea un cliente para el daemon que escucha en el socket.
+// Retorna ErrNoDaemon si no hay ninguno.
+func DialSocket(path string) (*Client, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, ErrNoDaemon
+	}
+	conn.Close()
+
+	dialer := &net.Dialer{}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		},
+	}
+	return &Client{
+		http: &http.Client{Transport: transport},
+		base: "http://daemon",
+	}, nil
+}
+
+// Submit envía un objetivo y retorna la ejecución creada
+func (c *Client) Submit(ctx context.Context, req SubmitRequest) (*SubmitResponse, error) {
+	var resp SubmitResponse
+	if err := c.do(ctx, http.MethodPost, "/v1/runs", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Runs lista las ejecuciones
+func (c *Client) Runs(ctx context.Context) ([]*types.Run, error) {
+	var runs []*types.Run
+	if err := c.do(ctx, http.MethodGet, "/v1/runs", nil, &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// Run retorna una ejecución con sus tareas
+func (c *Client) Run(ctx context.Context, runID string) (*RunResponse, error) {
+	var resp RunResponse
+	if err := c.do(ctx, http.MethodGet, "/v1/runs/"+runID, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RunResult retorna el resultado agregado de una ejecución
+func (c *Client) RunResult(ctx context.Context, runID string) (*types.RunResult, error) {
+	var result types.RunResult
+	if err := c.do(ctx, http.MethodGet, "/v1/runs/"+runID+"/result", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Task retorna una tarea con su resultado
+func (c *Client) Task(ctx context.Context, taskID string) (*TaskResponse, error) {
+	var resp TaskResponse
+	if err := c.do(ctx, http.MethodGet, "/v1/tasks/"+taskID, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeadLetters lista la dead-letter queue
+func (c *Client) DeadLetters(ctx context.Context) ([]*types.DeadLetter, error) {
+	var letters []*types.DeadLetter
+	if err := c.do(ctx, http.MethodGet, "/v1/deadletters", nil, &letters); err != nil {
+		return nil, err
+	}
+	return letters, nil
+}
+
+// DeadLetter retorna una tarea de la dead-letter queue
+func (c *Client) DeadLetter(ctx context.Context, taskID string) (*types.DeadLetter, error) {
+	var letter types.DeadLetter
+	if err := c.do(ctx, http.MethodGet, "/v1/deadletters/"+taskID, nil, &letter); err != nil {
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// Requeue vuelve a enviar una tarea de la dead-letter queue
+func (c *Client) Requeue(ctx context.Context, taskID string, inputs map[string]interface{}) (*SubmitResponse, error) {
+	var resp SubmitResponse
+	if err := c.do(ctx, http.MethodPost, "/v1/deadletters/"+taskID+"/requeue", RequeueRequest{Inputs: inputs}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// WontFix descarta una tarea de la dead-letter queue
+func (c *Client) WontFix(ctx context.Context, taskID, comment string) (*types.DeadLetter, error) {
+	var letter types.DeadLetter
+	if err := c.do(ctx, http.MethodPost, "/v1/deadletters/"+taskID+"/wontfix", WontFixRequest{Comment: comment}, &letter); err != nil {
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// Control cancela, pausa o reanuda una ejecución o una tarea
+func (c *Client) Control(ctx context.Context, action, id string) error {
+	err := c.do(ctx, http.MethodPost, "/v1/runs/"+id+"/"+action, nil, nil)
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		err = c.do(ctx, http.MethodPost, "/v1/tasks/"+id+"/"+action, nil, nil)
+	}
+	return err
+}
+
+// Decide aprueba (approve) o rechaza (reject) los cambios retenidos de una
+// ejecución o de una tarea. El servidor la registra a nombre del usuario
+// autenticado, que se retorna en la respuesta.
+func (c *Client) Decide(ctx context.Context, action, id string, req ApprovalRequest) (*DecisionResponse, error) {
+	var resp DecisionResponse
+	err := c.do(ctx, http.MethodPost, "/v1/runs/"+id+"/"+action, req, &resp)
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		err = c.do(ctx, http.MethodPost, "/v1/tasks/"+id+"/"+action, req, &resp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Events llama a fn con cada evento de la ejecución hasta que esta termina,
+// fn retorna un error o ctx se cancela
+func (c *Client) Events(ctx context.Context, runID string, fn func(events.Event) error) error {
+	req, err := c.request(ctx, http.MethodGet, "/v1/runs/"+runID+"/events", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+
+	completed := false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event events.Event
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("invalid event: %w", err)
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+		completed = completed || event.Type == events.RunCompleted
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !completed {
+		return fmt.Errorf("event stream for run %s closed before the run finished", runID)
+	}
+	return nil
+}
+
+// pollInterval es la frecuencia con la que Follow consulta el estado de la
+// ejecución cuando el stream de eventos se corta
+var pollInterval = 2 * time.Second
+
+// Wait bloquea hasta que la ejecución termina y retorna su resultado
+func (c *Client) Wait(ctx context.Context, runID string) (*types.RunResult, error) {
+	return c.Follow(ctx, runID, func(events.Event) error { return nil })
+}
+
+// Follow llama a fn con cada evento de la ejecución como Events y retorna su
+// resultado al terminar. El bus descarta eventos si el cliente se retrasa y
+// el stream puede cortarse, así que si se cierra sin el cierre de la
+// ejecución Follow consulta su estado hasta que termine.
+func (c *Client) Follow(ctx context.Context, runID string, fn func(events.Event) error) (*types.RunResult, error) {
+	err := c.Events(ctx, runID, fn)
+	var apiErr *Error
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case errors.As(err, &apiErr):
+		return nil, err
+	default:
+		if err := c.poll(ctx, runID); err != nil {
+			return nil, err
+		}
+	}
+	return c.RunResult(ctx, runID)
+}
+
+// poll consulta el estado de la ejecución hasta que termina. Los errores de
+// conexión (p. ej. un reinicio del daemon) no interrumpen la espera.
+func (c *Client) poll(ctx context.Context, runID string) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		resp, err := c.Run(ctx, runID)
+		var apiErr *Error
+		switch {
+		case err == nil && resp.Run.CompletedAt != nil:
+			return nil
+		case errors.As(err, &apiErr):
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// do ejecuta una petición JSON y decodifica la respuesta en out (si no es nil)
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := c.request(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return decodeError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", path, err)
+	}
+	return nil
+}
+
+// request crea una petición autenticada
+func (c *Client) request(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// decodeError convierte una respuesta de error en *Error
+func decodeError(resp *http.Response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+		body.Error = http.StatusText(resp.StatusCode)
+	}
+	return &Error{Status: resp.StatusCode, Message: body.Error}
+}
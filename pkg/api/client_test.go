@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+func TestFollowPollsWhenStreamCloses(t *testing.T) {
+	defer func(interval time.Duration) { pollInterval = interval }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+
+	s, _, st := testServer(t, map[string]string{testToken: "ana"})
+	if err := st.SaveRun(&types.Run{ID: "run-remote", State: types.StateRunning}); err != nil {
+		t.Fatal(err)
+	}
+	// Un proxy que corta el stream de eventos nada más abrirlo
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/events") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		s.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, testToken)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		completed := time.Now()
+		st.SaveRun(&types.Run{ID: "run-remote", State: types.StateFailed, CompletedAt: &completed})
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := client.Wait(ctx, "run-remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Run.State != types.StateFailed {
+		t.Errorf("run state = %s, want failed", result.Run.State)
+	}
+
+	// Una ejecución que no existe no se espera
+	if _, err := client.Wait(ctx, "run-missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Wait() of a missing run = %v, want not found", err)
+	}
+}
+
+func TestDecideOverSocketSignsAsPeer(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("peer credentials are only read on linux")
+	}
+	me, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+	_, o, st := testServer(t, nil)
+	if err := st.SaveRun(&types.Run{ID: "run-remote", State: types.StateAwaitingApproval}); err != nil {
+		t.Fatal(err)
+	}
+
+	socket := filepath.Join(t.TempDir(), "orchestrator.sock")
+	listener, err := ListenSocket(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: NewServer(o, nil), ConnContext: ConnContext}
+	go server.Serve(listener)
+	defer server.Close()
+
+	client, err := DialSocket(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Decide(context.Background(), "approve", "run-remote", ApprovalRequest{Comment: "reviewed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Approver != me.Username {
+		t.Errorf("approver = %q, want the socket peer %q", resp.Approver, me.Username)
+	}
+	requests, _, err := st.ReadControl(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || requests[0].Approver != me.Username {
+		t.Errorf("control requests = %+v, want one approval by %s", requests, me.Username)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build linux
+
+package api
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// peerUID retorna el UID del proceso al otro lado del socket (SO_PEERCRED)
+func peerUID(c net.Conn) (uint32, error) {
+	conn, ok := c.(*net.UnixConn)
+	if !ok {
+		return 0, fmt.Errorf("not a unix socket connection: %T", c)
+	}
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return 0, err
+	}
+	if credErr != nil {
+		return 0, fmt.Errorf("failed to read peer credentials: %w", credErr)
+	}
+	return cred.Uid, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package api
+
+import (
+	"errors"
+	"net"
+)
+
+// peerUID no está disponible en esta plataforma: las decisiones por el
+// socket quedan sin aprobador y se rechazan
+func peerUID(net.Conn) (uint32, error) {
+	return 0, errors.New("peer credentials are not supported on this platform")
+}
@@ -0,0 +1,487 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/nanochip/multi-agent/pkg/orchestrator"
+	"github.com/nanochip/multi-agent/pkg/store"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// Server expone el orchestrator como API HTTP/JSON:
+//
+//	POST /v1/runs                     enviar un objetivo (abre una ejecución)
+//	GET  /v1/runs                     listar ejecuciones
+//	GET  /v1/runs/{id}                ejecución con sus tareas
+//	GET  /v1/runs/{id}/result         resultado agregado (tareas y resultados)
+//	GET  /v1/runs/{id}/decisions      decisiones de las tareas de la ejecución
+//	GET  /v1/runs/{id}/events         progreso de la ejecución (SSE)
+//	POST /v1/runs/{id}/{action}       cancel, pause, resume, approve o reject
+//	GET  /v1/tasks/{id}               tarea con su resultado
+//	GET  /v1/tasks/{id}/evidence      evidencia de la tarea
+//	GET  /v1/tasks/{id}/decisions     decisiones de la tarea
+//	POST /v1/tasks/{id}/{action}      cancel, pause, resume, approve o reject
+//	GET  /v1/deadletters              tareas en la dead-letter queue
+//	GET  /v1/deadletters/{id}         tarea fallida con evidencia y decisiones
+//	POST /v1/deadletters/{id}/requeue volver a enviarla con otros inputs
+//	POST /v1/deadletters/{id}/wontfix descartarla con un comentario
+//	GET  /v1/events                   todos los eventos (SSE)
+//
+// Todas las rutas requieren un token (ver LoadTokens), salvo que tokens sea
+// nil, como en el socket del daemon, protegido por permisos de archivo. Las
+// aprobaciones quedan a nombre del dueño del token o, en el socket, del
+// usuario del proceso que se conecta (ver ConnContext).
+// Server implementa http.Handler, por lo que puede probarse con httptest.
+type Server struct {
+	orch   *orchestrator.Orchestrator
+	tokens map[string]string // token → dueño
+	mux    *http.ServeMux
+}
+
+// NewServer crea el servidor sobre un orchestrator ya iniciado
+func NewServer(orch *orchestrator.Orchestrator, tokens map[string]string) *Server {
+	s := &Server{
+		orch:   orch,
+		tokens: tokens,
+		mux:    http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/v1/runs", s.handleRuns)
+	s.mux.HandleFunc("/v1/runs/", s.handleRun)
+	s.mux.HandleFunc("/v1/tasks/", s.handleTask)
+	s.mux.HandleFunc("/v1/deadletters", s.handleDeadLetters)
+	s.mux.HandleFunc("/v1/deadletters/", s.handleDeadLetter)
+	s.mux.HandleFunc("/v1/events", s.handleEvents)
+	return s
+}
+
+// ServeHTTP autentica la petición y la despacha
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.tokens != nil {
+		owner, ok := s.authorized(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="multi-agent"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		r = r.WithContext(withCaller(r.Context(), owner))
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// SubmitRequest es el cuerpo de POST /v1/runs
+type SubmitRequest struct {
+	Objective   string                 `json:"objective"`
+	Inputs      map[string]interface{} `json:"inputs,omitempty"`
+	Constraints map[string]interface{} `json:"constraints,omitempty"`
+	MaxRetries  *int                   `json:"max_retries,omitempty"`
+	Priority    types.Priority         `json:"priority,omitempty"`
+}
+
+// SubmitResponse es la respuesta de POST /v1/runs
+type SubmitResponse struct {
+	RunID  string `json:"run_id"`
+	TaskID string `json:"task_id"`
+}
+
+// RunResponse es la respuesta de GET /v1/runs/{id}
+type RunResponse struct {
+	Run   *types.Run    `json:"run"`
+	Tasks []*types.Task `json:"tasks"`
+}
+
+// TaskResponse es la respuesta de GET /v1/tasks/{id}
+type TaskResponse struct {
+	Task   *types.Task       `json:"task"`
+	Result *types.TaskResult `json:"result,omitempty"`
+}
+
+// ApprovalRequest es el cuerpo de POST /v1/runs/{id}/approve y .../reject.
+// El aprobador no va en el cuerpo: es el usuario autenticado.
+type ApprovalRequest struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+// DecisionResponse es la respuesta de POST /v1/runs/{id}/approve y .../reject
+type DecisionResponse struct {
+	Action   string `json:"action"`
+	Target   string `json:"target"`
+	Approver string `json:"approver"`
+}
+
+// RequeueRequest es el cuerpo de POST /v1/deadletters/{id}/requeue
+type RequeueRequest struct {
+	Inputs map[string]interface{} `json:"inputs,omitempty"` // reemplazan a los de la tarea fallida
+}
+
+// WontFixRequest es el cuerpo de POST /v1/deadletters/{id}/wontfix
+type WontFixRequest struct {
+	Comment string `json:"comment"`
+}
+
+// handleRuns atiende /v1/runs
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		runs, err := s.orch.ListRuns()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, runs)
+
+	case http.MethodPost:
+		var req SubmitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+			return
+		}
+		if strings.TrimSpace(req.Objective) == "" {
+			writeError(w, http.StatusBadRequest, "objective is required")
+			return
+		}
+		if req.Priority.Rank() < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown priority %q", req.Priority))
+			return
+		}
+		task := &types.Task{
+			Type:        types.TaskPlan,
+			Objective:   req.Objective,
+			Inputs:      req.Inputs,
+			Constraints: req.Constraints,
+			MaxRetries:  3,
+			Priority:    req.Priority,
+		}
+		if task.Inputs == nil {
+			task.Inputs = make(map[string]interface{})
+		}
+		if task.Constraints == nil {
+			task.Constraints = make(map[string]interface{})
+		}
+		if req.MaxRetries != nil {
+			task.MaxRetries = *req.MaxRetries
+		}
+		// Con la cola llena la petición espera (backpressure)
+		if err := s.orch.SubmitTaskContext(r.Context(), task); err != nil {
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		w.Header().Set("Location", "/v1/runs/"+task.RunID)
+		writeJSON(w, http.StatusAccepted, SubmitResponse{RunID: task.RunID, TaskID: task.ID})
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// handleRun atiende /v1/runs/{id} y sus subrecursos
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	runID, sub, ok := splitPath(r.URL.Path, "/v1/runs/")
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	run, tasks, err := s.orch.GetRun(runID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	switch {
+	case sub == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, RunResponse{Run: run, Tasks: tasks})
+
+	case sub == "result" && r.Method == http.MethodGet:
+		result, err := s.orch.RunResult(runID)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+
+	case sub == "decisions" && r.Method == http.MethodGet:
+		ids := make(map[string]bool, len(tasks))
+		for _, task := range tasks {
+			ids[task.ID] = true
+		}
+		writeJSON(w, http.StatusOK, s.decisions(func(d types.Decision) bool { return ids[d.TaskID] }))
+
+	case sub == "events" && r.Method == http.MethodGet:
+		s.stream(w, r, run)
+
+	case isControl(sub) && r.Method == http.MethodPost:
+		s.control(w, sub, runID)
+
+	case isDecision(sub) && r.Method == http.MethodPost:
+		s.decide(w, r, sub, runID)
+
+	case sub == "" || sub == "result" || sub == "decisions" || sub == "events":
+		methodNotAllowed(w, http.MethodGet)
+
+	case isControl(sub) || isDecision(sub):
+		methodNotAllowed(w, http.MethodPost)
+
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleTask atiende /v1/tasks/{id} y sus subrecursos
+func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
+	taskID, sub, ok := splitPath(r.URL.Path, "/v1/tasks/")
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	task, result := s.orch.GetTaskState(taskID)
+	if task == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("task %s not found", taskID))
+		return
+	}
+
+	switch {
+	case sub == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, TaskResponse{Task: task, Result: result})
+
+	case sub == "evidence" && r.Method == http.MethodGet:
+		evidence, err := s.orch.GetEvidence(taskID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if evidence == nil {
+			evidence = []types.Evidence{}
+		}
+		writeJSON(w, http.StatusOK, evidence)
+
+	case sub == "decisions" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.decisions(func(d types.Decision) bool { return d.TaskID == taskID }))
+
+	case isControl(sub) && r.Method == http.MethodPost:
+		s.control(w, sub, taskID)
+
+	case isDecision(sub) && r.Method == http.MethodPost:
+		s.decide(w, r, sub, taskID)
+
+	case sub == "" || sub == "evidence" || sub == "decisions":
+		methodNotAllowed(w, http.MethodGet)
+
+	case isControl(sub) || isDecision(sub):
+		methodNotAllowed(w, http.MethodPost)
+
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleDeadLetters atiende /v1/deadletters
+func (s *Server) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	letters, err := s.orch.ListDeadLetters()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, letters)
+}
+
+// handleDeadLetter atiende /v1/deadletters/{id} y sus acciones
+func (s *Server) handleDeadLetter(w http.ResponseWriter, r *http.Request) {
+	taskID, sub, ok := splitPath(r.URL.Path, "/v1/deadletters/")
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	letter, err := s.orch.GetDeadLetter(taskID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	switch {
+	case sub == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, letter)
+
+	case sub == "requeue" && r.Method == http.MethodPost:
+		var req RequeueRequest
+		if err := decodeBody(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		task, err := s.orch.Requeue(taskID, req.Inputs)
+		if err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		w.Header().Set("Location", "/v1/tasks/"+task.ID)
+		writeJSON(w, http.StatusAccepted, SubmitResponse{RunID: task.RunID, TaskID: task.ID})
+
+	case sub == "wontfix" && r.Method == http.MethodPost:
+		var req WontFixRequest
+		if err := decodeBody(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := s.orch.WontFix(taskID, req.Comment); err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		letter, err := s.orch.GetDeadLetter(taskID)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, letter)
+
+	case sub == "":
+		methodNotAllowed(w, http.MethodGet)
+
+	case sub == "requeue" || sub == "wontfix":
+		methodNotAllowed(w, http.MethodPost)
+
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleEvents atiende /v1/events (todos los eventos, sin fin)
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	s.stream(w, r, nil)
+}
+
+// control cancela, pausa o reanuda una tarea o ejecución. Si no pertenece a
+// este proceso, la orden se deja en el store para el proceso que la ejecuta.
+func (s *Server) control(w http.ResponseWriter, action, id string) {
+	var err error
+	switch action {
+	case orchestrator.ControlCancel:
+		err = s.orch.Cancel(id)
+	case orchestrator.ControlPause:
+		err = s.orch.Pause(id)
+	case orchestrator.ControlResume:
+		err = s.orch.Resume(id)
+	}
+	if err != nil {
+		if err := s.orch.RequestControl(action, id); err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"action": action, "target": id})
+}
+
+// decide aprueba o rechaza los cambios retenidos de una tarea o ejecución en
+// nombre del usuario autenticado, dentro de este proceso o, si no le
+// pertenece, a través del store
+func (s *Server) decide(w http.ResponseWriter, r *http.Request, action, id string) {
+	approver := caller(r.Context())
+	if approver == "" {
+		writeError(w, http.StatusForbidden, "cannot identify the approver: give the token an owner in the token file")
+		return
+	}
+	var req ApprovalRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var err error
+	if action == orchestrator.ControlApprove {
+		err = s.orch.Approve(id, approver, req.Comment)
+	} else {
+		err = s.orch.Reject(id, approver, req.Comment)
+	}
+	if err != nil {
+		if err := s.orch.RequestDecision(action, id, approver, req.Comment); err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+	}
+	writeJSON(w, http.StatusAccepted, DecisionResponse{Action: action, Target: id, Approver: approver})
+}
+
+// decodeBody decodifica un cuerpo JSON opcional
+func decodeBody(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && err != io.EOF {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// decisions retorna las decisiones registradas que cumplen el filtro
+func (s *Server) decisions(keep func(types.Decision) bool) []types.Decision {
+	selected := make([]types.Decision, 0)
+	for _, decision := range s.orch.GetMemory() {
+		if keep(decision) {
+			selected = append(selected, decision)
+		}
+	}
+	return selected
+}
+
+// isControl indica si el subrecurso es una acción de control
+func isControl(sub string) bool {
+	switch sub {
+	case orchestrator.ControlCancel, orchestrator.ControlPause, orchestrator.ControlResume:
+		return true
+	}
+	return false
+}
+
+// isDecision indica si el subrecurso es una decisión de aprobación
+func isDecision(sub string) bool {
+	return sub == orchestrator.ControlApprove || sub == orchestrator.ControlReject
+}
+
+// splitPath separa "/prefix/{id}/{sub}" en id y sub
+func splitPath(path, prefix string) (id, sub string, ok bool) {
+	rest := strings.Trim(strings.TrimPrefix(path, prefix), "/")
+	id, sub, _ = strings.Cut(rest, "/")
+	if id == "" || strings.Contains(sub, "/") {
+		return "", "", false
+	}
+	return id, sub, true
+}
+
+// writeJSON escribe una respuesta JSON
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError escribe un error como {"error": "..."}
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// writeStoreError escribe un error del store (404 si no existe)
+func writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
+
+// methodNotAllowed responde 405 con los métodos aceptados
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
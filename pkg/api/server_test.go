@@ -0,0 +1,376 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/nanochip/multi-agent/pkg/events"
+	"github.com/nanochip/multi-agent/pkg/orchestrator"
+	"github.com/nanochip/multi-agent/pkg/pipeline"
+	"github.com/nanochip/multi-agent/pkg/policies"
+	"github.com/nanochip/multi-agent/pkg/store"
+	"github.com/nanochip/multi-agent/pkg/types"
+	"github.com/nanochip/multi-agent/pkg/workspace"
+)
+
+const testToken = "s3cret"
+
+// testServer crea un servidor sobre un orchestrator sin arrancar cuyo
+// pipeline solo planifica: cada ejecución termina tras su plan
+func testServer(t *testing.T, tokens map[string]string) (*Server, *orchestrator.Orchestrator, store.Store) {
+	t.Helper()
+	repo := t.TempDir()
+	if _, err := git.PlainInit(repo, false); err != nil {
+		t.Fatal(err)
+	}
+	ws, err := workspace.NewManager(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := store.NewMemoryStore()
+	o := orchestrator.NewWithStore(ws, policies.NewEngine(), st)
+	o.SetIsolation(false)
+	if err := o.SetPipeline(&pipeline.Pipeline{Name: "plan", Stages: []pipeline.Stage{{ID: "plan", Type: types.TaskPlan}}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(o.Stop)
+	return NewServer(o, tokens), o, st
+}
+
+// do envía una petición autenticada al servidor
+func do(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// decode decodifica la respuesta, que debe tener el código indicado
+func decode(t *testing.T, w *httptest.ResponseRecorder, status int, v interface{}) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d: %s", w.Code, status, w.Body.String())
+	}
+	if v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+		}
+	}
+}
+
+// submitRun envía un objetivo por la API y espera a que termine su ejecución
+func submitRun(t *testing.T, s *Server, o *orchestrator.Orchestrator, objective string) SubmitResponse {
+	t.Helper()
+	var submitted SubmitResponse
+	w := do(t, s, http.MethodPost, "/v1/runs", `{"objective": "`+objective+`", "priority": "high"}`)
+	decode(t, w, http.StatusAccepted, &submitted)
+	if loc := w.Header().Get("Location"); loc != "/v1/runs/"+submitted.RunID {
+		t.Errorf("Location = %q, want the run", loc)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := o.Wait(ctx, submitted.RunID); err != nil {
+		t.Fatal(err)
+	}
+	return submitted
+}
+
+func TestAuthorization(t *testing.T) {
+	s, o, _ := testServer(t, map[string]string{"other": "", testToken: "ana"})
+	if err := o.Start(); err != nil {
+		t.Fatal(err)
+	}
+	run := submitRun(t, s, o, "document the api")
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		status int
+	}{
+		{"valid token", "/v1/runs", "Bearer " + testToken, http.StatusOK},
+		{"case-insensitive scheme", "/v1/runs", "bearer " + testToken, http.StatusOK},
+		{"missing token", "/v1/runs", "", http.StatusUnauthorized},
+		{"wrong token", "/v1/runs", "Bearer nope", http.StatusUnauthorized},
+		{"wrong scheme", "/v1/runs", "Basic " + testToken, http.StatusUnauthorized},
+		{"query token outside a stream", "/v1/runs?access_token=" + testToken, "", http.StatusUnauthorized},
+		{"query token on a stream", "/v1/runs/" + run.RunID + "/events?access_token=" + testToken, "", http.StatusOK},
+		{"wrong query token on a stream", "/v1/runs/" + run.RunID + "/events?access_token=nope", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+
+	// Sin tokens (socket del daemon) no se pide autenticación
+	open := NewServer(o, nil)
+	w := httptest.NewRecorder()
+	open.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/runs", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status without tokens = %d, want 200", w.Code)
+	}
+}
+
+func TestLoadTokens(t *testing.T) {
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "tokens")
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tokens, err := LoadTokens(write("# equipo\nana s3cret\n\n  bob\tt0ken \nci-only\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"s3cret": "ana", "t0ken": "bob", "ci-only": ""}; !reflect.DeepEqual(tokens, want) {
+		t.Errorf("LoadTokens() = %v, want %v", tokens, want)
+	}
+
+	tests := []struct {
+		name, content, want string
+	}{
+		{"no tokens", "# nada\n", "has no tokens"},
+		{"too many fields", "ana bob s3cret\n", `:1: expected "<owner> <token>" or "<token>"`},
+		{"duplicate token", "ana s3cret\nbob s3cret\n", ":2: duplicate token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadTokens(write(tt.content)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadTokens() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmitAndShowRun(t *testing.T) {
+	s, o, _ := testServer(t, map[string]string{testToken: "ana"})
+	if err := o.Start(); err != nil {
+		t.Fatal(err)
+	}
+	submitted := submitRun(t, s, o, "document the api")
+
+	var runs []*types.Run
+	decode(t, do(t, s, http.MethodGet, "/v1/runs", ""), http.StatusOK, &runs)
+	if len(runs) != 1 || runs[0].ID != submitted.RunID {
+		t.Fatalf("GET /v1/runs = %v, want [%s]", runs, submitted.RunID)
+	}
+
+	var run RunResponse
+	decode(t, do(t, s, http.MethodGet, "/v1/runs/"+submitted.RunID, ""), http.StatusOK, &run)
+	if run.Run.State != types.StateSuccess || len(run.Tasks) != 1 || run.Tasks[0].ID != submitted.TaskID {
+		t.Errorf("GET /v1/runs/{id} = %s with %d tasks, want success with the plan", run.Run.State, len(run.Tasks))
+	}
+	if run.Tasks[0].Priority != types.PriorityHigh {
+		t.Errorf("priority = %q, want high", run.Tasks[0].Priority)
+	}
+
+	var result types.RunResult
+	decode(t, do(t, s, http.MethodGet, "/v1/runs/"+submitted.RunID+"/result", ""), http.StatusOK, &result)
+	if result.Results[submitted.TaskID] == nil || !result.Results[submitted.TaskID].Success {
+		t.Errorf("GET /v1/runs/{id}/result has no successful result for %s", submitted.TaskID)
+	}
+
+	var task TaskResponse
+	decode(t, do(t, s, http.MethodGet, "/v1/tasks/"+submitted.TaskID, ""), http.StatusOK, &task)
+	if task.Task.Type != types.TaskPlan || task.Result == nil || task.Result.State != types.StateSuccess {
+		t.Errorf("GET /v1/tasks/{id} = %+v, want the successful plan", task)
+	}
+
+	var evidence []types.Evidence
+	decode(t, do(t, s, http.MethodGet, "/v1/tasks/"+submitted.TaskID+"/evidence", ""), http.StatusOK, &evidence)
+	if evidence == nil {
+		t.Error("GET /v1/tasks/{id}/evidence should return a list")
+	}
+
+	for _, path := range []string{"/v1/tasks/" + submitted.TaskID + "/decisions", "/v1/runs/" + submitted.RunID + "/decisions"} {
+		var decisions []types.Decision
+		decode(t, do(t, s, http.MethodGet, path, ""), http.StatusOK, &decisions)
+		if len(decisions) == 0 || decisions[0].Action != "plan" || decisions[0].TaskID != submitted.TaskID {
+			t.Errorf("GET %s = %+v, want the planner decision", path, decisions)
+		}
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	s, _, _ := testServer(t, map[string]string{testToken: "ana"})
+	tests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{http.MethodPost, "/v1/runs", `{"objective": "  "}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/runs", `{"objective": "x", "priority": "asap"}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/runs", `not json`, http.StatusBadRequest},
+		{http.MethodDelete, "/v1/runs", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/v1/runs/run-missing", "", http.StatusNotFound},
+		{http.MethodGet, "/v1/tasks/task-missing", "", http.StatusNotFound},
+		{http.MethodGet, "/v1/tasks/task-missing/evidence", "", http.StatusNotFound},
+		{http.MethodGet, "/v1/deadletters/task-missing", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		var body map[string]string
+		decode(t, do(t, s, tt.method, tt.path, tt.body), tt.status, &body)
+		if body["error"] == "" {
+			t.Errorf("%s %s: no error message", tt.method, tt.path)
+		}
+	}
+}
+
+func TestControlAndApprove(t *testing.T) {
+	s, o, st := testServer(t, map[string]string{testToken: "ana", "anonymous": ""})
+	if err := o.Start(); err != nil {
+		t.Fatal(err)
+	}
+	finished := submitRun(t, s, o, "document the api")
+
+	// Una tarea y una ejecución de otro proceso: las órdenes van al store
+	if err := st.SaveTask(&types.Task{ID: "task-remote", RunID: "run-remote", State: types.StateRunning}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SaveRun(&types.Run{ID: "run-remote", State: types.StateAwaitingApproval}); err != nil {
+		t.Fatal(err)
+	}
+
+	var accepted map[string]string
+	decode(t, do(t, s, http.MethodPost, "/v1/tasks/task-remote/cancel", ""), http.StatusAccepted, &accepted)
+	if accepted["action"] != "cancel" || accepted["target"] != "task-remote" {
+		t.Errorf("cancel response = %v", accepted)
+	}
+	// El aprobador es el dueño del token, no el que diga el cuerpo
+	var decided DecisionResponse
+	decode(t, do(t, s, http.MethodPost, "/v1/runs/run-remote/approve", `{"approver": "mallory", "comment": "reviewed"}`), http.StatusAccepted, &decided)
+	if decided.Approver != "ana" {
+		t.Errorf("approve response = %+v, want the approval by ana", decided)
+	}
+
+	// Un token sin dueño no puede decidir
+	r := httptest.NewRequest(http.MethodPost, "/v1/runs/run-remote/approve", nil)
+	r.Header.Set("Authorization", "Bearer anonymous")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	decode(t, w, http.StatusForbidden, nil)
+
+	requests, _, err := st.ReadControl(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || requests[0].Action != "cancel" || requests[1].Action != "approve" || requests[1].Approver != "ana" || requests[1].Comment != "reviewed" {
+		t.Errorf("control requests = %+v, want the cancel and the approval by ana", requests)
+	}
+
+	// Lo ya terminado o que no espera aprobación es un conflicto
+	decode(t, do(t, s, http.MethodPost, "/v1/runs/"+finished.RunID+"/cancel", ""), http.StatusConflict, nil)
+	decode(t, do(t, s, http.MethodPost, "/v1/tasks/"+finished.TaskID+"/approve", ""), http.StatusConflict, nil)
+	decode(t, do(t, s, http.MethodPost, "/v1/runs/run-remote/reject", `{"comment": `), http.StatusBadRequest, nil)
+	decode(t, do(t, s, http.MethodGet, "/v1/runs/run-remote/cancel", ""), http.StatusMethodNotAllowed, nil)
+}
+
+func TestRunEventsStreamClosesOnCompletion(t *testing.T) {
+	s, o, _ := testServer(t, map[string]string{testToken: "ana"})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// La ejecución queda abierta hasta que el orchestrator arranque
+	task := &types.Task{Type: types.TaskPlan, Objective: "document the api"}
+	if err := o.SubmitTask(task); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/v1/runs/"+task.RunID+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	// Con la suscripción ya hecha, ejecutar
+	if err := o.Start(); err != nil {
+		t.Fatal(err)
+	}
+	seen := make([]string, 0)
+	var last events.Event
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			seen = append(seen, name)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			if err := json.Unmarshal([]byte(data), &last); err != nil {
+				t.Fatalf("invalid event data %q: %v", data, err)
+			}
+		}
+	}
+	// El stream termina por sí solo: un error aquí es el timeout del contexto
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("stream did not close after the run completed: %v (events %v)", err, seen)
+	}
+	if len(seen) == 0 || seen[len(seen)-1] != string(events.RunCompleted) {
+		t.Fatalf("events = %v, want run.completed last", seen)
+	}
+	if !strings.Contains(strings.Join(seen, " "), string(events.TaskCompleted)) {
+		t.Errorf("events = %v, want the task completion", seen)
+	}
+	if last.RunID != task.RunID || last.Run == nil || last.Run.State != types.StateSuccess {
+		t.Errorf("run.completed = %+v, want the successful run", last)
+	}
+}
+
+func TestRunEventsStreamNoticesMissedCompletion(t *testing.T) {
+	defer func(interval time.Duration) { keepAliveInterval = interval }(keepAliveInterval)
+	keepAliveInterval = 10 * time.Millisecond
+
+	// Una ejecución de otro proceso: su cierre nunca pasa por el bus
+	s, _, st := testServer(t, map[string]string{testToken: "ana"})
+	if err := st.SaveRun(&types.Run{ID: "run-remote", State: types.StateRunning}); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		done <- NewClient(ts.URL, testToken).Events(ctx, "run-remote", func(events.Event) error { return nil })
+	}()
+	time.Sleep(50 * time.Millisecond)
+	completed := time.Now()
+	if err := st.SaveRun(&types.Run{ID: "run-remote", State: types.StateSuccess, CompletedAt: &completed}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Events() = %v, want the stream to close with the run", err)
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/events"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// keepAliveInterval evita que proxies cierren streams sin actividad. En el
+// stream de una ejecución también es la frecuencia con la que se comprueba si
+// terminó, por si el bus descartó su cierre.
+var keepAliveInterval = 15 * time.Second
+
+// stream envía eventos como server-sent events. Con run, solo los de esa
+// ejecución, y el stream se cierra al terminar la ejecución.
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, run *types.Run) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	ch, unsubscribe := s.orch.Events().Subscribe(256)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Una ejecución ya terminada solo emite su cierre. Se consulta después de
+	// suscribirse para no perder un cierre concurrente.
+	if s.completed(w, run) {
+		flusher.Flush()
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if s.completed(w, run) {
+				flusher.Flush()
+				return
+			}
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			if run != nil && event.RunID != run.ID {
+				continue
+			}
+			writeEvent(w, event)
+			flusher.Flush()
+			if run != nil && event.Type == events.RunCompleted {
+				return
+			}
+		}
+	}
+}
+
+// completed escribe el cierre de la ejecución si ya terminó según el store
+func (s *Server) completed(w http.ResponseWriter, run *types.Run) bool {
+	if run == nil {
+		return false
+	}
+	latest, _, err := s.orch.GetRun(run.ID)
+	if err != nil || latest.CompletedAt == nil {
+		return false
+	}
+	writeEvent(w, events.Event{Type: events.RunCompleted, Time: *latest.CompletedAt, RunID: latest.ID, State: latest.State, Run: latest})
+	return true
+}
+
+// writeEvent escribe un evento en formato SSE
+func writeEvent(w http.ResponseWriter, event events.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+}
@@ -0,0 +1,245 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule es una expresión cron de cinco campos ya interpretada:
+//
+//	minuto hora día-del-mes mes día-de-la-semana
+//
+// Cada campo acepta *, valores, rangos (1-5), listas (1,15) y pasos (*/10,
+// 0-30/5). Meses y días admiten nombres (jan, mon). También se aceptan
+// @yearly, @monthly, @weekly, @daily y @hourly. Como en cron, si día del mes
+// y día de la semana están restringidos basta con que coincida uno; un campo
+// que empieza por * (también */2) no cuenta como restringido, así que
+// "0 0 */2 * 1" se ejecuta los días impares que sean lunes.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	hourAny, domAny, dowAny       bool
+}
+
+// field describe los valores válidos de un campo
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// El domingo es 0 o 7
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// macros son las abreviaturas admitidas
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse interpreta una expresión cron
+func Parse(expr string) (*Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if macro, ok := macros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields (minute hour day-of-month month day-of-week), got %d", expr, len(fields))
+	}
+
+	s := &Schedule{
+		hourAny: unrestricted(fields[1]),
+		domAny:  unrestricted(fields[2]),
+		dowAny:  unrestricted(fields[4]),
+	}
+	var err error
+	for i, target := range []struct {
+		bits *uint64
+		f    field
+	}{
+		{&s.minute, minuteField},
+		{&s.hour, hourField},
+		{&s.dom, domField},
+		{&s.month, monthField},
+		{&s.dow, dowField},
+	} {
+		if *target.bits, err = parseField(fields[i], target.f); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+	}
+	// Domingo como 7 equivale a 0
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+// unrestricted indica si un campo cuenta como "cualquiera" para la regla de
+// día del mes y día de la semana y el cambio de hora. Como en vixie cron,
+// basta con que empiece por * (o ?), aunque lleve un paso.
+func unrestricted(text string) bool {
+	return strings.HasPrefix(text, "*") || strings.HasPrefix(text, "?")
+}
+
+// parseField convierte un campo en el conjunto de valores que admite
+func parseField(text string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(text, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepText, f.name)
+			}
+			step = n
+		}
+
+		var lo, hi int
+		switch {
+		case rng == "*" || rng == "?":
+			lo, hi = f.min, f.max
+		case strings.Contains(rng, "-"):
+			first, last, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(first); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(last); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q in %s field", rng, f.name)
+			}
+		default:
+			var err error
+			if lo, err = f.value(rng); err != nil {
+				return 0, err
+			}
+			hi = lo
+			// "5/15" significa desde 5 hasta el final en pasos de 15
+			if hasStep {
+				hi = f.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value interpreta un valor numérico o un nombre del campo
+func (f field) value(text string) (int, error) {
+	if v, ok := f.names[strings.ToLower(text)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", text, f.name)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d in %s field", v, f.min, f.max, f.name)
+	}
+	return v, nil
+}
+
+// Next retorna el primer instante posterior a t que cumple la expresión, en
+// la zona horaria de t. Retorna el instante cero si no hay ninguno en cinco
+// años (p. ej. "0 0 30 2 *").
+//
+// En los cambios de hora manda el reloj local. Como en vixie cron, lo que
+// vencía en las horas que se saltan al adelantarlo se ejecuta una vez en el
+// primer instante tras el salto (0 2 * * * a las 03:00). Las horas que se
+// repiten al retrasarlo se ejecutan una sola vez, en su segunda aparición.
+// Si el campo de la hora empieza por * (p. ej. "30 * * * *") se sigue al
+// tiempo real: no se recupera lo saltado y lo repetido se ejecuta dos veces.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !s.hourAny && s.skipped(t) {
+			return t
+		}
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 || (!s.hourAny && repeated(t)) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// skipped indica si t es el primer minuto tras adelantar el reloj y la
+// expresión se cumple en alguna de las horas locales que no existieron
+func (s *Schedule) skipped(t time.Time) bool {
+	_, after := t.Zone()
+	_, before := t.Add(-time.Minute).Zone()
+	if after <= before {
+		return false
+	}
+	// Las horas locales saltadas, sin zona para que no se normalicen
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	for w := wall.Add(-time.Duration(after-before) * time.Second); w.Before(wall); w = w.Add(time.Minute) {
+		if s.month&(1<<uint(w.Month())) != 0 && s.dayMatches(w) &&
+			s.hour&(1<<uint(w.Hour())) != 0 && s.minute&(1<<uint(w.Minute())) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// repeated indica si la hora local de t vuelve a darse más tarde, es decir, si
+// t está en la primera aparición de una hora repetida al retrasar el reloj
+func repeated(t time.Time) bool {
+	_, before := t.Zone()
+	_, after := t.Add(time.Hour).Zone()
+	if after >= before {
+		return false
+	}
+	later := t.Add(time.Duration(before-after) * time.Second)
+	return later.Hour() == t.Hour() && later.Minute() == t.Minute()
+}
+
+// dayMatches aplica la regla de cron para día del mes y día de la semana
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
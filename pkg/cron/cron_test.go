@@ -0,0 +1,122 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// at es un instante en UTC
+func at(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"every 15 minutes", "*/15 * * * *", at(2026, 1, 1, 10, 7), at(2026, 1, 1, 10, 15)},
+		{"strictly after from", "0 * * * *", at(2026, 1, 1, 10, 0), at(2026, 1, 1, 11, 0)},
+		{"hour range", "0 9-17 * * *", at(2026, 1, 1, 17, 30), at(2026, 1, 2, 9, 0)},
+		{"step in a range", "0-30/10 * * * *", at(2026, 1, 1, 10, 31), at(2026, 1, 1, 11, 0)},
+		{"step from a value", "5/15 * * * *", at(2026, 1, 1, 10, 21), at(2026, 1, 1, 10, 35)},
+		{"list", "0 0 1,15 * *", at(2026, 1, 2, 0, 0), at(2026, 1, 15, 0, 0)},
+		{"month names", "0 0 1 jan,JUL *", at(2026, 2, 1, 0, 0), at(2026, 7, 1, 0, 0)},
+		{"day names", "0 9 * * mon-fri", at(2026, 1, 3, 10, 0), at(2026, 1, 5, 9, 0)},
+		{"7 is sunday", "0 0 * * 7", at(2026, 1, 1, 0, 0), at(2026, 1, 4, 0, 0)},
+		{"0 is sunday", "0 0 * * 0", at(2026, 1, 1, 0, 0), at(2026, 1, 4, 0, 0)},
+		{"range ending in 7", "0 0 * * 6-7", at(2026, 1, 4, 12, 0), at(2026, 1, 10, 0, 0)},
+		{"day of month or day of week", "0 0 13 * fri", at(2026, 1, 1, 0, 0), at(2026, 1, 2, 0, 0)},
+		{"day of month when it is not that weekday", "0 0 13 * fri", at(2026, 1, 10, 0, 0), at(2026, 1, 13, 0, 0)},
+		{"stepped day of month is unrestricted", "0 0 */2 * 1", at(2026, 1, 1, 0, 0), at(2026, 1, 5, 0, 0)},
+		{"stepped day of month and weekday must both match", "0 0 */2 * 1", at(2026, 1, 5, 0, 0), at(2026, 1, 19, 0, 0)},
+		{"stepped weekday is unrestricted", "0 0 1 * */3", at(2026, 1, 1, 0, 0), at(2026, 2, 1, 0, 0)},
+		{"day 31 skips short months", "0 0 31 * *", at(2026, 4, 1, 0, 0), at(2026, 5, 31, 0, 0)},
+		{"leap day", "0 0 29 2 *", at(2026, 3, 1, 0, 0), at(2028, 2, 29, 0, 0)},
+		{"weekly macro", "@weekly", at(2026, 1, 1, 12, 0), at(2026, 1, 4, 0, 0)},
+		{"hourly macro", "@HOURLY", at(2026, 1, 1, 12, 30), at(2026, 1, 1, 13, 0)},
+		{"impossible date", "0 0 30 2 *", at(2026, 1, 1, 0, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextAcrossDST(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, madrid)
+	}
+
+	// 2026-03-29 02:00 CET (01:00 UTC) pasa a 03:00 CEST; 2026-10-25 03:00
+	// CEST vuelve a 02:00 CET, así que 02:30 ocurre a las 00:30 y a las 01:30 UTC
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"skipped hour runs after the gap", "30 2 * * *", local(3, 28, 23, 0), at(2026, 3, 29, 1, 0)},
+		{"skipped hour from just before the gap", "30 2 * * *", local(3, 29, 1, 59), at(2026, 3, 29, 1, 0)},
+		{"skipped hour after running", "30 2 * * *", at(2026, 3, 29, 1, 0).In(madrid), local(3, 30, 2, 30)},
+		{"every minute of the skipped hour runs once", "* 2 * * *", at(2026, 3, 29, 1, 0).In(madrid), local(3, 30, 2, 0)},
+		{"skipped hour on a matching weekday", "30 2 * * sun", local(3, 28, 23, 0), at(2026, 3, 29, 1, 0)},
+		{"skipped hour on another weekday does not run", "30 2 * * mon", local(3, 28, 23, 0), local(3, 30, 2, 30)},
+		{"hourly across the gap", "30 * * * *", local(3, 29, 1, 45), local(3, 29, 3, 30)},
+		{"after the gap", "0 3 * * *", local(3, 29, 1, 0), local(3, 29, 3, 0)},
+		{"repeated hour runs once", "30 2 * * *", local(10, 25, 0, 0), at(2026, 10, 25, 1, 30)},
+		{"repeated hour from its first occurrence", "30 2 * * *", at(2026, 10, 25, 0, 10).In(madrid), at(2026, 10, 25, 1, 30)},
+		{"repeated hour after running", "30 2 * * *", at(2026, 10, 25, 1, 30).In(madrid), local(10, 26, 2, 30)},
+		{"hourly in the first occurrence", "30 * * * *", at(2026, 10, 25, 0, 10).In(madrid), at(2026, 10, 25, 0, 30)},
+		{"hourly in the second occurrence", "30 * * * *", at(2026, 10, 25, 0, 30).In(madrid), at(2026, 10, 25, 1, 30)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want.In(madrid))
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"* * * *", "expected 5 fields"},
+		{"@every 5m", "expected 5 fields"},
+		{"60 * * * *", "value 60 out of range 0-59 in minute field"},
+		{"0 0 0 * *", "value 0 out of range 1-31 in day of month field"},
+		{"0 0 * 13 *", "out of range 1-12 in month field"},
+		{"0 0 * * 8", "out of range 0-7 in day of week field"},
+		{"*/0 * * * *", "invalid step"},
+		{"0 17-9 * * *", "invalid range"},
+		{"0 0 * * funday", `invalid value "funday" in day of week field`},
+		{"0 0 * mon *", `invalid value "mon" in month field`},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.expr); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) = %v, want an error containing %q", tt.expr, err, tt.want)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package evaluation
 
 import (
-	"fmt"
+	"crypto/sha256"
+	"encoding/hex"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/nanochip/multi-agent/pkg/types"
@@ -17,7 +19,7 @@ type Engine struct {
 type FailurePattern struct {
 	ID          string
 	Name        string
-	Category    string // "compilation", "test", "runtime", "lint", "security"
+	Category    string // "compilation", "test", "runtime", "lint", "security", "timeout"
 	Severity    types.Severity
 	Regex       *regexp.Regexp
 	Description string
@@ -122,9 +124,9 @@ func (e *Engine) loadDefaultPatterns() {
 		{
 			ID:          "timeout",
 			Name:        "Timeout",
-			Category:    "test",
+			Category:    "timeout",
 			Severity:    types.SeverityMedium,
-			Regex:       regexp.MustCompile(`timeout|context deadline exceeded`),
+			Regex:       regexp.MustCompile(`timeout|timed out|context deadline exceeded`),
 			Description: "Operation timed out",
 			Remediation: "Increase timeout or optimize slow operation",
 		},
@@ -160,7 +162,7 @@ func (e *Engine) ParseResult(result *types.TaskResult) []*FailureClassification
 	// Buscar en evidence
 	for _, evidence := range result.Evidence {
 		if evidence.Type == "log" || evidence.Type == "report" {
-			content := string(evidence.Content)
+			content := evidence.Text()
 			classifications = append(classifications, e.classifyFailure(content)...)
 		}
 	}
@@ -251,3 +253,62 @@ func (e *Engine) AddPattern(pattern *FailurePattern) {
 func (e *Engine) GetPattern(id string) *FailurePattern {
 	return e.patterns[id]
 }
+
+// volatile reconoce partes de un mensaje que cambian entre ejecuciones
+// del mismo fallo (direcciones, duraciones, números de línea)
+var volatile = regexp.MustCompile(`0x[0-9a-f]+|\d+(\.\d+)?`)
+
+// Fingerprint identifica un fallo de forma estable entre intentos: dos
+// resultados con la misma huella fallan por el mismo motivo. Se basa en los
+// tests fallidos, los hallazgos que hacen fallar la auditoría (severidad
+// alta o crítica) o, si no hay, el error (o los logs) normalizados.
+// Retorna "" si el resultado es un éxito o no contiene ningún fallo.
+func Fingerprint(result *types.TaskResult) string {
+	if result.Success {
+		return ""
+	}
+	parts := make([]string, 0)
+
+	if testResult, ok := result.Outputs["test_result"].(*types.TestResult); ok {
+		for _, failure := range testResult.Failures {
+			parts = append(parts, "test:"+failure.Package+"."+failure.Test)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, key := range []string{"findings", "critical_findings"} {
+		if findings, ok := result.Outputs[key].([]types.AuditFinding); ok {
+			for _, finding := range findings {
+				part := "finding:" + finding.Rule + "@" + finding.File
+				if blocking(finding) && !seen[part] {
+					seen[part] = true
+					parts = append(parts, part)
+				}
+			}
+		}
+	}
+	if len(parts) == 0 {
+		text := result.Error
+		if text == "" {
+			for _, evidence := range result.Evidence {
+				if evidence.Type == "log" || evidence.Type == "report" {
+					text += evidence.Text()
+				}
+			}
+		}
+		if text != "" {
+			parts = append(parts, "error:"+volatile.ReplaceAllString(strings.ToLower(text), "#"))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+
+	sort.Strings(parts)
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// blocking indica si un hallazgo hace fallar una auditoría
+func blocking(finding types.AuditFinding) bool {
+	return finding.Severity == types.SeverityCritical || finding.Severity == types.SeverityHigh
+}
@@ -0,0 +1,103 @@
+package evaluation
+
+import (
+	"testing"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// failedTests es un resultado fallido con los tests indicados
+func failedTests(tests ...string) *types.TaskResult {
+	result := &types.TaskResult{State: types.StateFailed, Outputs: map[string]interface{}{}}
+	testResult := &types.TestResult{Failed: len(tests)}
+	for _, test := range tests {
+		testResult.Failures = append(testResult.Failures, types.TestFailure{Package: "cache", Test: test, Message: "failed at " + test})
+	}
+	result.Outputs["test_result"] = testResult
+	return result
+}
+
+// audit es el resultado de una auditoría con los hallazgos indicados
+func audit(success bool, findings ...types.AuditFinding) *types.TaskResult {
+	critical := make([]types.AuditFinding, 0)
+	for _, finding := range findings {
+		if finding.Severity == types.SeverityCritical || finding.Severity == types.SeverityHigh {
+			critical = append(critical, finding)
+		}
+	}
+	return &types.TaskResult{
+		Success: success,
+		Outputs: map[string]interface{}{"findings": findings, "critical_findings": critical},
+	}
+}
+
+var (
+	styleFinding  = types.AuditFinding{Rule: "line-length", File: "main.go", Severity: types.SeverityLow}
+	secretFinding = types.AuditFinding{Rule: "hardcoded-secret", File: "config.go", Severity: types.SeverityCritical}
+)
+
+func TestFingerprintIgnoresSuccess(t *testing.T) {
+	for name, result := range map[string]*types.TaskResult{
+		"plain":         {Success: true, State: types.StateSuccess},
+		"with findings": audit(true, styleFinding),
+		"with error":    {Success: true, Error: "warning: deprecated flag"},
+	} {
+		if fp := Fingerprint(result); fp != "" {
+			t.Errorf("%s: Fingerprint() = %q, want \"\" for a successful result", name, fp)
+		}
+	}
+	if fp := Fingerprint(&types.TaskResult{State: types.StateFailed}); fp != "" {
+		t.Errorf("Fingerprint() = %q, want \"\" for a failure without details", fp)
+	}
+}
+
+func TestFingerprintIsStable(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *types.TaskResult
+	}{
+		{"same tests in another order", failedTests("TestGet", "TestSet"), failedTests("TestSet", "TestGet")},
+		{
+			name: "errors differing only in numbers",
+			a:    &types.TaskResult{Error: "panic at 0xc000123 in main.go:42 after 1.5s"},
+			b:    &types.TaskResult{Error: "PANIC at 0xc000fff in main.go:57 after 2.25s"},
+		},
+		{
+			name: "logs when there is no error",
+			a:    &types.TaskResult{Evidence: []types.Evidence{{Type: "log", Content: types.TextContent("exit status 2")}}},
+			b:    &types.TaskResult{Evidence: []types.Evidence{{Type: "log", Content: types.TextContent("exit status 3")}}},
+		},
+		{"same blocking finding with other minor ones", audit(false, secretFinding), audit(false, secretFinding, styleFinding)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := Fingerprint(tt.a), Fingerprint(tt.b)
+			if a == "" || a != b {
+				t.Errorf("Fingerprint() = %q and %q, want the same non-empty fingerprint", a, b)
+			}
+		})
+	}
+}
+
+func TestFingerprintDistinguishesFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *types.TaskResult
+	}{
+		{"different tests", failedTests("TestGet"), failedTests("TestSet")},
+		{"different errors", &types.TaskResult{Error: "connection refused"}, &types.TaskResult{Error: "permission denied"}},
+		{"tests before error", failedTests("TestGet"), &types.TaskResult{Error: "FAIL: TestGet"}},
+		{
+			name: "different blocking findings",
+			a:    audit(false, secretFinding),
+			b:    audit(false, types.AuditFinding{Rule: "sql-injection", File: "db.go", Severity: types.SeverityHigh}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if a, b := Fingerprint(tt.a), Fingerprint(tt.b); a == b {
+				t.Errorf("Fingerprint() = %q for both, want different fingerprints", a)
+			}
+		})
+	}
+}
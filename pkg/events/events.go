@@ -0,0 +1,228 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// Type identifica el tipo de evento
+type Type string
+
+const (
+	TaskSubmitted    Type = "task.submitted"     // la tarea entró al sistema (o se retomó)
+	TaskStarted      Type = "task.started"       // un agente empezó a ejecutarla
+	TaskRetried      Type = "task.retried"       // falló y se volverá a intentar
+	GateFailed       Type = "gate.failed"        // una política la bloqueó o rechazó su resultado
+	TaskCompleted    Type = "task.completed"     // alcanzó un estado final
+	DecisionRecorded Type = "decision.recorded"  // un agente registró una decisión
+	EvidenceAttached Type = "evidence.attached"  // un agente adjuntó evidencia
+	TaskDeadLettered Type = "task.dead-lettered" // falló sin recuperación y pasó a la dead-letter queue
+	ApprovalRequired Type = "approval.required"  // un cambio de alto riesgo espera aprobación
+	RunHalted        Type = "run.halted"         // se agotó el presupuesto o el fallo se repite
+	RunCompleted     Type = "run.completed"      // todo el árbol de tareas terminó
+)
+
+// Event representa un cambio observable en el orchestrator
+type Event struct {
+	Type     Type            `json:"type"`
+	Time     time.Time       `json:"time"`
+	RunID    string          `json:"run_id,omitempty"`
+	TaskID   string          `json:"task_id,omitempty"`
+	TaskType types.TaskType  `json:"task_type,omitempty"`
+	State    types.TaskState `json:"state,omitempty"`
+	Attempt  int             `json:"attempt,omitempty"`
+	Message  string          `json:"message,omitempty"`
+	Decision *types.Decision `json:"decision,omitempty"`
+	Evidence *types.Evidence `json:"evidence,omitempty"`
+	Run      *types.Run      `json:"run,omitempty"`
+}
+
+// ForTask crea un evento con los datos de la tarea
+func ForTask(eventType Type, task *types.Task) Event {
+	return Event{
+		Type:     eventType,
+		RunID:    task.RunID,
+		TaskID:   task.ID,
+		TaskType: task.Type,
+		State:    task.State,
+		Attempt:  task.RetryCount,
+	}
+}
+
+// Sink recibe todos los eventos publicados (p. ej. un archivo o un notificador)
+type Sink interface {
+	Publish(event Event) error
+}
+
+// subscription es un suscriptor en proceso
+type subscription struct {
+	ch      chan Event
+	types   map[Type]bool
+	dropped int
+}
+
+// Bus distribuye eventos a suscriptores en proceso y a sinks.
+// Publish nunca bloquea al orchestrator: si el buffer de un suscriptor
+// está lleno, el evento se descarta para ese suscriptor. Los sinks se
+// escriben fuera del lock, así que uno lento solo retrasa a quien publica.
+type Bus struct {
+	mu       sync.RWMutex
+	subs     map[*subscription]bool
+	sinks    []Sink
+	closed   bool
+	inflight sync.WaitGroup // publicaciones escribiendo en los sinks
+}
+
+// NewBus crea un bus de eventos vacío
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[*subscription]bool),
+	}
+}
+
+// Subscribe retorna un canal con los eventos de los tipos indicados (todos
+// si no se indica ninguno) y una función para cancelar la suscripción
+func (b *Bus) Subscribe(buffer int, filter ...Type) (<-chan Event, func()) {
+	sub := &subscription{
+		ch:    make(chan Event, buffer),
+		types: make(map[Type]bool),
+	}
+	for _, t := range filter {
+		sub.types[t] = true
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		close(sub.ch)
+	} else {
+		b.subs[sub] = true
+	}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.subs[sub] {
+				delete(b.subs, sub)
+				close(sub.ch)
+			}
+		})
+	}
+}
+
+// AddSink registra un sink que recibe todos los eventos
+func (b *Bus) AddSink(sink Sink) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Copia nueva: las publicaciones en curso recorren la anterior sin lock
+	b.sinks = append(append([]Sink{}, b.sinks...), sink)
+}
+
+// Publish distribuye un evento
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+
+	for sub := range b.subs {
+		if len(sub.types) > 0 && !sub.types[event.Type] {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			sub.dropped++
+			if sub.dropped == 1 {
+				log.Printf("events: subscriber buffer full, dropping %s events", event.Type)
+			}
+		}
+	}
+	sinks := b.sinks
+	b.inflight.Add(1)
+	b.mu.Unlock()
+	defer b.inflight.Done()
+
+	for _, sink := range sinks {
+		if err := sink.Publish(event); err != nil {
+			log.Printf("events: sink failed for %s: %v", event.Type, err)
+		}
+	}
+}
+
+// Close cierra los canales de todos los suscriptores y, cuando terminan las
+// publicaciones en curso, los sinks que implementan io.Closer
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+
+	for sub := range b.subs {
+		close(sub.ch)
+		delete(b.subs, sub)
+	}
+	sinks := b.sinks
+	b.mu.Unlock()
+
+	b.inflight.Wait()
+	for _, sink := range sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+// NDJSONSink escribe cada evento como una línea JSON
+type NDJSONSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewNDJSONSink crea un sink NDJSON sobre un writer
+func NewNDJSONSink(w io.Writer) *NDJSONSink {
+	return &NDJSONSink{w: w, enc: json.NewEncoder(w)}
+}
+
+// OpenNDJSONFile crea un sink NDJSON que añade eventos al archivo indicado
+func OpenNDJSONFile(path string) (*NDJSONSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	return NewNDJSONSink(f), nil
+}
+
+// Publish escribe el evento
+func (s *NDJSONSink) Publish(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(event)
+}
+
+// Close cierra el writer si es cerrable
+func (s *NDJSONSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if closer, ok := s.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
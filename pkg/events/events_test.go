@@ -0,0 +1,248 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// recv retorna el siguiente evento del canal o falla si no llega
+func recv(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case event, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed, want an event")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+	return Event{}
+}
+
+// empty falla si el canal tiene un evento pendiente
+func empty(t *testing.T, ch <-chan Event) {
+	t.Helper()
+	select {
+	case event := <-ch:
+		t.Fatalf("unexpected %s event", event.Type)
+	default:
+	}
+}
+
+func TestSubscribeFilter(t *testing.T) {
+	bus := NewBus()
+	all, _ := bus.Subscribe(10)
+	completed, _ := bus.Subscribe(10, TaskCompleted, RunCompleted)
+
+	bus.Publish(Event{Type: TaskStarted, TaskID: "task-1"})
+	bus.Publish(Event{Type: TaskCompleted, TaskID: "task-1"})
+	bus.Publish(Event{Type: RunCompleted, RunID: "run-1"})
+
+	for _, want := range []Type{TaskStarted, TaskCompleted, RunCompleted} {
+		if event := recv(t, all); event.Type != want {
+			t.Errorf("unfiltered subscriber got %s, want %s", event.Type, want)
+		}
+	}
+	for _, want := range []Type{TaskCompleted, RunCompleted} {
+		if event := recv(t, completed); event.Type != want {
+			t.Errorf("filtered subscriber got %s, want %s", event.Type, want)
+		}
+	}
+	empty(t, completed)
+}
+
+func TestPublishSetsTime(t *testing.T) {
+	bus := NewBus()
+	ch, _ := bus.Subscribe(2)
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	bus.Publish(Event{Type: TaskStarted})
+	bus.Publish(Event{Type: TaskStarted, Time: at})
+	if event := recv(t, ch); event.Time.IsZero() {
+		t.Error("Publish() did not set the time of the event")
+	}
+	if event := recv(t, ch); !event.Time.Equal(at) {
+		t.Errorf("Publish() changed the time to %s, want %s", event.Time, at)
+	}
+}
+
+func TestPublishDropsWhenBufferIsFull(t *testing.T) {
+	bus := NewBus()
+	slow, _ := bus.Subscribe(1)
+	fast, _ := bus.Subscribe(10)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			bus.Publish(Event{Type: TaskStarted, Attempt: i})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish() blocked on a full subscriber")
+	}
+
+	// El lento recibe solo lo que cabía; el otro, todo
+	if event := recv(t, slow); event.Attempt != 0 {
+		t.Errorf("slow subscriber got attempt %d, want 0", event.Attempt)
+	}
+	empty(t, slow)
+	for i := 0; i < 3; i++ {
+		if event := recv(t, fast); event.Attempt != i {
+			t.Errorf("fast subscriber got attempt %d, want %d", event.Attempt, i)
+		}
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	bus := NewBus()
+	ch, unsubscribe := bus.Subscribe(10)
+	unsubscribe()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel still open after unsubscribe")
+	}
+	unsubscribe() // idempotente
+	bus.Publish(Event{Type: TaskStarted})
+
+	// Cancelar después de Close no cierra el canal dos veces
+	ch, unsubscribe = bus.Subscribe(10)
+	bus.Close()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel still open after Close")
+	}
+	unsubscribe()
+	bus.Close()
+	bus.Publish(Event{Type: TaskStarted})
+
+	// Suscribirse a un bus cerrado retorna un canal cerrado
+	ch, unsubscribe = bus.Subscribe(10)
+	if _, ok := <-ch; ok {
+		t.Fatal("subscription to a closed bus is open")
+	}
+	unsubscribe()
+}
+
+// blockingSink retiene cada evento hasta que se libera
+type blockingSink struct {
+	entered chan Event
+	release chan struct{}
+	mu      sync.Mutex
+	closed  bool
+	late    bool // recibió un evento después de Close
+}
+
+func (s *blockingSink) Publish(event Event) error {
+	s.entered <- event
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		s.late = true
+	}
+	return nil
+}
+
+func (s *blockingSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func TestSlowSinkDoesNotHoldTheBus(t *testing.T) {
+	bus := NewBus()
+	sink := &blockingSink{entered: make(chan Event, 1), release: make(chan struct{})}
+	bus.AddSink(sink)
+
+	go bus.Publish(Event{Type: TaskStarted})
+	<-sink.entered
+
+	// Mientras el sink escribe, el bus sigue atendiendo a los suscriptores
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ch, unsubscribe := bus.Subscribe(1)
+		defer unsubscribe()
+		go bus.Publish(Event{Type: RunCompleted})
+		if event := <-ch; event.Type != RunCompleted {
+			t.Errorf("subscriber got %s, want %s", event.Type, RunCompleted)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a slow sink blocked the bus")
+	}
+
+	// Close espera a las escrituras en curso antes de cerrar el sink
+	closed := make(chan struct{})
+	go func() {
+		bus.Close()
+		close(closed)
+	}()
+	<-sink.entered // la segunda publicación
+	close(sink.release)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close() did not return")
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if !sink.closed || sink.late {
+		t.Errorf("sink closed %v, written after close %v; want closed after the pending writes", sink.closed, sink.late)
+	}
+}
+
+// failingSink falla siempre
+type failingSink struct{}
+
+func (failingSink) Publish(Event) error { return errors.New("disk full") }
+
+func TestSinkErrorDoesNotStopOtherSinks(t *testing.T) {
+	bus := NewBus()
+	var buf bytes.Buffer
+	bus.AddSink(failingSink{})
+	bus.AddSink(NewNDJSONSink(&buf))
+	bus.Publish(Event{Type: TaskStarted})
+	if buf.Len() == 0 {
+		t.Error("the second sink did not receive the event")
+	}
+}
+
+func TestNDJSONSink(t *testing.T) {
+	var buf bytes.Buffer
+	bus := NewBus()
+	bus.AddSink(NewNDJSONSink(&buf))
+
+	task := &types.Task{ID: "task-1", RunID: "run-1", Type: types.TaskCode, State: types.StateRunning, RetryCount: 2}
+	bus.Publish(ForTask(TaskRetried, task))
+	bus.Publish(Event{Type: RunCompleted, RunID: "run-1", Time: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)})
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("sink wrote %d lines, want one per event:\n%s", len(lines), buf.String())
+	}
+	var first Event
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.Type != TaskRetried || first.TaskID != "task-1" || first.RunID != "run-1" || first.TaskType != types.TaskCode || first.Attempt != 2 || first.Time.IsZero() {
+		t.Errorf("first line = %+v, want the retried task", first)
+	}
+
+	// Los campos vacíos se omiten
+	if want := `{"type":"run.completed","time":"2026-01-01T00:00:00Z","run_id":"run-1"}`; lines[1] != want {
+		t.Errorf("second line = %s, want %s", lines[1], want)
+	}
+}
@@ -0,0 +1,834 @@
+// Package expr implementa el lenguaje de las condiciones de las políticas:
+// expresiones sin efectos secundarios sobre datos JSON (mapas, listas,
+// textos, números, booleanos y null).
+//
+//	result.test_result.coverage < 80 && task.type == "code"
+//	len(files_changed) > 50 || matches(files_changed, "migrations/**")
+//
+// Operadores, de menor a mayor precedencia: ||, &&, comparaciones (== != < <=
+// > >= in), + -, * / %, ! y - unarios. Acceso con a.b y a["b"] o a[0]; los
+// campos que no existen valen null. Funciones: len, contains, matches (glob),
+// startsWith y endsWith.
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nanochip/multi-agent/pkg/glob"
+)
+
+// maxDepth limita el anidamiento de una expresión
+const maxDepth = 64
+
+// Expr es una expresión ya interpretada
+type Expr struct {
+	source string
+	root   node
+	idents map[string]bool
+}
+
+// Parse interpreta una expresión
+func Parse(source string) (*Expr, error) {
+	p := &parser{lexer: lexer{src: source}, idents: make(map[string]bool)}
+	p.next()
+	root, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	return &Expr{source: source, root: root, idents: p.idents}, nil
+}
+
+// String retorna el texto original de la expresión
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Uses indica si la expresión lee la variable name
+func (e *Expr) Uses(name string) bool {
+	return e.idents[name]
+}
+
+// Idents retorna, ordenadas, las variables que lee la expresión
+func (e *Expr) Idents() []string {
+	names := make([]string, 0, len(e.idents))
+	for name := range e.idents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Eval evalúa la expresión con las variables dadas. Las variables que no
+// existen valen null.
+func (e *Expr) Eval(vars map[string]interface{}) (interface{}, error) {
+	return e.root.eval(vars)
+}
+
+// EvalBool evalúa una condición: el resultado debe ser un booleano (null
+// cuenta como false)
+func (e *Expr) EvalBool(vars map[string]interface{}) (bool, error) {
+	v, err := e.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	return truth(v)
+}
+
+// --- Evaluación ---
+
+type node interface {
+	eval(vars map[string]interface{}) (interface{}, error)
+}
+
+type literal struct{ value interface{} }
+
+type ident struct{ name string }
+
+type member struct {
+	x    node
+	name string
+}
+
+type index struct{ x, i node }
+
+type call struct {
+	fn   string
+	args []node
+}
+
+type unary struct {
+	op string
+	x  node
+}
+
+type binary struct {
+	op   string
+	l, r node
+}
+
+func (n literal) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n ident) eval(vars map[string]interface{}) (interface{}, error) {
+	return normalize(vars[n.name]), nil
+}
+
+func (n member) eval(vars map[string]interface{}) (interface{}, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return field(x, n.name)
+}
+
+func (n index) eval(vars map[string]interface{}) (interface{}, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	i, err := n.i.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch key := i.(type) {
+	case string:
+		return field(x, key)
+	case float64:
+		switch list := x.(type) {
+		case nil:
+			return nil, nil
+		case []interface{}:
+			if key != math.Trunc(key) {
+				return nil, fmt.Errorf("invalid index %v", key)
+			}
+			if key < 0 || int(key) >= len(list) {
+				return nil, nil
+			}
+			return normalize(list[int(key)]), nil
+		}
+		return nil, fmt.Errorf("cannot index %s with a number", typeName(x))
+	}
+	return nil, fmt.Errorf("invalid index of type %s", typeName(i))
+}
+
+// field retorna un campo de un mapa; el de null es null
+func field(x interface{}, name string) (interface{}, error) {
+	switch m := x.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return normalize(m[name]), nil
+	}
+	return nil, fmt.Errorf("cannot read field %q of %s", name, typeName(x))
+}
+
+func (n call) eval(vars map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := functions[n.fn].call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.fn, err)
+	}
+	return v, nil
+}
+
+func (n unary) eval(vars map[string]interface{}) (interface{}, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		b, err := truth(x)
+		return !b, err
+	default: // "-"
+		if x == nil {
+			return nil, nil
+		}
+		f, ok := x.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate %s", typeName(x))
+		}
+		return -f, nil
+	}
+}
+
+func (n binary) eval(vars map[string]interface{}) (interface{}, error) {
+	l, err := n.l.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	// && y || no evalúan el lado derecho si no hace falta
+	if n.op == "&&" || n.op == "||" {
+		lb, err := truth(l)
+		if err != nil {
+			return nil, err
+		}
+		if lb == (n.op == "||") {
+			return lb, nil
+		}
+		r, err := n.r.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		return truth(r)
+	}
+
+	r, err := n.r.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return reflect.DeepEqual(l, r), nil
+	case "!=":
+		return !reflect.DeepEqual(l, r), nil
+	case "<", "<=", ">", ">=":
+		return compare(n.op, l, r)
+	case "in":
+		return contains(r, l)
+	}
+	return arithmetic(n.op, l, r)
+}
+
+// compare compara dos números o dos textos. Con null el resultado es false.
+func compare(op string, l, r interface{}) (bool, error) {
+	if l == nil || r == nil {
+		return false, nil
+	}
+	var c int
+	switch lv := l.(type) {
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return false, fmt.Errorf("cannot compare number %s %s", op, typeName(r))
+		}
+		switch {
+		case lv < rv:
+			c = -1
+		case lv > rv:
+			c = 1
+		}
+	case string:
+		rv, ok := r.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot compare string %s %s", op, typeName(r))
+		}
+		c = strings.Compare(lv, rv)
+	default:
+		return false, fmt.Errorf("cannot compare %s %s %s", typeName(l), op, typeName(r))
+	}
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+// arithmetic aplica + - * / %. Con null el resultado es null.
+func arithmetic(op string, l, r interface{}) (interface{}, error) {
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	if ls, ok := l.(string); ok && op == "+" {
+		if rs, ok := r.(string); ok {
+			return ls + rs, nil
+		}
+	}
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("cannot apply %s to %s and %s", op, typeName(l), typeName(r))
+	}
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	}
+	if rf == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	if op == "/" {
+		return lf / rf, nil
+	}
+	return math.Mod(lf, rf), nil
+}
+
+// truth convierte un valor en condición: solo se admiten booleanos y null
+func truth(v interface{}) (bool, error) {
+	switch b := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return b, nil
+	}
+	return false, fmt.Errorf("expected a boolean, got %s", typeName(v))
+}
+
+// contains indica si x está en una lista, es clave de un mapa o es parte de un texto
+func contains(haystack, x interface{}) (bool, error) {
+	switch h := haystack.(type) {
+	case nil:
+		return false, nil
+	case []interface{}:
+		for _, item := range h {
+			if reflect.DeepEqual(normalize(item), x) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		key, ok := x.(string)
+		if !ok {
+			return false, fmt.Errorf("map keys are strings, got %s", typeName(x))
+		}
+		_, found := h[key]
+		return found, nil
+	case string:
+		sub, ok := x.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot look for %s in a string", typeName(x))
+		}
+		return strings.Contains(h, sub), nil
+	}
+	return false, fmt.Errorf("cannot look inside %s", typeName(haystack))
+}
+
+// normalize convierte los valores de Go más comunes a los del lenguaje
+// (números como float64, listas como []interface{})
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil, bool, float64, string, []interface{}, map[string]interface{}:
+		return v
+	case int:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case int32:
+		return float64(x)
+	case float32:
+		return float64(x)
+	case []string:
+		list := make([]interface{}, len(x))
+		for i, s := range x {
+			list[i] = s
+		}
+		return list
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Slice, reflect.Array:
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = normalize(rv.Index(i).Interface())
+		}
+		return list
+	}
+	return v
+}
+
+// typeName retorna el nombre del tipo de un valor en los mensajes de error
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// --- Funciones ---
+
+type function struct {
+	args int
+	call func(args []interface{}) (interface{}, error)
+}
+
+var functions = map[string]function{
+	// len retorna la longitud de una lista, un mapa o un texto (0 para null)
+	"len": {1, func(args []interface{}) (interface{}, error) {
+		switch x := args[0].(type) {
+		case nil:
+			return float64(0), nil
+		case []interface{}:
+			return float64(len(x)), nil
+		case map[string]interface{}:
+			return float64(len(x)), nil
+		case string:
+			return float64(utf8.RuneCountInString(x)), nil
+		}
+		return nil, fmt.Errorf("expected a list, map or string, got %s", typeName(args[0]))
+	}},
+	// contains(x, y) equivale a y in x
+	"contains": {2, func(args []interface{}) (interface{}, error) {
+		return contains(args[0], args[1])
+	}},
+	// matches indica si una ruta, o alguna de una lista, coincide con el patrón
+	"matches": {2, func(args []interface{}) (interface{}, error) {
+		pattern, ok := args[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("pattern must be a string, got %s", typeName(args[1]))
+		}
+		paths := []interface{}{args[0]}
+		if list, ok := args[0].([]interface{}); ok {
+			paths = list
+		}
+		for _, p := range paths {
+			if p == nil {
+				continue
+			}
+			s, ok := normalize(p).(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a path, got %s", typeName(p))
+			}
+			if matched, err := glob.Match(pattern, glob.Normalize("", s)); err != nil {
+				return nil, err
+			} else if matched {
+				return true, nil
+			}
+		}
+		return false, nil
+	}},
+	"startsWith": {2, stringFunc(strings.HasPrefix)},
+	"endsWith":   {2, stringFunc(strings.HasSuffix)},
+}
+
+// stringFunc adapta una función de dos textos (null cuenta como false)
+func stringFunc(f func(s, x string) bool) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return false, nil
+		}
+		s, ok1 := args[0].(string)
+		x, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("expected strings, got %s and %s", typeName(args[0]), typeName(args[1]))
+		}
+		return f(s, x), nil
+	}
+}
+
+// --- Análisis léxico ---
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	col   int // columna (1 = primera), en caracteres
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return "string " + strconv.Quote(t.value.(string))
+	}
+	return strconv.Quote(t.text)
+}
+
+// operators son los operadores y signos, los de dos caracteres primero
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", "[", "]", ".", ","}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) errorf(pos int, format string, args ...interface{}) error {
+	col := utf8.RuneCountInString(l.src[:pos]) + 1
+	return fmt.Errorf("column %d: %s", col, fmt.Sprintf(format, args...))
+}
+
+// next lee el siguiente token
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && strings.IndexByte(" \t\r\n", l.src[l.pos]) >= 0 {
+		l.pos++
+	}
+	start := l.pos
+	tok := token{col: utf8.RuneCountInString(l.src[:start]) + 1}
+	if l.pos >= len(l.src) {
+		return tok, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case isDigit(c):
+		for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		tok.kind, tok.text = tokNumber, l.src[start:l.pos]
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return tok, l.errorf(start, "invalid number %q", tok.text)
+		}
+		tok.value = f
+		return tok, nil
+
+	case c == '"' || c == '\'':
+		return l.str(tok, c)
+
+	case isLetter(c):
+		for l.pos < len(l.src) && (isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		tok.kind, tok.text = tokIdent, l.src[start:l.pos]
+		return tok, nil
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			tok.kind, tok.text = tokOp, op
+			return tok, nil
+		}
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return tok, l.errorf(start, "unexpected character %q", r)
+}
+
+// str lee un texto entre comillas simples o dobles con escapes de Go
+func (l *lexer) str(tok token, quote byte) (token, error) {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.src) && l.src[l.pos] != quote {
+		if l.src[l.pos] == '\\' {
+			l.pos++
+		}
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return tok, l.errorf(start, "unterminated string")
+	}
+	l.pos++
+
+	tok.kind, tok.text = tokString, l.src[start:l.pos]
+	body := tok.text[1 : len(tok.text)-1]
+	if quote == '\'' {
+		body = strings.ReplaceAll(strings.ReplaceAll(body, `\'`, `'`), `"`, `\"`)
+	}
+	s, err := strconv.Unquote(`"` + body + `"`)
+	if err != nil {
+		return tok, l.errorf(start, "invalid string %s", tok.text)
+	}
+	tok.value = s
+	return tok, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isLetter admite las letras ASCII y _ en los identificadores
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// --- Análisis sintáctico ---
+
+type parser struct {
+	lexer
+	tok    token
+	err    error
+	depth  int
+	idents map[string]bool
+}
+
+// next avanza al siguiente token; un error léxico se guarda y se informa al
+// consumir el token
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lexer.next()
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+	return fmt.Errorf("column %d: %s", p.tok.col, fmt.Sprintf(format, args...))
+}
+
+// precedence retorna la precedencia de un operador binario (0 si no lo es)
+func precedence(t token) int {
+	if t.kind == tokIdent && t.text == "in" {
+		return 3
+	}
+	if t.kind != tokOp {
+		return 0
+	}
+	switch t.text {
+	case "||":
+		return 1
+	case "&&":
+		return 2
+	case "==", "!=", "<", "<=", ">", ">=":
+		return 3
+	case "+", "-":
+		return 4
+	case "*", "/", "%":
+		return 5
+	}
+	return 0
+}
+
+// parseExpr interpreta operadores binarios de precedencia mayor que min
+func (p *parser) parseExpr(min int) (node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		return nil, p.errorf("expression nested too deeply")
+	}
+
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		prec := precedence(p.tok)
+		if prec <= min {
+			return left, nil
+		}
+		op := p.tok.text
+		p.next()
+		right, err := p.parseExpr(prec)
+		if err != nil {
+			return nil, err
+		}
+		// Las comparaciones no se encadenan (a < b < c)
+		if prec == 3 && precedence(p.tok) == 3 {
+			return nil, p.errorf("comparisons cannot be chained, use && or parentheses")
+		}
+		left = binary{op: op, l: left, r: right}
+	}
+}
+
+// parseUnary interpreta ! y - unarios
+func (p *parser) parseUnary() (node, error) {
+	if p.tok.kind == tokOp && (p.tok.text == "!" || p.tok.text == "-") {
+		op := p.tok.text
+		p.next()
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.depth > maxDepth {
+			return nil, p.errorf("expression nested too deeply")
+		}
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unary{op: op, x: x}, nil
+	}
+	return p.parsePostfix()
+}
+
+// parsePostfix interpreta un operando seguido de .campo e [índice]
+func (p *parser) parsePostfix() (node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp {
+		switch p.tok.text {
+		case ".":
+			p.next()
+			if p.tok.kind != tokIdent {
+				return nil, p.errorf("expected a field name after '.', got %s", p.tok)
+			}
+			x = member{x: x, name: p.tok.text}
+			p.next()
+		case "[":
+			p.next()
+			i, err := p.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = index{x: x, i: i}
+		default:
+			return x, nil
+		}
+	}
+	return x, nil
+}
+
+// parsePrimary interpreta literales, variables, llamadas y paréntesis
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.tok
+	if p.err != nil {
+		return nil, p.err
+	}
+	switch tok.kind {
+	case tokNumber, tokString:
+		p.next()
+		return literal{tok.value}, nil
+
+	case tokIdent:
+		p.next()
+		switch tok.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		case "null":
+			return literal{nil}, nil
+		case "in":
+			return nil, fmt.Errorf("column %d: unexpected \"in\"", tok.col)
+		}
+		if p.tok.kind == tokOp && p.tok.text == "(" {
+			return p.parseCall(tok)
+		}
+		p.idents[tok.text] = true
+		return ident{tok.text}, nil
+
+	case tokOp:
+		if tok.text == "(" {
+			p.next()
+			x, err := p.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	}
+	return nil, p.errorf("unexpected %s", tok)
+}
+
+// parseCall interpreta los argumentos de una llamada a función
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("column %d: unknown function %q", name.col, name.text)
+	}
+	p.next() // (
+	args := make([]node, 0, fn.args)
+	for !(p.tok.kind == tokOp && p.tok.text == ")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if len(args) != fn.args {
+		return nil, fmt.Errorf("column %d: %s expects %d argument(s), got %d", name.col, name.text, fn.args, len(args))
+	}
+	return call{fn: name.text, args: args}, nil
+}
+
+// expect consume el operador op o falla
+func (p *parser) expect(op string) error {
+	if p.tok.kind != tokOp || p.tok.text != op {
+		return p.errorf("expected %q, got %s", op, p.tok)
+	}
+	p.next()
+	return nil
+}
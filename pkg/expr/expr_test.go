@@ -0,0 +1,178 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+func testVars() map[string]interface{} {
+	return map[string]interface{}{
+		"task": map[string]interface{}{
+			"type":        "code",
+			"max_retries": 3,
+			"inputs":      map[string]interface{}{"files": []interface{}{"pkg/a.go"}},
+		},
+		"result": map[string]interface{}{
+			"success":     true,
+			"test_result": map[string]interface{}{"coverage": 72.5, "failed": 0.0},
+		},
+		"files_changed": []string{"pkg/a.go", "migrations/001.sql"},
+		"categories":    []interface{}{"security", "style"},
+		"name":          "O'Brien",
+	}
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want interface{}
+	}{
+		// Literales y aritmética
+		{`1 + 2 * 3`, 7.0},
+		{`(1 + 2) * 3`, 9.0},
+		{`10 / 4`, 2.5},
+		{`10 % 4`, 2.0},
+		{`-2 - -3`, 1.0},
+		{`"a" + 'b'`, "ab"},
+		{`"tab\t"`, "tab\t"},
+		{`'say "hi"'`, `say "hi"`},
+		{`null`, nil},
+
+		// Acceso a campos
+		{`task.type`, "code"},
+		{`task["type"]`, "code"},
+		{`task.max_retries`, 3.0},
+		{`task.inputs.files[0]`, "pkg/a.go"},
+		{`task.inputs.files[5]`, nil},
+		{`task.missing.deeper`, nil},
+		{`unknown`, nil},
+		{`files_changed[1]`, "migrations/001.sql"},
+
+		// Comparaciones
+		{`result.test_result.coverage < 80 && task.type == "code"`, true},
+		{`result.test_result.coverage >= 80`, false},
+		{`task.type != "test"`, true},
+		{`"abc" < "abd"`, true},
+		{`missing < 80`, false},
+		{`missing > 80`, false},
+		{`missing == null`, true},
+		{`task.max_retries == 3`, true},
+		{`"security" in categories`, true},
+		{`"lint" in categories`, false},
+		{`"type" in task`, true},
+		{`"Bri" in name`, true},
+		{`"x" in missing`, false},
+
+		// Lógica
+		{`!result.success`, false},
+		{`!missing`, true},
+		{`result.success || 1 / 0 > 1`, true},
+		{`!result.success && 1 / 0 > 1`, false},
+
+		// Funciones
+		{`len(files_changed)`, 2.0},
+		{`len(files_changed) > 50`, false},
+		{`len(missing)`, 0.0},
+		{`len("año")`, 3.0},
+		{`len(task)`, 3.0},
+		{`contains(categories, "style")`, true},
+		{`matches(files_changed, "migrations/**")`, true},
+		{`matches(files_changed, "**/*_test.go")`, false},
+		{`matches("./pkg/a.go", "pkg/*.go")`, true},
+		{`matches(missing, "**")`, false},
+		{`startsWith(task.type, "co")`, true},
+		{`endsWith(name, "en")`, true},
+		{`startsWith(missing, "x")`, false},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		got, err := e.Eval(testVars())
+		if err != nil {
+			t.Errorf("Eval(%q): %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Eval(%q) = %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{`task.type < 3`, "cannot compare string < number"},
+		{`task.type.name`, `cannot read field "name" of string`},
+		{`1 / 0`, "division by zero"},
+		{`task && true`, "expected a boolean, got map"},
+		{`-task.type`, "cannot negate string"},
+		{`len(1)`, "len: expected a list, map or string, got number"},
+		{`matches(files_changed, "[a")`, "matches: invalid pattern"},
+		{`files_changed[0.5]`, "invalid index 0.5"},
+		{`task[true]`, "invalid index of type boolean"},
+		{`1 in task`, "map keys are strings"},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if _, err := e.Eval(testVars()); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Eval(%q) error = %v, want %q", tt.expr, err, tt.err)
+		}
+	}
+
+	e, _ := Parse(`task.type`)
+	if _, err := e.EvalBool(testVars()); err == nil {
+		t.Error("EvalBool of a string: expected an error")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{``, "column 1: unexpected end of expression"},
+		{`a ==`, "column 5: unexpected end of expression"},
+		{`(a`, `column 3: expected ")", got end of expression`},
+		{`a b`, `column 3: unexpected "b"`},
+		{`1 < 2 < 3`, "comparisons cannot be chained"},
+		{`a @ b`, `column 3: unexpected character '@'`},
+		{`"abc`, "column 1: unterminated string"},
+		{`foo(1)`, `column 1: unknown function "foo"`},
+		{`len(1, 2)`, "len expects 1 argument(s), got 2"},
+		{`a.`, "expected a field name"},
+		{`a = 1`, `unexpected character '='`},
+		{`in`, `unexpected "in"`},
+		{`categories == ["security"]`, `column 15: unexpected "["`},
+		{strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100), "nested too deeply"},
+		{strings.Repeat("!", 100) + "true", "nested too deeply"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.expr); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.expr, err, tt.err)
+		}
+	}
+}
+
+func TestUses(t *testing.T) {
+	e, err := Parse(`len(files_changed) > 0 && task.type == "code"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"files_changed": true, "task": true, "result": false, "len": false, "type": false} {
+		if got := e.Uses(name); got != want {
+			t.Errorf("Uses(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if got := strings.Join(e.Idents(), ","); got != "files_changed,task" {
+		t.Errorf("Idents() = %s, want files_changed,task", got)
+	}
+}
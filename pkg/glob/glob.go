@@ -0,0 +1,262 @@
+// Package glob compara rutas relativas al repositorio con patrones estilo
+// doublestar, los que usan los contratos de los agentes y las políticas:
+//
+//	**        cero o más directorios completos (src/**, **/*_test.go)
+//	*         cualquier secuencia de caracteres dentro de un segmento
+//	?         un carácter dentro de un segmento
+//	[abc]     un carácter de la clase; admite rangos ([a-z]) y negación ([!a] o [^a])
+//	\x        el carácter x literal
+//	!patrón   niega el patrón completo
+//
+// Las rutas se comparan con "/" como separador y sin "./" inicial (ver Normalize).
+package glob
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Match indica si name coincide con pattern. Retorna un error si el patrón
+// está mal formado.
+func Match(pattern, name string) (bool, error) {
+	negated := strings.HasPrefix(pattern, "!")
+	if negated {
+		pattern = pattern[1:]
+	}
+	if err := Validate(pattern); err != nil {
+		return false, err
+	}
+	matched := matchSegments(split(pattern), split(name))
+	return matched != negated, nil
+}
+
+// MatchAny evalúa una lista de patrones en orden, como .gitignore: un patrón
+// que coincide incluye la ruta y uno negado que coincide la vuelve a excluir
+// ("pkg/**", "!pkg/internal/**"). Una lista que empieza por una negación
+// parte de que todo coincide.
+func MatchAny(patterns []string, name string) (bool, error) {
+	matched := len(patterns) > 0 && strings.HasPrefix(patterns[0], "!")
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		if negated == !matched {
+			// El patrón no puede cambiar el resultado, pero debe ser válido
+			if err := Validate(strings.TrimPrefix(pattern, "!")); err != nil {
+				return false, err
+			}
+			continue
+		}
+		ok, err := Match(strings.TrimPrefix(pattern, "!"), name)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			matched = !negated
+		}
+	}
+	return matched, nil
+}
+
+// Validate verifica que un patrón (sin "!" inicial) esté bien formado
+func Validate(pattern string) error {
+	for _, segment := range split(pattern) {
+		if segment == "**" {
+			continue
+		}
+		if _, err := matchSegment(segment, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Normalize convierte una ruta en relativa a root con "/" como separador y
+// sin "./" ni "/" finales. Las rutas absolutas fuera de root (o cualquier
+// absoluta si root es "") se conservan; ver Inside.
+func Normalize(root, name string) string {
+	if root != "" && filepath.IsAbs(name) {
+		if absRoot, err := filepath.Abs(root); err == nil {
+			if rel, err := filepath.Rel(absRoot, name); err == nil {
+				name = rel
+			}
+		}
+	}
+	name = path.Clean(filepath.ToSlash(name))
+	if name == "." {
+		return ""
+	}
+	return name
+}
+
+// Inside indica si una ruta normalizada queda dentro del repositorio
+func Inside(name string) bool {
+	return name != ".." && !strings.HasPrefix(name, "../") && !path.IsAbs(name)
+}
+
+// split divide un patrón o una ruta en segmentos
+func split(s string) []string {
+	s = strings.Trim(s, "/")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "/")
+}
+
+// matchSegments compara segmento a segmento; "**" absorbe cero o más segmentos
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Varios "**" seguidos equivalen a uno
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range name {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := matchSegment(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// matchSegment compara un segmento con *, ? y clases. Recorre el patrón
+// completo aunque no coincida para detectar los mal formados.
+func matchSegment(pattern, name string) (bool, error) {
+	matched := true
+	for len(pattern) > 0 {
+		if pattern[0] == '*' {
+			pattern = strings.TrimLeft(pattern, "*")
+			if pattern == "" {
+				return matched, nil
+			}
+			// Probar cada sufijo de name contra el resto del patrón
+			for i := 0; i <= len(name); i++ {
+				if ok, err := matchSegment(pattern, name[i:]); err != nil {
+					return false, err
+				} else if ok {
+					return matched, nil
+				}
+			}
+			_, err := matchSegment(pattern, "")
+			return false, err
+		}
+
+		var ok bool
+		var err error
+		ok, pattern, name, err = matchChar(pattern, name)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			matched = false
+		}
+	}
+	return matched && name == "", nil
+}
+
+// matchChar consume un elemento del patrón (literal, \x, ? o clase) y un
+// carácter de name
+func matchChar(pattern, name string) (bool, string, string, error) {
+	var r rune
+	var size int
+	if name != "" {
+		r, size = utf8.DecodeRuneInString(name)
+	}
+	rest := name[size:]
+
+	switch pattern[0] {
+	case '?':
+		return name != "", pattern[1:], rest, nil
+
+	case '[':
+		class, remaining, err := parseClass(pattern[1:])
+		if err != nil {
+			return false, "", "", err
+		}
+		return name != "" && class.matches(r), remaining, rest, nil
+
+	case '\\':
+		if len(pattern) < 2 {
+			return false, "", "", fmt.Errorf("trailing backslash")
+		}
+		pattern = pattern[1:]
+	}
+
+	literal, n := utf8.DecodeRuneInString(pattern)
+	return name != "" && literal == r, pattern[n:], rest, nil
+}
+
+// class es una clase de caracteres: [a-z0-9] o negada con [!...] o [^...]
+type class struct {
+	negated bool
+	ranges  [][2]rune
+}
+
+// parseClass interpreta una clase a partir del carácter siguiente a "["
+func parseClass(pattern string) (class, string, error) {
+	c := class{}
+	if pattern != "" && (pattern[0] == '!' || pattern[0] == '^') {
+		c.negated = true
+		pattern = pattern[1:]
+	}
+	for first := true; ; first = false {
+		if pattern == "" {
+			return c, "", fmt.Errorf("unterminated character class")
+		}
+		// "]" como primer carácter es literal ([]a])
+		if pattern[0] == ']' && !first {
+			return c, pattern[1:], nil
+		}
+		lo, rest, err := classChar(pattern)
+		if err != nil {
+			return c, "", err
+		}
+		hi := lo
+		if len(rest) > 1 && rest[0] == '-' && rest[1] != ']' {
+			if hi, rest, err = classChar(rest[1:]); err != nil {
+				return c, "", err
+			}
+			if hi < lo {
+				return c, "", fmt.Errorf("invalid range %c-%c", lo, hi)
+			}
+		}
+		c.ranges = append(c.ranges, [2]rune{lo, hi})
+		pattern = rest
+	}
+}
+
+// classChar lee un carácter de una clase, admitiendo \x
+func classChar(pattern string) (rune, string, error) {
+	if pattern[0] == '\\' {
+		pattern = pattern[1:]
+		if pattern == "" {
+			return 0, "", fmt.Errorf("trailing backslash")
+		}
+	}
+	r, n := utf8.DecodeRuneInString(pattern)
+	return r, pattern[n:], nil
+}
+
+// matches indica si r pertenece a la clase
+func (c class) matches(r rune) bool {
+	for _, rng := range c.ranges {
+		if r >= rng[0] && r <= rng[1] {
+			return !c.negated
+		}
+	}
+	return c.negated
+}
@@ -0,0 +1,192 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		// Literales
+		{"go.mod", "go.mod", true},
+		{"go.mod", "go.sum", false},
+		{"cmd/main.go", "cmd/main.go", true},
+		{"cmd/main.go", "cmd/cli/main.go", false},
+		{"", "", true},
+
+		// *
+		{"*", "main.go", true},
+		{"*", "cmd/main.go", false},
+		{"*.go", "main.go", true},
+		{"*.go", "main.go.orig", false},
+		{"*_test.go", "engine_test.go", true},
+		{"*_test.go", "engine.go", false},
+		{"cmd/*/main.go", "cmd/cli/main.go", true},
+		{"cmd/*/main.go", "cmd/main.go", false},
+		{"a*b*c", "abc", true},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+
+		// ?
+		{"v?.go", "v1.go", true},
+		{"v?.go", "v10.go", false},
+		{"v?.go", "v.go", false},
+		{"?", "/", false},
+		{"año?.txt", "año1.txt", true},
+
+		// Clases
+		{"v[0-9].go", "v7.go", true},
+		{"v[0-9].go", "vx.go", false},
+		{"[abc].go", "b.go", true},
+		{"[abc].go", "d.go", false},
+		{"[!abc].go", "d.go", true},
+		{"[!abc].go", "a.go", false},
+		{"[^abc].go", "a.go", false},
+		{"[a-cx-z].go", "y.go", true},
+		{"[]].go", "].go", true},
+		{"[a-].go", "-.go", true},
+		{"[\\]].go", "].go", true},
+
+		// Escapes
+		{"\\*.go", "*.go", true},
+		{"\\*.go", "a.go", false},
+		{"\\[a].go", "[a].go", true},
+
+		// **
+		{"**", "", true},
+		{"**", "a", true},
+		{"**", "a/b/c.go", true},
+		{"src/**", "src", true},
+		{"src/**", "src/main.go", true},
+		{"src/**", "src/a/b/main.go", true},
+		{"src/**", "srcs/main.go", false},
+		{"src/**", "lib/src/main.go", false},
+		{"**/*_test.go", "engine_test.go", true},
+		{"**/*_test.go", "pkg/policies/engine_test.go", true},
+		{"**/*_test.go", "pkg/policies/engine.go", false},
+		{"**/Dockerfile", "Dockerfile", true},
+		{"**/Dockerfile", "deploy/api/Dockerfile", true},
+		{"**/Dockerfile", "Dockerfile.dev", false},
+		{"pkg/**/*.go", "pkg/main.go", true},
+		{"pkg/**/*.go", "pkg/a/b/main.go", true},
+		{"pkg/**/*.go", "cmd/main.go", false},
+		{"a/**/b/**/c", "a/b/c", true},
+		{"a/**/b/**/c", "a/x/b/y/z/c", true},
+		{"a/**/b/**/c", "a/x/y/c", false},
+		{"a/**/**/b", "a/b", true},
+		{"**/*", "main.go", true},
+		{"**/*", "a/b/main.go", true},
+		{"**/*", "", false},
+
+		// Negación
+		{"!vendor/**", "vendor/x/y.go", false},
+		{"!vendor/**", "pkg/y.go", true},
+		{"!**/*.go", "README.md", true},
+
+		// Barras sobrantes
+		{"/src/**", "src/main.go", true},
+		{"src/", "src", true},
+	}
+	for _, tt := range tests {
+		got, err := Match(tt.pattern, tt.name)
+		if err != nil {
+			t.Errorf("Match(%q, %q): unexpected error: %v", tt.pattern, tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchInvalidPattern(t *testing.T) {
+	for _, pattern := range []string{"[a", "a[", "[!", "a\\", "[z-a]", "src/[a/**", "!*[", "*["} {
+		if _, err := Match(pattern, "anything"); err == nil {
+			t.Errorf("Match(%q): expected an error", pattern)
+		}
+		if err := Validate(pattern); err == nil && pattern[0] != '!' {
+			t.Errorf("Validate(%q): expected an error", pattern)
+		}
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		name     string
+		want     bool
+	}{
+		{nil, "main.go", false},
+		{[]string{}, "main.go", false},
+		{[]string{"src/**", "pkg/**"}, "pkg/a.go", true},
+		{[]string{"src/**", "pkg/**"}, "cmd/a.go", false},
+		{[]string{"pkg/**", "!pkg/internal/**"}, "pkg/a.go", true},
+		{[]string{"pkg/**", "!pkg/internal/**"}, "pkg/internal/a.go", false},
+		{[]string{"pkg/**", "!pkg/internal/**", "pkg/internal/ok.go"}, "pkg/internal/ok.go", true},
+		{[]string{"**/*.go", "!**/*_test.go"}, "pkg/a.go", true},
+		{[]string{"**/*.go", "!**/*_test.go"}, "pkg/a_test.go", false},
+		{[]string{"!vendor/**"}, "pkg/a.go", true},
+		{[]string{"!vendor/**"}, "vendor/a.go", false},
+	}
+	for _, tt := range tests {
+		got, err := MatchAny(tt.patterns, tt.name)
+		if err != nil {
+			t.Errorf("MatchAny(%q, %q): unexpected error: %v", tt.patterns, tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("MatchAny(%q, %q) = %v, want %v", tt.patterns, tt.name, got, tt.want)
+		}
+	}
+
+	// Un patrón inválido es un error aunque no llegue a evaluarse
+	if _, err := MatchAny([]string{"**", "src/[a"}, "main.go"); err == nil {
+		t.Error("MatchAny with an invalid pattern: expected an error")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		root string
+		name string
+		want string
+	}{
+		{"", "main.go", "main.go"},
+		{"", "./pkg/a.go", "pkg/a.go"},
+		{"", "pkg//a.go", "pkg/a.go"},
+		{"", "pkg/../cmd/a.go", "cmd/a.go"},
+		{"", "pkg/", "pkg"},
+		{"", ".", ""},
+		{"", "/repo/pkg/a.go", "/repo/pkg/a.go"},
+		{"/repo", "/repo/pkg/a.go", "pkg/a.go"},
+		{"/repo/", "/repo/pkg/a.go", "pkg/a.go"},
+		{"/repo", "pkg/a.go", "pkg/a.go"},
+		{"/repo", "/repo", ""},
+		{"/repo", "/other/a.go", "../other/a.go"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.root, tt.name); got != tt.want {
+			t.Errorf("Normalize(%q, %q) = %q, want %q", tt.root, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInside(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"", true},
+		{"pkg/a.go", true},
+		{"..foo/a.go", true},
+		{"..", false},
+		{"../a.go", false},
+		{"/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		if got := Inside(tt.name); got != tt.want {
+			t.Errorf("Inside(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
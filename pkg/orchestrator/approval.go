@@ -0,0 +1,241 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/events"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// Decisiones humanas aceptadas por RequestControl
+const (
+	ControlApprove = "approve"
+	ControlReject  = "reject"
+)
+
+// DefaultApprovalTimeout es cuánto espera un cambio de alto riesgo su aprobación
+const DefaultApprovalTimeout = 24 * time.Hour
+
+// approval es un resultado de alto riesgo retenido hasta que lo aprueben
+type approval struct {
+	task      *types.Task
+	result    *types.TaskResult
+	required  int
+	approvers []string
+	timer     *time.Timer
+}
+
+// SetApprovalTimeout fija cuánto se espera una aprobación antes de dar el
+// cambio por rechazado (0 = sin límite). Debe llamarse antes de Start.
+func (o *Orchestrator) SetApprovalTimeout(d time.Duration) {
+	o.awaitLimit = d
+}
+
+// awaitApproval retiene el resultado de alto riesgo de una tarea. La tarea y
+// su ejecución quedan en awaiting-approval y el worker queda libre.
+func (o *Orchestrator) awaitApproval(task *types.Task, result *types.TaskResult, required int, reasons []string) {
+	reason := fmt.Sprintf("high-risk change needs %d approval(s)", required)
+	if len(reasons) > 0 {
+		reason += ": " + strings.Join(reasons, "; ")
+	}
+	o.recordDecision(task, types.Decision{
+		Agent:      "orchestrator",
+		Reason:     reason,
+		Action:     "request-approval",
+		Timestamp:  time.Now(),
+		Confidence: 1,
+		Metadata:   map[string]interface{}{"required": required, "risk": reasons},
+	})
+
+	o.transition(task, types.StateAwaitingApproval, nil)
+
+	o.mu.Lock()
+	a := &approval{task: task, result: result, required: required}
+	if o.awaitLimit > 0 {
+		timeout := o.awaitLimit
+		a.timer = time.AfterFunc(timeout, func() { o.expireApproval(task.ID, timeout) })
+	}
+	o.approvals[task.ID] = a
+	o.setRunState(task.RunID)
+	o.mu.Unlock()
+
+	event := events.ForTask(events.ApprovalRequired, task)
+	event.Message = reason
+	o.events.Publish(event)
+}
+
+// Approve aprueba los cambios retenidos de una ejecución o de una tarea y
+// sus descendientes. Cuando un cambio reúne las aprobaciones necesarias la
+// tarea continúa según el pipeline. approver debe ser la identidad
+// autenticada de quien decide: cada una cuenta una sola vez.
+func (o *Orchestrator) Approve(id, approver, comment string) error {
+	return o.decide(id, approver, comment, true)
+}
+
+// Reject rechaza los cambios retenidos de una ejecución o de una tarea y sus
+// descendientes: las tareas terminan como fallidas.
+func (o *Orchestrator) Reject(id, approver, comment string) error {
+	return o.decide(id, approver, comment, false)
+}
+
+// decide registra la decisión humana sobre los cambios retenidos bajo id
+func (o *Orchestrator) decide(id, approver, comment string, approve bool) error {
+	if approver == "" {
+		return errors.New("approver identity is required")
+	}
+
+	o.mu.Lock()
+	marks := map[string]bool{id: true}
+	pending := make([]*approval, 0)
+	for _, a := range o.approvals {
+		if o.flagged(marks, a.task) {
+			pending = append(pending, a)
+		}
+	}
+	if len(pending) == 0 {
+		o.mu.Unlock()
+		return fmt.Errorf("%s has no changes awaiting approval", id)
+	}
+	for _, a := range pending {
+		for _, previous := range a.approvers {
+			if approve && previous == approver {
+				o.mu.Unlock()
+				return fmt.Errorf("%s already approved %s", approver, a.task.ID)
+			}
+		}
+	}
+
+	resolved := make([]*approval, 0)
+	for _, a := range pending {
+		if approve {
+			a.approvers = append(a.approvers, approver)
+			if len(a.approvers) < a.required {
+				continue
+			}
+		}
+		if a.timer != nil {
+			a.timer.Stop()
+		}
+		delete(o.approvals, a.task.ID)
+		resolved = append(resolved, a)
+	}
+	o.mu.Unlock()
+
+	action, reason := ControlReject, "rejected without comment"
+	if approve {
+		action, reason = ControlApprove, "approved without comment"
+	}
+	if comment != "" {
+		reason = comment
+	}
+	for _, a := range pending {
+		o.recordDecision(a.task, types.Decision{
+			Agent:      "human",
+			Reason:     reason,
+			Action:     action,
+			Timestamp:  time.Now(),
+			Confidence: 1,
+			Metadata:   map[string]interface{}{"approver": approver},
+		})
+	}
+
+	for _, a := range resolved {
+		if !approve {
+			message := fmt.Sprintf("change rejected by %s", approver)
+			if comment != "" {
+				message += ": " + comment
+			}
+			rejectResult(a.result, message)
+		}
+		o.resumeApproved(a)
+	}
+	return nil
+}
+
+// expireApproval rechaza un cambio que no se aprobó a tiempo
+func (o *Orchestrator) expireApproval(taskID string, timeout time.Duration) {
+	o.mu.Lock()
+	a, ok := o.approvals[taskID]
+	if ok {
+		delete(o.approvals, taskID)
+	}
+	o.mu.Unlock()
+	if !ok || o.ctx.Err() != nil {
+		return
+	}
+
+	message := fmt.Sprintf("approval timed out after %v", timeout)
+	o.recordDecision(a.task, types.Decision{
+		Agent:      "orchestrator",
+		Reason:     message,
+		Action:     "approval-timeout",
+		Timestamp:  time.Now(),
+		Confidence: 1,
+		Metadata:   map[string]interface{}{"approvers": a.approvers},
+	})
+	rejectResult(a.result, message)
+	o.resumeApproved(a)
+}
+
+// resumeApproved devuelve la ejecución a running y cierra la tarea con su resultado
+func (o *Orchestrator) resumeApproved(a *approval) {
+	o.mu.Lock()
+	o.setRunState(a.task.RunID)
+	o.mu.Unlock()
+	o.completeTask(a.task, a.result)
+}
+
+// setRunState marca la ejecución como awaiting-approval mientras retenga
+// algún cambio, y como running si no (requiere o.mu tomado)
+func (o *Orchestrator) setRunState(runID string) {
+	run, ok := o.runs[runID]
+	if !ok || run.CompletedAt != nil {
+		return
+	}
+	state := types.StateRunning
+	for _, a := range o.approvals {
+		if a.task.RunID == runID {
+			state = types.StateAwaitingApproval
+			break
+		}
+	}
+	if run.State != state {
+		run.State = state
+		o.persistRun(run)
+	}
+}
+
+// recordDecision guarda una decisión en la memoria y la publica
+func (o *Orchestrator) recordDecision(task *types.Task, decision types.Decision) {
+	decision.TaskID = task.ID
+	if err := o.store.AppendDecisions(decision); err != nil {
+		log.Printf("store: failed to record decisions for %s: %v", task.ID, err)
+	}
+	event := events.ForTask(events.DecisionRecorded, task)
+	event.Decision = &decision
+	o.events.Publish(event)
+}
+
+// rejectResult convierte el resultado retenido en un fallo
+func rejectResult(result *types.TaskResult, message string) {
+	result.Success = false
+	result.State = types.StateFailed
+	result.Error = message
+}
+
+// stopApprovals cancela las esperas de aprobación. Las tareas siguen en
+// awaiting-approval en el store, para Recover.
+func (o *Orchestrator) stopApprovals() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for id, a := range o.approvals {
+		if a.timer != nil {
+			a.timer.Stop()
+		}
+		delete(o.approvals, id)
+	}
+}
@@ -0,0 +1,155 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/policies"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// riskyOrchestrator crea un orchestrator en el que cada tarea termina con un
+// cambio de alto riesgo que necesita required aprobaciones
+func riskyOrchestrator(t *testing.T, required int, configure ...func(o *Orchestrator)) *Orchestrator {
+	return testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		result := succeed(task)
+		result.Outputs = map[string]interface{}{"risk_level": policies.RiskHigh}
+		return result
+	}, append([]func(o *Orchestrator){func(o *Orchestrator) {
+		o.policy.SetRequiredApprovals(required)
+	}}, configure...)...)
+}
+
+// parkChange envía una tarea de código y espera a que quede retenida
+// esperando aprobación, junto con su ejecución
+func parkChange(t *testing.T, o *Orchestrator) *types.Task {
+	t.Helper()
+	task := &types.Task{Type: types.TaskCode, Objective: "rotate the signing keys"}
+	if err := o.SubmitTask(task); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the change to await approval", func() bool {
+		return stateOf(o, task.ID) == types.StateAwaitingApproval
+	})
+	run, _, err := o.GetRun(task.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.State != types.StateAwaitingApproval {
+		t.Errorf("run state = %s, want %s while the change is parked", run.State, types.StateAwaitingApproval)
+	}
+	return task
+}
+
+// waitRun espera a que termine la ejecución de la tarea
+func waitRun(t *testing.T, o *Orchestrator, task *types.Task) *types.RunResult {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := o.Wait(ctx, task.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// humanDecisions retorna las decisiones humanas registradas para la tarea
+func humanDecisions(o *Orchestrator, taskID string) []types.Decision {
+	decisions := make([]types.Decision, 0)
+	for _, decision := range o.GetMemory() {
+		if decision.TaskID == taskID && decision.Agent == "human" {
+			decisions = append(decisions, decision)
+		}
+	}
+	return decisions
+}
+
+func TestApproveResumesParkedChange(t *testing.T) {
+	o := riskyOrchestrator(t, 1)
+	task := parkChange(t, o)
+
+	if err := o.Approve(task.RunID, "", "lgtm"); err == nil || !strings.Contains(err.Error(), "approver identity is required") {
+		t.Errorf("Approve() without an approver = %v, want an identity error", err)
+	}
+	if err := o.Approve("run-unknown", "ana", ""); err == nil || !strings.Contains(err.Error(), "no changes awaiting approval") {
+		t.Errorf("Approve() of an unknown run = %v, want nothing to approve", err)
+	}
+	if err := o.Approve(task.RunID, "ana", "lgtm"); err != nil {
+		t.Fatal(err)
+	}
+
+	result := waitRun(t, o, task)
+	if !result.Success() || taskStates(result)[task.ID] != types.StateSuccess {
+		t.Errorf("run = %s with task %s, want both succeeded after the approval", result.Run.State, taskStates(result)[task.ID])
+	}
+	decisions := humanDecisions(o, task.ID)
+	if len(decisions) != 1 || decisions[0].Action != ControlApprove || decisions[0].Metadata["approver"] != "ana" || decisions[0].Reason != "lgtm" {
+		t.Errorf("human decisions = %+v, want the approval by ana", decisions)
+	}
+}
+
+func TestRejectFailsParkedChange(t *testing.T) {
+	o := riskyOrchestrator(t, 1)
+	task := parkChange(t, o)
+
+	if err := o.Reject(task.ID, "bob", "touches production keys"); err != nil {
+		t.Fatal(err)
+	}
+	result := waitRun(t, o, task)
+	if result.Success() || taskStates(result)[task.ID] != types.StateFailed {
+		t.Errorf("run = %s with task %s, want both failed after the rejection", result.Run.State, taskStates(result)[task.ID])
+	}
+	if want := "change rejected by bob: touches production keys"; result.Results[task.ID].Error != want {
+		t.Errorf("task error = %q, want %q", result.Results[task.ID].Error, want)
+	}
+
+	// Ya no queda nada que decidir
+	if err := o.Approve(task.ID, "ana", ""); err == nil {
+		t.Error("Approve() of a rejected change should fail")
+	}
+}
+
+func TestApprovalCountsDistinctApprovers(t *testing.T) {
+	o := riskyOrchestrator(t, 2)
+	task := parkChange(t, o)
+
+	if err := o.Approve(task.RunID, "ana", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Approve(task.ID, "ana", "again"); err == nil || !strings.Contains(err.Error(), "ana already approved") {
+		t.Errorf("second Approve() by ana = %v, want a duplicate approval error", err)
+	}
+	if state := stateOf(o, task.ID); state != types.StateAwaitingApproval {
+		t.Fatalf("task state after 1 of 2 approvals = %s, want %s", state, types.StateAwaitingApproval)
+	}
+
+	if err := o.Approve(task.RunID, "bob", ""); err != nil {
+		t.Fatal(err)
+	}
+	if result := waitRun(t, o, task); !result.Success() {
+		t.Errorf("run = %s, want success after 2 approvals", result.Run.State)
+	}
+	if decisions := humanDecisions(o, task.ID); len(decisions) != 2 {
+		t.Errorf("human decisions = %+v, want the approvals by ana and bob", decisions)
+	}
+}
+
+func TestApprovalTimesOut(t *testing.T) {
+	o := riskyOrchestrator(t, 1, func(o *Orchestrator) {
+		o.SetApprovalTimeout(200 * time.Millisecond)
+	})
+	task := parkChange(t, o)
+
+	result := waitRun(t, o, task)
+	if result.Success() || taskStates(result)[task.ID] != types.StateFailed {
+		t.Errorf("run = %s with task %s, want both failed after the timeout", result.Run.State, taskStates(result)[task.ID])
+	}
+	if err := result.Results[task.ID].Error; !strings.Contains(err, "approval timed out after 200ms") {
+		t.Errorf("task error = %q, want an approval timeout", err)
+	}
+	if err := o.Approve(task.ID, "ana", ""); err == nil {
+		t.Error("Approve() after the timeout should fail")
+	}
+}
@@ -0,0 +1,162 @@
+package orchestrator
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/evaluation"
+	"github.com/nanochip/multi-agent/pkg/events"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// Budget limita lo que puede consumir una ejecución antes de detenerse con
+// el veredicto needs-human. Un valor 0 desactiva el límite correspondiente.
+type Budget struct {
+	MaxRepairs      int           // iteraciones de reparación por ejecución
+	MaxTasks        int           // tareas totales por ejecución
+	Deadline        time.Duration // tiempo máximo desde que el proceso toma la ejecución
+	MaxSameFailures int           // veces que puede repetirse el mismo fallo
+}
+
+// DefaultBudget retorna los límites por defecto
+func DefaultBudget() Budget {
+	return Budget{
+		MaxRepairs:      5,
+		MaxTasks:        50,
+		Deadline:        time.Hour,
+		MaxSameFailures: 2,
+	}
+}
+
+// runBudget lleva la cuenta de lo consumido por una ejecución
+type runBudget struct {
+	started  time.Time
+	tasks    int
+	repairs  int
+	failures map[string]int // huella del fallo -> veces vista
+	halted   string         // motivo de la detención ("" si sigue activa)
+}
+
+// SetBudget configura los límites por ejecución. Debe llamarse antes de Start.
+func (o *Orchestrator) SetBudget(b Budget) {
+	o.budget = b
+}
+
+// budgetFor retorna la cuenta de una ejecución, creándola si no existe
+// (requiere o.mu tomado)
+func (o *Orchestrator) budgetFor(runID string) *runBudget {
+	b, ok := o.budgets[runID]
+	if !ok {
+		b = &runBudget{started: time.Now(), failures: make(map[string]int)}
+		o.budgets[runID] = b
+	}
+	return b
+}
+
+// countTask suma una tarea admitida al presupuesto de su ejecución
+// (requiere o.mu tomado)
+func (o *Orchestrator) countTask(task *types.Task) {
+	b := o.budgetFor(task.RunID)
+	b.tasks++
+	if task.Type == types.TaskRepair {
+		b.repairs++
+	}
+}
+
+// checkBudget decide si la ejecución puede continuar con las tareas
+// siguientes. Retorna el motivo si algún límite se ha superado.
+func (o *Orchestrator) checkBudget(task *types.Task, result *types.TaskResult, next []*types.Task) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	b := o.budgetFor(task.RunID)
+	if b.halted != "" {
+		return b.halted
+	}
+	if len(next) == 0 {
+		return ""
+	}
+
+	if reason := o.deadlineExceeded(b); reason != "" {
+		return reason
+	}
+
+	// El mismo fallo una y otra vez: las reparaciones no avanzan. Los
+	// resultados exitosos no cuentan aunque repitan hallazgos menores.
+	if fp := evaluation.Fingerprint(result); fp != "" {
+		key := task.Stage + ":" + fp
+		b.failures[key]++
+		if o.budget.MaxSameFailures > 0 && b.failures[key] >= o.budget.MaxSameFailures {
+			return fmt.Sprintf("same failure in stage %s seen %d times (fingerprint %s)", task.Stage, b.failures[key], fp)
+		}
+	}
+
+	repairs := 0
+	for _, t := range next {
+		if t.Type == types.TaskRepair {
+			repairs++
+		}
+	}
+	if o.budget.MaxRepairs > 0 && repairs > 0 && b.repairs+repairs > o.budget.MaxRepairs {
+		return fmt.Sprintf("repair budget of %d iterations exhausted", o.budget.MaxRepairs)
+	}
+	if o.budget.MaxTasks > 0 && b.tasks+len(next) > o.budget.MaxTasks {
+		return fmt.Sprintf("task budget of %d tasks exhausted", o.budget.MaxTasks)
+	}
+	return ""
+}
+
+// deadlineExceeded retorna el motivo si la ejecución superó su plazo
+// (requiere o.mu tomado)
+func (o *Orchestrator) deadlineExceeded(b *runBudget) string {
+	if o.budget.Deadline > 0 && time.Since(b.started) > o.budget.Deadline {
+		return fmt.Sprintf("deadline of %v exceeded", o.budget.Deadline)
+	}
+	return ""
+}
+
+// checkDeadline detiene la ejecución si superó su plazo y retorna el motivo
+// por el que está detenida ("" si puede continuar)
+func (o *Orchestrator) checkDeadline(runID string) string {
+	o.mu.Lock()
+	b := o.budgetFor(runID)
+	halted, exceeded := b.halted, o.deadlineExceeded(b)
+	o.mu.Unlock()
+
+	if halted != "" {
+		return halted
+	}
+	if exceeded != "" {
+		o.haltRun(runID, exceeded)
+	}
+	return exceeded
+}
+
+// haltRun detiene una ejecución: no se generan más tareas y las que
+// esperaban dependencias se omiten. La ejecución termina como needs-human.
+func (o *Orchestrator) haltRun(runID, reason string) {
+	o.mu.Lock()
+	b := o.budgetFor(runID)
+	if b.halted != "" {
+		o.mu.Unlock()
+		return
+	}
+	b.halted = reason
+
+	pending := make([]*types.Task, 0)
+	for id, task := range o.waiting {
+		if task.RunID == runID {
+			pending = append(pending, task)
+			delete(o.waiting, id)
+		}
+	}
+	o.mu.Unlock()
+
+	log.Printf("run %s halted: %s", runID, reason)
+	o.events.Publish(events.Event{Type: events.RunHalted, RunID: runID, State: types.StateNeedsHuman, Message: reason})
+
+	for _, task := range pending {
+		o.closeTask(task, types.StateSkipped, "run halted: "+reason)
+	}
+}
@@ -0,0 +1,149 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/pipeline"
+	"github.com/nanochip/multi-agent/pkg/policies"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// budgetOrchestrator crea un orchestrator sin arrancar con el presupuesto dado
+func budgetOrchestrator(t *testing.T, b Budget) *Orchestrator {
+	t.Helper()
+	ws, _ := testRepo(t)
+	o := New(ws, policies.NewEngine())
+	o.SetBudget(b)
+	t.Cleanup(o.Stop)
+	return o
+}
+
+// tasksOf retorna n tareas del tipo indicado
+func tasksOf(taskType types.TaskType, n int) []*types.Task {
+	tasks := make([]*types.Task, n)
+	for i := range tasks {
+		tasks[i] = &types.Task{Type: taskType}
+	}
+	return tasks
+}
+
+func TestBudgetSameFailure(t *testing.T) {
+	o := budgetOrchestrator(t, Budget{MaxSameFailures: 2})
+	task := &types.Task{ID: "t", RunID: "run", Stage: "test"}
+	next := tasksOf(types.TaskRepair, 1)
+
+	if reason := o.checkBudget(task, fail(task, "FAIL: TestGet after 0.5s"), next); reason != "" {
+		t.Fatalf("first failure halted the run: %s", reason)
+	}
+	// Otro fallo no cuenta como repetición
+	if reason := o.checkBudget(task, fail(task, "connection refused"), next); reason != "" {
+		t.Fatalf("a different failure halted the run: %s", reason)
+	}
+	reason := o.checkBudget(task, fail(task, "FAIL: TestGet after 1.2s"), next)
+	if !strings.Contains(reason, "same failure in stage test seen 2 times") {
+		t.Errorf("checkBudget() = %q, want the repeated failure", reason)
+	}
+}
+
+func TestBudgetIgnoresRepeatedSuccess(t *testing.T) {
+	o := budgetOrchestrator(t, Budget{MaxSameFailures: 2})
+	task := &types.Task{ID: "t", RunID: "run", Stage: "audit"}
+	minor := []types.AuditFinding{{Rule: "line-length", File: "main.go", Severity: types.SeverityLow}}
+
+	// Una auditoría que pasa con el mismo hallazgo menor no detiene la ejecución
+	for i := 0; i < 5; i++ {
+		result := succeed(task)
+		result.Outputs = map[string]interface{}{"findings": minor}
+		if reason := o.checkBudget(task, result, tasksOf(types.TaskOptimize, 1)); reason != "" {
+			t.Fatalf("successful result %d halted the run: %s", i+1, reason)
+		}
+	}
+}
+
+func TestBudgetMaxTasks(t *testing.T) {
+	o := budgetOrchestrator(t, Budget{MaxTasks: 5})
+	task := &types.Task{ID: "t", RunID: "run"}
+	o.budgetFor("run").tasks = 3
+
+	if reason := o.checkBudget(task, succeed(task), tasksOf(types.TaskCode, 2)); reason != "" {
+		t.Fatalf("reaching the limit halted the run: %s", reason)
+	}
+	if reason := o.checkBudget(task, succeed(task), tasksOf(types.TaskCode, 3)); reason != "task budget of 5 tasks exhausted" {
+		t.Errorf("checkBudget() = %q, want the task budget", reason)
+	}
+}
+
+func TestBudgetMaxRepairs(t *testing.T) {
+	o := budgetOrchestrator(t, Budget{MaxRepairs: 2})
+	task := &types.Task{ID: "t", RunID: "run"}
+	o.budgetFor("run").repairs = 2
+
+	// Solo cuentan las reparaciones
+	if reason := o.checkBudget(task, succeed(task), tasksOf(types.TaskTest, 3)); reason != "" {
+		t.Fatalf("non-repair tasks halted the run: %s", reason)
+	}
+	if reason := o.checkBudget(task, fail(task, "boom"), tasksOf(types.TaskRepair, 1)); reason != "repair budget of 2 iterations exhausted" {
+		t.Errorf("checkBudget() = %q, want the repair budget", reason)
+	}
+}
+
+func TestBudgetDeadline(t *testing.T) {
+	o := budgetOrchestrator(t, Budget{Deadline: time.Hour})
+	task := &types.Task{ID: "t", RunID: "run"}
+	next := tasksOf(types.TaskTest, 1)
+
+	if reason := o.checkBudget(task, succeed(task), next); reason != "" {
+		t.Fatalf("checkBudget() = %q within the deadline", reason)
+	}
+	o.budgetFor("run").started = time.Now().Add(-2 * time.Hour)
+	if reason := o.checkBudget(task, succeed(task), next); reason != "deadline of 1h0m0s exceeded" {
+		t.Errorf("checkBudget() = %q, want the deadline", reason)
+	}
+	// Sin tareas siguientes no hay nada que detener
+	if reason := o.checkBudget(task, succeed(task), nil); reason != "" {
+		t.Errorf("checkBudget() = %q without next tasks", reason)
+	}
+}
+
+func TestBudgetDisabled(t *testing.T) {
+	o := budgetOrchestrator(t, Budget{})
+	task := &types.Task{ID: "t", RunID: "run"}
+	b := o.budgetFor("run")
+	b.tasks, b.repairs, b.started = 1000, 1000, time.Now().Add(-24*time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if reason := o.checkBudget(task, fail(task, "boom"), tasksOf(types.TaskRepair, 1)); reason != "" {
+			t.Fatalf("checkBudget() = %q with every limit disabled", reason)
+		}
+	}
+}
+
+func TestRepeatedFailureHaltsRun(t *testing.T) {
+	o := testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		if task.Type == types.TaskPlan {
+			// Un plan que vuelve a planificarse y falla siempre igual
+			result := plan(task, &types.Task{ID: "again", Type: types.TaskPlan})
+			result.Success, result.State, result.Error = false, types.StateFailed, "undefined: cache.Get"
+			return result
+		}
+		return succeed(task)
+	}, func(o *Orchestrator) {
+		p := testPipeline()
+		p.Stages[0].Transitions[0].On = pipeline.OnFailure
+		if err := o.SetPipeline(p); err != nil {
+			t.Fatal(err)
+		}
+		o.SetBudget(Budget{MaxSameFailures: 2})
+	})
+
+	result := runObjective(t, o, &types.Task{Type: types.TaskPlan, Objective: "loop"})
+	if result.Run.State != types.StateNeedsHuman || !strings.Contains(result.Run.Reason, "same failure") {
+		t.Errorf("run = %s (%s), want needs-human for the same failure", result.Run.State, result.Run.Reason)
+	}
+	if len(result.Tasks) != 2 {
+		t.Errorf("run has %d tasks, want 2", len(result.Tasks))
+	}
+}
@@ -0,0 +1,324 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/store"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// Acciones de control aceptadas por RequestControl
+const (
+	ControlCancel = "cancel"
+	ControlPause  = "pause"
+	ControlResume = "resume"
+)
+
+// controlPollInterval es cada cuánto se leen las órdenes de otros procesos
+const controlPollInterval = 500 * time.Millisecond
+
+// Cancel cancela una tarea y todos sus descendientes, o todas las tareas de
+// una ejecución. Las tareas en curso reciben la cancelación por su contexto,
+// lo que termina los comandos que estén ejecutando.
+func (o *Orchestrator) Cancel(id string) error {
+	o.mu.Lock()
+	if !o.knownTarget(id) {
+		o.mu.Unlock()
+		return fmt.Errorf("task or run %s not found in this orchestrator", id)
+	}
+	if !o.hasActive(id) {
+		o.mu.Unlock()
+		return fmt.Errorf("%s already finished", id)
+	}
+	o.cancelled[id] = true
+
+	interrupt := make([]context.CancelFunc, 0)
+	closing := make([]*types.Task, 0)
+	for taskID, task := range o.taskState {
+		if task.State.IsTerminal() || !o.flagged(o.cancelled, task) {
+			continue
+		}
+		if cancel, ok := o.interrupts[taskID]; ok {
+			// En curso: executeTask la cierra al retornar el agente
+			interrupt = append(interrupt, cancel)
+			continue
+		}
+		if _, ok := o.waiting[taskID]; ok {
+			delete(o.waiting, taskID)
+			closing = append(closing, task)
+			continue
+		}
+		if _, ok := o.held[taskID]; ok {
+			delete(o.held, taskID)
+			closing = append(closing, task)
+			continue
+		}
+		if timer, ok := o.timers[taskID]; ok && timer.Stop() {
+			delete(o.timers, taskID)
+			closing = append(closing, task)
+			continue
+		}
+		if a, ok := o.approvals[taskID]; ok {
+			if a.timer != nil {
+				a.timer.Stop()
+			}
+			delete(o.approvals, taskID)
+			closing = append(closing, task)
+		}
+		// Las encoladas se cierran cuando un worker las toma
+	}
+	o.mu.Unlock()
+
+	for _, cancel := range interrupt {
+		cancel()
+	}
+	for _, task := range closing {
+		o.closeTask(task, types.StateCancelled, "cancelled by request")
+	}
+	return nil
+}
+
+// Pause detiene el inicio de nuevas tareas de una ejecución o de una tarea y
+// sus descendientes. Las tareas en curso terminan normalmente.
+func (o *Orchestrator) Pause(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.knownTarget(id) {
+		return fmt.Errorf("task or run %s not found in this orchestrator", id)
+	}
+	if !o.hasActive(id) {
+		return fmt.Errorf("%s already finished", id)
+	}
+	o.paused[id] = true
+	return nil
+}
+
+// Resume reanuda lo pausado con Pause y encola las tareas retenidas
+func (o *Orchestrator) Resume(id string) error {
+	o.mu.Lock()
+	if !o.paused[id] {
+		o.mu.Unlock()
+		return fmt.Errorf("%s is not paused", id)
+	}
+	delete(o.paused, id)
+
+	released := make([]*types.Task, 0)
+	for taskID, task := range o.held {
+		if !o.flagged(o.paused, task) {
+			delete(o.held, taskID)
+			released = append(released, task)
+		}
+	}
+	o.mu.Unlock()
+
+	for _, task := range released {
+		o.transition(task, types.StatePending, nil)
+		if err := o.enqueue(task); err != nil {
+			log.Printf("failed to enqueue %s: %v", task.ID, err)
+		}
+	}
+	return nil
+}
+
+// RequestControl registra una orden para el orchestrator que esté
+// ejecutando la tarea o ejecución (normalmente otro proceso)
+func (o *Orchestrator) RequestControl(action, target string) error {
+	switch action {
+	case ControlCancel, ControlPause, ControlResume:
+	default:
+		return fmt.Errorf("unknown control action: %q", action)
+	}
+	if task, err := o.store.GetTask(target); err == nil {
+		if task.State.IsTerminal() {
+			return fmt.Errorf("task %s already finished (%s)", target, task.State)
+		}
+	} else if run, err := o.store.GetRun(target); err == nil {
+		if run.CompletedAt != nil {
+			return fmt.Errorf("run %s already finished (%s)", target, run.State)
+		}
+	} else {
+		return fmt.Errorf("task or run %s not found", target)
+	}
+	return o.store.AppendControl(store.ControlRequest{
+		Time:   time.Now(),
+		Action: action,
+		Target: target,
+	})
+}
+
+// RequestDecision registra la aprobación o el rechazo de los cambios
+// retenidos de una tarea o ejecución para el orchestrator que la ejecuta
+func (o *Orchestrator) RequestDecision(action, target, approver, comment string) error {
+	switch action {
+	case ControlApprove, ControlReject:
+	default:
+		return fmt.Errorf("unknown decision: %q", action)
+	}
+	if approver == "" {
+		return errors.New("approver identity is required")
+	}
+	if task, err := o.store.GetTask(target); err == nil {
+		if task.State != types.StateAwaitingApproval {
+			return fmt.Errorf("task %s is not awaiting approval (%s)", target, task.State)
+		}
+	} else if run, err := o.store.GetRun(target); err == nil {
+		if run.State != types.StateAwaitingApproval {
+			return fmt.Errorf("run %s is not awaiting approval (%s)", target, run.State)
+		}
+	} else {
+		return fmt.Errorf("task or run %s not found", target)
+	}
+	return o.store.AppendControl(store.ControlRequest{
+		Time:     time.Now(),
+		Action:   action,
+		Target:   target,
+		Approver: approver,
+		Comment:  comment,
+	})
+}
+
+// watchControl aplica las órdenes registradas con RequestControl a partir
+// de offset, el final del log al arrancar este orchestrator. Cada consulta
+// lee solo las órdenes nuevas.
+func (o *Orchestrator) watchControl(offset int64) {
+	ticker := time.NewTicker(controlPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-o.ctx.Done():
+			return
+		}
+
+		requests, next, err := o.store.ReadControl(offset)
+		if err != nil {
+			log.Printf("store: failed to read control requests: %v", err)
+			continue
+		}
+		for _, request := range requests {
+			o.applyControl(request)
+		}
+		offset = next
+	}
+}
+
+// applyControl ejecuta una orden si afecta a tareas de este orchestrator
+func (o *Orchestrator) applyControl(request store.ControlRequest) {
+	o.mu.RLock()
+	known := o.knownTarget(request.Target)
+	o.mu.RUnlock()
+	if !known {
+		return
+	}
+
+	var err error
+	switch request.Action {
+	case ControlCancel:
+		err = o.Cancel(request.Target)
+	case ControlPause:
+		err = o.Pause(request.Target)
+	case ControlResume:
+		err = o.Resume(request.Target)
+	case ControlApprove:
+		err = o.Approve(request.Target, request.Approver, request.Comment)
+	case ControlReject:
+		err = o.Reject(request.Target, request.Approver, request.Comment)
+	}
+	if err != nil {
+		log.Printf("control: %s %s: %v", request.Action, request.Target, err)
+	}
+}
+
+// taskContext crea el contexto de ejecución de una tarea; Cancel lo cancela
+func (o *Orchestrator) taskContext(task *types.Task) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(o.ctx)
+
+	o.mu.Lock()
+	o.interrupts[task.ID] = cancel
+	o.mu.Unlock()
+
+	return ctx, func() {
+		o.mu.Lock()
+		delete(o.interrupts, task.ID)
+		o.mu.Unlock()
+		cancel()
+	}
+}
+
+// isCancelled indica si se pidió cancelar la tarea
+func (o *Orchestrator) isCancelled(task *types.Task) bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.flagged(o.cancelled, task)
+}
+
+// hold retiene la tarea si está pausada; Resume la vuelve a encolar
+func (o *Orchestrator) hold(task *types.Task) bool {
+	o.mu.Lock()
+	if !o.flagged(o.paused, task) {
+		o.mu.Unlock()
+		return false
+	}
+	o.held[task.ID] = task
+	o.mu.Unlock()
+
+	o.transition(task, types.StatePaused, nil)
+	return true
+}
+
+// knownTarget indica si id es una tarea o ejecución de este orchestrator
+// (requiere o.mu tomado)
+func (o *Orchestrator) knownTarget(id string) bool {
+	if _, ok := o.taskState[id]; ok {
+		return true
+	}
+	_, ok := o.runs[id]
+	return ok
+}
+
+// hasActive indica si id (tarea o ejecución) tiene tareas sin terminar
+// (requiere o.mu tomado)
+func (o *Orchestrator) hasActive(id string) bool {
+	marks := map[string]bool{id: true}
+	for _, task := range o.taskState {
+		if !task.State.IsTerminal() && o.flagged(marks, task) {
+			return true
+		}
+	}
+	return false
+}
+
+// flagged indica si la tarea, su ejecución o alguno de sus ancestros está
+// marcado (requiere o.mu tomado)
+func (o *Orchestrator) flagged(marks map[string]bool, task *types.Task) bool {
+	if len(marks) == 0 {
+		return false
+	}
+	if marks[task.RunID] {
+		return true
+	}
+	for t := task; t != nil; t = o.taskState[t.ParentID] {
+		if marks[t.ID] {
+			return true
+		}
+		if t.ParentID == "" || t.ParentID == t.ID {
+			break
+		}
+	}
+	return false
+}
+
+// cancelledResult es el resultado de una tarea interrumpida por Cancel
+func cancelledResult(task *types.Task, startTime time.Time) *types.TaskResult {
+	return &types.TaskResult{
+		TaskID:   task.ID,
+		State:    types.StateCancelled,
+		Success:  false,
+		Error:    "cancelled by request",
+		Duration: time.Since(startTime),
+	}
+}
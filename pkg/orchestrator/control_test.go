@@ -0,0 +1,186 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/store"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// eventually espera hasta que cond se cumpla o venza el plazo
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// stateOf retorna el estado actual de una tarea de este orchestrator
+func stateOf(o *Orchestrator, taskID string) types.TaskState {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if task, ok := o.taskState[taskID]; ok {
+		return task.State
+	}
+	return ""
+}
+
+// blockingPlan es un agente cuyo plan genera code 1 y test 2 tras él. La
+// tarea de código avisa en started y espera a que su contexto se cancele.
+func blockingPlan(t *testing.T, started chan<- string) func(ctx context.Context, task *types.Task) *types.TaskResult {
+	return func(ctx context.Context, task *types.Task) *types.TaskResult {
+		switch task.Type {
+		case types.TaskPlan:
+			return plan(task,
+				&types.Task{ID: "1", Type: types.TaskCode},
+				&types.Task{ID: "2", Type: types.TaskTest, DependsOn: []string{"1"}},
+			)
+		case types.TaskCode:
+			started <- task.ID
+			<-ctx.Done()
+			return fail(task, "interrupted: "+ctx.Err().Error())
+		}
+		t.Errorf("%s ran although its dependency was cancelled", task.ID)
+		return succeed(task)
+	}
+}
+
+func TestCancelRunInterruptsTasks(t *testing.T) {
+	started := make(chan string, 1)
+	o := testOrchestrator(t, blockingPlan(t, started))
+
+	root := &types.Task{Type: types.TaskPlan, Objective: "cancel"}
+	if err := o.SubmitTask(root); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := o.Cancel("task-unknown"); err == nil {
+		t.Error("Cancel() of an unknown task should fail")
+	}
+	if err := o.Cancel(root.RunID); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := o.Wait(ctx, root.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := taskStates(result)
+	for _, id := range []string{root.ID + ".1", root.ID + ".2"} {
+		if states[id] != types.StateCancelled {
+			t.Errorf("%s: state = %s, want cancelled", id, states[id])
+		}
+	}
+	if result.Run.State != types.StateCancelled {
+		t.Errorf("run state = %s, want cancelled", result.Run.State)
+	}
+	if err := o.Cancel(root.RunID); err == nil || !strings.Contains(err.Error(), "already finished") {
+		t.Errorf("Cancel() of a finished run = %v, want already finished", err)
+	}
+}
+
+func TestPauseHoldsTasksUntilResume(t *testing.T) {
+	proceed := make(chan struct{})
+	var ran int32
+	o := testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		if task.Type == types.TaskPlan {
+			return plan(task,
+				&types.Task{ID: "1", Type: types.TaskCode},
+				&types.Task{ID: "2", Type: types.TaskTest, DependsOn: []string{"1"}},
+			)
+		}
+		if task.Type == types.TaskCode {
+			<-proceed
+		} else {
+			atomic.AddInt32(&ran, 1)
+		}
+		return succeed(task)
+	})
+
+	root := &types.Task{Type: types.TaskPlan, Objective: "pause"}
+	if err := o.SubmitTask(root); err != nil {
+		t.Fatal(err)
+	}
+	first, second := root.ID+".1", root.ID+".2"
+	eventually(t, first+" to start", func() bool { return stateOf(o, first) == types.StateRunning })
+	if err := o.Pause(root.RunID); err != nil {
+		t.Fatal(err)
+	}
+
+	// La tarea en curso termina; la siguiente queda retenida
+	close(proceed)
+	eventually(t, second+" to be held", func() bool { return stateOf(o, second) == types.StatePaused })
+	if stateOf(o, first) != types.StateSuccess {
+		t.Errorf("%s: state = %s, want success", first, stateOf(o, first))
+	}
+	if n := atomic.LoadInt32(&ran); n != 0 {
+		t.Fatalf("paused task ran %d times", n)
+	}
+
+	if err := o.Resume(root.RunID); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Resume(root.RunID); err == nil {
+		t.Error("Resume() of a run that is not paused should fail")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := o.Wait(ctx, root.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&ran); result.Run.State != types.StateSuccess || n != 1 {
+		t.Errorf("run state = %s with %d runs of the held task, want success and 1", result.Run.State, n)
+	}
+}
+
+func TestRequestControlFromAnotherProcess(t *testing.T) {
+	dir := t.TempDir()
+	started := make(chan string, 1)
+	o := testOrchestrator(t, blockingPlan(t, started), func(o *Orchestrator) {
+		st, err := store.NewFileStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o.store = st
+	})
+
+	root := &types.Task{Type: types.TaskPlan, Objective: "control"}
+	if err := o.SubmitTask(root); err != nil {
+		t.Fatal(err)
+	}
+	running := <-started
+
+	// Otro proceso (p. ej. la CLI) deja la orden en el store compartido
+	ws, _ := testRepo(t)
+	cli := fileOrchestrator(t, ws, dir)
+	if err := cli.RequestControl("stop", running); err == nil {
+		t.Error("RequestControl() with an unknown action should fail")
+	}
+	if err := cli.RequestControl(ControlCancel, running); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := o.Wait(ctx, root.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := taskStates(result)[running]; state != types.StateCancelled {
+		t.Errorf("%s: state = %s, want cancelled", running, state)
+	}
+	if err := cli.RequestControl(ControlPause, running); err == nil || !strings.Contains(err.Error(), "already finished") {
+		t.Errorf("RequestControl() on a finished task = %v, want already finished", err)
+	}
+}
@@ -0,0 +1,225 @@
+package orchestrator
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// dependencyStatus indica si las dependencias de la tarea terminaron con éxito.
+// Si alguna terminó sin éxito la retorna como blocker. Requiere o.mu tomado.
+func (o *Orchestrator) dependencyStatus(task *types.Task) (bool, *types.Task) {
+	ready := true
+	for _, depID := range task.DependsOn {
+		dep := o.taskState[depID]
+		if dep == nil {
+			// Dependencia de otro proceso (p. ej. tras una recuperación)
+			dep, _ = o.store.GetTask(depID)
+		}
+		if dep == nil {
+			// Aún no enviada: seguir esperando
+			ready = false
+			continue
+		}
+		if dep.State == types.StateSuccess {
+			continue
+		}
+		if dep.State.IsTerminal() {
+			return false, dep
+		}
+		ready = false
+	}
+	return ready, nil
+}
+
+// unknownDependency retorna la primera dependencia de la tarea que no existe
+// ni en este proceso ni en el store, o "" si todas existen. Requiere o.mu
+// tomado.
+func (o *Orchestrator) unknownDependency(task *types.Task) string {
+	for _, depID := range task.DependsOn {
+		if _, ok := o.taskState[depID]; ok {
+			continue
+		}
+		if _, err := o.store.GetTask(depID); err == nil {
+			continue
+		}
+		return depID
+	}
+	return ""
+}
+
+// failUnknownDependencies cierra como fallidas las tareas de un lote ya
+// enviado que siguen esperando a una dependencia inexistente (p. ej. un error
+// del planner): nadie la va a enviar y la ejecución no terminaría nunca. Sus
+// dependientes se omiten en cascada.
+func (o *Orchestrator) failUnknownDependencies(batch []*types.Task) {
+	orphans := make(map[*types.Task]string)
+	o.mu.Lock()
+	for _, task := range batch {
+		if _, waiting := o.waiting[task.ID]; !waiting {
+			continue
+		}
+		if depID := o.unknownDependency(task); depID != "" {
+			orphans[task] = depID
+			delete(o.waiting, task.ID)
+		}
+	}
+	o.mu.Unlock()
+
+	for task, depID := range orphans {
+		o.closeTask(task, types.StateFailed, fmt.Sprintf("depends on unknown task %s", depID))
+	}
+}
+
+// releaseDependents encola las tareas en espera cuyas dependencias ya
+// terminaron con éxito y descarta las que dependen de una tarea fallida
+func (o *Orchestrator) releaseDependents() {
+	ready := make([]*types.Task, 0)
+	blocked := make(map[*types.Task]*types.Task)
+
+	o.mu.Lock()
+	for id, task := range o.waiting {
+		ok, blocker := o.dependencyStatus(task)
+		switch {
+		case blocker != nil:
+			blocked[task] = blocker
+			delete(o.waiting, id)
+		case ok:
+			ready = append(ready, task)
+			delete(o.waiting, id)
+		}
+	}
+	o.mu.Unlock()
+
+	for _, task := range ready {
+		if err := o.enqueue(task); err != nil {
+			log.Printf("failed to enqueue %s: %v", task.ID, err)
+		}
+	}
+	for task, blocker := range blocked {
+		o.skipTask(task, blocker)
+	}
+}
+
+// skipTask cierra una tarea cuya dependencia no terminó con éxito.
+// Si la dependencia fue cancelada la tarea se cancela; si no, se omite.
+// El descarte se propaga en cascada a sus propios dependientes.
+func (o *Orchestrator) skipTask(task *types.Task, blocker *types.Task) {
+	state := types.StateSkipped
+	if blocker.State == types.StateCancelled {
+		state = types.StateCancelled
+	}
+	o.closeTask(task, state, fmt.Sprintf("dependency %s ended in state %s", blocker.ID, blocker.State))
+}
+
+// closeTask lleva a un estado final una tarea que no llegó a ejecutarse
+func (o *Orchestrator) closeTask(task *types.Task, state types.TaskState, reason string) {
+	now := time.Now()
+	task.CompletedAt = &now
+	o.transition(task, state, nil)
+	result := &types.TaskResult{
+		TaskID:  task.ID,
+		State:   state,
+		Success: false,
+		Error:   reason,
+	}
+	o.recordResult(result)
+	o.publishCompleted(task, result)
+
+	o.releaseDependents()
+	o.finishRun(task.RunID)
+}
+
+// RenderGraph renderiza el DAG de tareas en formato "dot" (Graphviz) o "mermaid".
+// Las aristas sólidas son dependencias; las discontinuas, tareas generadas por su padre.
+func RenderGraph(tasks []*types.Task, format string) (string, error) {
+	known := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		known[task.ID] = i
+	}
+
+	type edge struct {
+		from, to int
+		spawned  bool
+	}
+	edges := make([]edge, 0)
+	for i, task := range tasks {
+		deps := make(map[string]bool)
+		for _, depID := range task.DependsOn {
+			if j, ok := known[depID]; ok {
+				edges = append(edges, edge{from: j, to: i})
+				deps[depID] = true
+			}
+		}
+		if j, ok := known[task.ParentID]; ok && !deps[task.ParentID] {
+			edges = append(edges, edge{from: j, to: i, spawned: true})
+		}
+	}
+
+	var b strings.Builder
+	switch format {
+	case "dot":
+		b.WriteString("digraph tasks {\n")
+		b.WriteString("  rankdir=LR;\n")
+		b.WriteString("  node [shape=box, style=rounded];\n")
+		for _, task := range tasks {
+			fmt.Fprintf(&b, "  %q [label=%q, color=%q];\n",
+				task.ID, fmt.Sprintf("%s\n%s\n%s", task.ID, task.Type, task.State), stateColor(task.State))
+		}
+		for _, e := range edges {
+			style := ""
+			if e.spawned {
+				style = " [style=dashed]"
+			}
+			fmt.Fprintf(&b, "  %q -> %q%s;\n", tasks[e.from].ID, tasks[e.to].ID, style)
+		}
+		b.WriteString("}\n")
+
+	case "mermaid":
+		b.WriteString("flowchart LR\n")
+		for i, task := range tasks {
+			label := fmt.Sprintf("%s<br/>%s: %s", task.ID, task.Type, task.State)
+			fmt.Fprintf(&b, "  n%d[\"%s\"]:::%s\n", i, strings.ReplaceAll(label, `"`, "'"), task.State)
+		}
+		for _, e := range edges {
+			arrow := "-->"
+			if e.spawned {
+				arrow = "-.->"
+			}
+			fmt.Fprintf(&b, "  n%d %s n%d\n", e.from, arrow, e.to)
+		}
+		for _, state := range []types.TaskState{
+			types.StatePending, types.StateRunning, types.StateRetrying, types.StateSuccess,
+			types.StateFailed, types.StateCancelled, types.StateAbandoned, types.StateSkipped,
+			types.StateAwaitingApproval,
+		} {
+			fmt.Fprintf(&b, "  classDef %s stroke:%s\n", state, stateColor(state))
+		}
+
+	default:
+		return "", fmt.Errorf("unknown graph format: %q (use dot or mermaid)", format)
+	}
+
+	return b.String(), nil
+}
+
+// stateColor retorna el color con que se dibuja cada estado
+func stateColor(state types.TaskState) string {
+	switch state {
+	case types.StateSuccess:
+		return "green"
+	case types.StateFailed:
+		return "red"
+	case types.StateRunning, types.StateRetrying:
+		return "blue"
+	case types.StatePending:
+		return "black"
+	case types.StateAwaitingApproval:
+		return "orange"
+	default:
+		return "gray"
+	}
+}
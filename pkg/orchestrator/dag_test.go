@@ -0,0 +1,167 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// diamond es code 1 y 2 en paralelo, test 3 tras ambos y audit 4 tras test
+func diamond() []*types.Task {
+	return []*types.Task{
+		{ID: "1", Type: types.TaskCode, Objective: "one"},
+		{ID: "2", Type: types.TaskCode, Objective: "two"},
+		{ID: "3", Type: types.TaskTest, Objective: "test", DependsOn: []string{"1", "2"}},
+		{ID: "4", Type: types.TaskAudit, Objective: "audit", DependsOn: []string{"3"}},
+	}
+}
+
+func TestDependsOnOrdersTasks(t *testing.T) {
+	var mu sync.Mutex
+	violations := make([]string, 0)
+	var o *Orchestrator
+	o = testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		if task.Type == types.TaskPlan {
+			return plan(task, diamond()...)
+		}
+		// Al empezar, todas las dependencias deben haber terminado con éxito
+		for _, dep := range task.DependsOn {
+			if state, _ := o.GetTaskState(dep); state == nil || state.State != types.StateSuccess {
+				mu.Lock()
+				violations = append(violations, task.ID+" started before "+dep)
+				mu.Unlock()
+			}
+		}
+		return succeed(task)
+	})
+
+	root := &types.Task{Type: types.TaskPlan, Objective: "diamond"}
+	result := runObjective(t, o, root)
+	if len(violations) > 0 {
+		t.Errorf("dependencies not respected: %s", strings.Join(violations, ", "))
+	}
+	if result.Run.State != types.StateSuccess {
+		t.Errorf("run state = %s, want success", result.Run.State)
+	}
+	if len(result.Tasks) != 5 {
+		t.Errorf("run has %d tasks, want 5", len(result.Tasks))
+	}
+}
+
+func TestFailedDependencySkipsDependents(t *testing.T) {
+	o := testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		switch {
+		case task.Type == types.TaskPlan:
+			return plan(task, diamond()...)
+		case strings.HasSuffix(task.ID, ".2"):
+			return fail(task, "boom")
+		case task.Type != types.TaskCode:
+			t.Errorf("%s ran although a dependency failed", task.ID)
+		}
+		return succeed(task)
+	})
+
+	root := &types.Task{Type: types.TaskPlan, Objective: "diamond"}
+	result := runObjective(t, o, root)
+	want := map[string]types.TaskState{
+		root.ID:        types.StateSuccess,
+		root.ID + ".1": types.StateSuccess,
+		root.ID + ".2": types.StateFailed,
+		root.ID + ".3": types.StateSkipped,
+		root.ID + ".4": types.StateSkipped, // en cascada
+	}
+	states := taskStates(result)
+	for id, state := range want {
+		if states[id] != state {
+			t.Errorf("%s: state = %s, want %s", id, states[id], state)
+		}
+	}
+	if reason := result.Results[root.ID+".4"].Error; !strings.Contains(reason, root.ID+".3 ended in state skipped") {
+		t.Errorf("skip reason = %q, want the skipped dependency", reason)
+	}
+	if result.Run.State != types.StateFailed {
+		t.Errorf("run state = %s, want failed", result.Run.State)
+	}
+}
+
+func TestCancelledDependencyCancelsDependents(t *testing.T) {
+	o := testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		if task.Type == types.TaskPlan {
+			return plan(task,
+				&types.Task{ID: "1", Type: types.TaskCode},
+				&types.Task{ID: "2", Type: types.TaskTest, DependsOn: []string{"1"}},
+			)
+		}
+		if task.Type == types.TaskCode {
+			return &types.TaskResult{TaskID: task.ID, State: types.StateCancelled, Error: "cancelled"}
+		}
+		t.Errorf("%s ran although its dependency was cancelled", task.ID)
+		return succeed(task)
+	})
+
+	root := &types.Task{Type: types.TaskPlan, Objective: "cancel"}
+	states := taskStates(runObjective(t, o, root))
+	if states[root.ID+".2"] != types.StateCancelled {
+		t.Errorf("dependent state = %s, want cancelled", states[root.ID+".2"])
+	}
+}
+
+func TestUnknownDependencyFailsDependents(t *testing.T) {
+	o := testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		switch task.Type {
+		case types.TaskPlan:
+			// "3" depende de una tarea que el plan nunca genera
+			return plan(task,
+				&types.Task{ID: "1", Type: types.TaskCode},
+				&types.Task{ID: "2", Type: types.TaskTest, DependsOn: []string{"3"}},
+				&types.Task{ID: "3", Type: types.TaskTest, DependsOn: []string{"1", "typo"}},
+				&types.Task{ID: "4", Type: types.TaskAudit, DependsOn: []string{"3"}},
+			)
+		case types.TaskCode:
+			return succeed(task)
+		}
+		t.Errorf("%s ran although a dependency does not exist", task.ID)
+		return succeed(task)
+	})
+
+	root := &types.Task{Type: types.TaskPlan, Objective: "typo"}
+	result := runObjective(t, o, root)
+	want := map[string]types.TaskState{
+		root.ID + ".1": types.StateSuccess,
+		root.ID + ".2": types.StateSkipped,
+		root.ID + ".3": types.StateFailed,
+		root.ID + ".4": types.StateSkipped,
+	}
+	states := taskStates(result)
+	for id, state := range want {
+		if states[id] != state {
+			t.Errorf("%s: state = %s, want %s", id, states[id], state)
+		}
+	}
+	if reason := result.Results[root.ID+".3"].Error; reason != "depends on unknown task "+root.ID+".typo" {
+		t.Errorf("failure reason = %q, want the unknown dependency", reason)
+	}
+	if result.Run.State != types.StateFailed {
+		t.Errorf("run state = %s, want failed", result.Run.State)
+	}
+}
+
+func TestSubmitRejectsUnknownDependency(t *testing.T) {
+	o := testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		return succeed(task)
+	})
+	first := &types.Task{Type: types.TaskCode, Objective: "first"}
+	runObjective(t, o, first)
+
+	err := o.SubmitTask(&types.Task{Type: types.TaskTest, DependsOn: []string{first.ID, "task-missing"}})
+	if err == nil || !strings.Contains(err.Error(), "unknown task task-missing") {
+		t.Errorf("SubmitTask() = %v, want the unknown dependency", err)
+	}
+	// Una dependencia ya terminada es válida
+	if err := o.SubmitTask(&types.Task{Type: types.TaskTest, DependsOn: []string{first.ID}}); err != nil {
+		t.Errorf("SubmitTask() = %v with a known dependency", err)
+	}
+}
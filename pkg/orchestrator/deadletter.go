@@ -0,0 +1,173 @@
+package orchestrator
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/events"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// deadLetter guarda en la dead-letter queue una tarea que falló sin que el
+// pipeline generara nada para recuperarla, con su evidencia, la
+// clasificación del fallo y las decisiones de la tarea y sus antecesoras
+func (o *Orchestrator) deadLetter(task *types.Task, result *types.TaskResult) {
+	classes := make([]types.FailureClass, 0)
+	for _, c := range o.evaluator.ParseResult(result) {
+		classes = append(classes, types.FailureClass{
+			Pattern:     c.Pattern.ID,
+			Category:    c.Pattern.Category,
+			Severity:    c.Pattern.Severity,
+			Matches:     c.Matches,
+			Remediation: c.Pattern.Remediation,
+		})
+	}
+
+	letter := &types.DeadLetter{
+		Task:            task,
+		Result:          result,
+		Evidence:        result.Evidence,
+		Classifications: classes,
+		Decisions:       o.decisionTrail(task),
+		Status:          types.DeadLetterOpen,
+		CreatedAt:       time.Now(),
+	}
+	if err := o.store.SaveDeadLetter(letter); err != nil {
+		log.Printf("store: failed to save dead letter for %s: %v", task.ID, err)
+		return
+	}
+
+	event := events.ForTask(events.TaskDeadLettered, task)
+	event.State = result.State
+	event.Message = result.Error
+	o.events.Publish(event)
+}
+
+// decisionTrail retorna las decisiones de la tarea y de sus antecesoras
+func (o *Orchestrator) decisionTrail(task *types.Task) []types.Decision {
+	lineage := map[string]bool{task.ID: true}
+	for parentID := task.ParentID; parentID != "" && !lineage[parentID]; {
+		lineage[parentID] = true
+		parent, _ := o.GetTaskState(parentID)
+		if parent == nil {
+			break
+		}
+		parentID = parent.ParentID
+	}
+
+	trail := make([]types.Decision, 0)
+	for _, decision := range o.GetMemory() {
+		if lineage[decision.TaskID] {
+			trail = append(trail, decision)
+		}
+	}
+	return trail
+}
+
+// ListDeadLetters retorna la dead-letter queue, de la más antigua a la más reciente
+func (o *Orchestrator) ListDeadLetters() ([]*types.DeadLetter, error) {
+	return o.store.ListDeadLetters()
+}
+
+// GetDeadLetter retorna la entrada de la dead-letter queue de una tarea
+func (o *Orchestrator) GetDeadLetter(taskID string) (*types.DeadLetter, error) {
+	letter, err := o.store.GetDeadLetter(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("dead letter %s: %w", taskID, err)
+	}
+	return letter, nil
+}
+
+// Requeue vuelve a enviar una tarea de la dead-letter queue dentro de su
+// ejecución, con inputs que reemplazan a los originales. La nueva tarea es
+// hija de la fallida; la ejecución se reabre si ya había terminado y, al ser
+// una decisión humana, se levanta su detención y se reinicia su plazo. Los
+// inputs originales vienen del store decodificados de JSON; los agentes que
+// leen resultados tipados (p. ej. el repairer) aceptan las dos formas.
+func (o *Orchestrator) Requeue(taskID string, inputs map[string]interface{}) (*types.Task, error) {
+	var task *types.Task
+	letter, err := o.resolveDeadLetter(taskID, types.DeadLetterRequeued, func(letter *types.DeadLetter) {
+		failed := letter.Task
+		task = &types.Task{
+			Type:        failed.Type,
+			Objective:   failed.Objective,
+			Inputs:      make(map[string]interface{}, len(failed.Inputs)+len(inputs)),
+			Constraints: make(map[string]interface{}, len(failed.Constraints)),
+			MaxRetries:  failed.MaxRetries,
+			ParentID:    failed.ID,
+			RunID:       failed.RunID,
+			Stage:       failed.Stage,
+			Priority:    failed.Priority,
+		}
+		for k, v := range failed.Inputs {
+			task.Inputs[k] = v
+		}
+		for k, v := range inputs {
+			task.Inputs[k] = v
+		}
+		for k, v := range failed.Constraints {
+			task.Constraints[k] = v
+		}
+		o.assignID(task)
+		letter.RequeuedAs = task.ID
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	o.reopenRun(task)
+	o.mu.Lock()
+	if b, ok := o.budgets[task.RunID]; ok {
+		b.halted = ""
+		b.started = time.Now()
+	}
+	o.mu.Unlock()
+
+	if err := o.submit(task); err != nil {
+		// La entrada vuelve a quedar abierta para otro intento
+		o.mu.Lock()
+		letter.Status = types.DeadLetterOpen
+		letter.ResolvedAt = nil
+		letter.RequeuedAs = ""
+		if err := o.store.SaveDeadLetter(letter); err != nil {
+			log.Printf("store: failed to save dead letter for %s: %v", taskID, err)
+		}
+		o.mu.Unlock()
+		return nil, err
+	}
+	return task, nil
+}
+
+// WontFix cierra una entrada de la dead-letter queue sin volver a intentarla
+func (o *Orchestrator) WontFix(taskID, comment string) error {
+	_, err := o.resolveDeadLetter(taskID, types.DeadLetterWontFix, func(letter *types.DeadLetter) {
+		letter.Comment = comment
+	})
+	return err
+}
+
+// resolveDeadLetter cierra una entrada abierta con el estado indicado tras
+// completarla con fn. La comprobación y la marca se hacen bajo o.mu para
+// que dos Requeue o WontFix simultáneos no resuelvan la misma entrada.
+func (o *Orchestrator) resolveDeadLetter(taskID string, status types.DeadLetterStatus, fn func(letter *types.DeadLetter)) (*types.DeadLetter, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	letter, err := o.GetDeadLetter(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if letter.Status != types.DeadLetterOpen {
+		return nil, fmt.Errorf("dead letter %s is already %s", taskID, letter.Status)
+	}
+
+	now := time.Now()
+	letter.Status = status
+	letter.ResolvedAt = &now
+	fn(letter)
+	if err := o.store.SaveDeadLetter(letter); err != nil {
+		return nil, fmt.Errorf("failed to save dead letter %s: %w", taskID, err)
+	}
+	return letter, nil
+}
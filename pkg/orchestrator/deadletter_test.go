@@ -0,0 +1,214 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/agents"
+	"github.com/nanochip/multi-agent/pkg/pipeline"
+	"github.com/nanochip/multi-agent/pkg/store"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// deadLetterOrchestrator crea un orchestrator sobre un store en disco en el
+// que las reparaciones enviadas directamente fallan y las re-encoladas se
+// ejecutan con el repairer real
+func deadLetterOrchestrator(t *testing.T) *Orchestrator {
+	t.Helper()
+	var o *Orchestrator
+	o = testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		if task.ParentID == "" {
+			return fail(task, "FAIL: TestGet")
+		}
+		return agents.NewRepairer(o.workspace, nil).Execute(ctx, task)
+	}, func(o *Orchestrator) {
+		st, err := store.NewFileStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		o.store = st
+		p := testPipeline()
+		p.Stages = append(p.Stages, pipeline.Stage{ID: "repair", Type: types.TaskRepair})
+		if err := o.SetPipeline(p); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return o
+}
+
+// failedRepair ejecuta una reparación que termina en la dead-letter queue
+func failedRepair(t *testing.T, o *Orchestrator) *types.Task {
+	t.Helper()
+	task := &types.Task{
+		Type:      types.TaskRepair,
+		Objective: "repair failing tests",
+		Inputs:    map[string]interface{}{"test_result": failedTests()},
+	}
+	if result := runObjective(t, o, task); result.Run.State != types.StateFailed {
+		t.Fatalf("run state = %s, want failed", result.Run.State)
+	}
+	letter, err := o.GetDeadLetter(task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if letter.Status != types.DeadLetterOpen {
+		t.Fatalf("dead letter status = %s, want open", letter.Status)
+	}
+	return task
+}
+
+func TestRequeueReopensRunWithFailureContext(t *testing.T) {
+	o := deadLetterOrchestrator(t)
+	failed := failedRepair(t, o)
+
+	// Una ejecución detenida por el presupuesto y con el plazo agotado
+	o.mu.Lock()
+	b := o.budgetFor(failed.RunID)
+	b.halted = "deadline of 1h0m0s exceeded"
+	b.started = time.Now().Add(-2 * time.Hour)
+	o.mu.Unlock()
+
+	requeued, err := o.Requeue(failed.ID, map[string]interface{}{"hint": "check the cache"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requeued.ParentID != failed.ID || requeued.RunID != failed.RunID {
+		t.Errorf("requeued task has parent %s in %s, want %s in %s", requeued.ParentID, requeued.RunID, failed.ID, failed.RunID)
+	}
+	if _, typed := requeued.Inputs["test_result"].(*types.TaskResult); typed {
+		t.Fatal("test_result should come back decoded from the dead letter")
+	}
+	if requeued.Inputs["hint"] != "check the cache" {
+		t.Errorf("hint input = %v, want the override", requeued.Inputs["hint"])
+	}
+	o.mu.Lock()
+	halted, started := b.halted, b.started
+	o.mu.Unlock()
+	if halted != "" || time.Since(started) > time.Minute {
+		t.Errorf("budget halted = %q since %s, want it lifted and restarted", halted, started)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := o.Wait(ctx, failed.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Run.State != types.StateSuccess || result.Run.CompletedAt == nil {
+		t.Errorf("run = %s, want it reopened and closed with success", result.Run.State)
+	}
+	// Los inputs vienen del store decodificados de JSON; el repairer debe
+	// seguir viendo el fallo original
+	repair := result.Results[requeued.ID]
+	if repair == nil || repair.Outputs["strategy"] != "repair_1_failures" {
+		t.Fatalf("requeued repair = %+v, want the repair_1_failures strategy", repair)
+	}
+
+	letter, err := o.GetDeadLetter(failed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if letter.Status != types.DeadLetterRequeued || letter.RequeuedAs != requeued.ID || letter.ResolvedAt == nil {
+		t.Errorf("dead letter = %s as %q, want requeued as %s", letter.Status, letter.RequeuedAs, requeued.ID)
+	}
+	if _, err := o.Requeue(failed.ID, nil); err == nil || !strings.Contains(err.Error(), "already requeued") {
+		t.Errorf("second Requeue() = %v, want already requeued", err)
+	}
+}
+
+func TestWontFixClosesDeadLetter(t *testing.T) {
+	o := deadLetterOrchestrator(t)
+	failed := failedRepair(t, o)
+
+	if err := o.WontFix("task-unknown", "no"); err == nil {
+		t.Error("WontFix() of an unknown task should fail")
+	}
+	if err := o.WontFix(failed.ID, "flaky upstream, tracked elsewhere"); err != nil {
+		t.Fatal(err)
+	}
+	letter, err := o.GetDeadLetter(failed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if letter.Status != types.DeadLetterWontFix || letter.Comment != "flaky upstream, tracked elsewhere" || letter.ResolvedAt == nil {
+		t.Errorf("dead letter = %s (%q), want wontfix with the comment", letter.Status, letter.Comment)
+	}
+	if _, err := o.Requeue(failed.ID, nil); err == nil || !strings.Contains(err.Error(), "already wont-fix") {
+		t.Errorf("Requeue() after WontFix = %v, want already wont-fix", err)
+	}
+	if err := o.WontFix(failed.ID, "again"); err == nil {
+		t.Error("second WontFix() should fail")
+	}
+
+	run, _, err := o.GetRun(failed.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.State != types.StateFailed {
+		t.Errorf("run state = %s, want it to stay failed", run.State)
+	}
+}
+
+func TestConcurrentRequeueResolvesOnce(t *testing.T) {
+	o := deadLetterOrchestrator(t)
+	failed := failedRepair(t, o)
+
+	// Varios Requeue y WontFix a la vez: solo uno resuelve la entrada
+	const callers = 8
+	var wg sync.WaitGroup
+	var resolved int32
+	requeued := make(chan *types.Task, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				if err := o.WontFix(failed.ID, "duplicate"); err == nil {
+					atomic.AddInt32(&resolved, 1)
+				}
+				return
+			}
+			if task, err := o.Requeue(failed.ID, nil); err == nil {
+				atomic.AddInt32(&resolved, 1)
+				requeued <- task
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(requeued)
+
+	if n := atomic.LoadInt32(&resolved); n != 1 {
+		t.Fatalf("%d callers resolved the dead letter, want 1", n)
+	}
+	letter, err := o.GetDeadLetter(failed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := o.ListTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	children := 0
+	for _, task := range tasks {
+		if task.ParentID == failed.ID {
+			children++
+		}
+	}
+	if task, ok := <-requeued; ok {
+		if letter.Status != types.DeadLetterRequeued || letter.RequeuedAs != task.ID || children != 1 {
+			t.Errorf("dead letter = %s as %q with %d requeued tasks, want requeued once as %s", letter.Status, letter.RequeuedAs, children, task.ID)
+		}
+		// La tarea reenviada escribe en el store hasta que termina su ejecución
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := o.Wait(ctx, task.RunID); err != nil {
+			t.Fatal(err)
+		}
+	} else if letter.Status != types.DeadLetterWontFix || children != 0 {
+		t.Errorf("dead letter = %s with %d requeued tasks, want wont-fix and none", letter.Status, children)
+	}
+}
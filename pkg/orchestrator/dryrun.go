@@ -0,0 +1,187 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"github.com/nanochip/multi-agent/pkg/agents"
+	"github.com/nanochip/multi-agent/pkg/pipeline"
+	"github.com/nanochip/multi-agent/pkg/tools"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// DryRunReport es lo que haría el orchestrator con una tarea
+type DryRunReport struct {
+	Pipeline string      `json:"pipeline"`
+	Policies []string    `json:"policies"` // políticas activas
+	Gates    []string    `json:"gates"`    // gates obligatorios
+	Root     *DryRunTask `json:"root"`
+}
+
+// DryRunTask es una tarea del grafo esperado, con el veredicto de las políticas
+type DryRunTask struct {
+	Task      *types.Task   `json:"task"`
+	Agent     string        `json:"agent"`
+	Allowed   bool          `json:"allowed"`
+	Fails     bool          `json:"fails,omitempty"`    // terminaría sin éxito (bloqueada, fallida u omitida)
+	Reason    string        `json:"reason,omitempty"`   // por qué se bloquearía o fallaría
+	Warnings  []string      `json:"warnings,omitempty"` // avisos de las reglas warn
+	Steps     []DryRunStep  `json:"steps,omitempty"`
+	Approvals int           `json:"approvals,omitempty"` // aprobaciones que esperaría
+	Risk      []string      `json:"risk,omitempty"`
+	Otherwise []string      `json:"otherwise,omitempty"` // transiciones si no termina con éxito
+	Revisits  bool          `json:"revisits,omitempty"`  // su etapa ya se recorrió en la rama: no se expande
+	Children  []*DryRunTask `json:"children,omitempty"`
+}
+
+// DryRunStep es un paso simulado de un agente con su veredicto
+type DryRunStep struct {
+	agents.Step
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// DryRun simula una tarea sin ejecutar comandos ni escribir archivos. El
+// planner planifica de verdad; el resto de agentes describe sus pasos y se
+// supone que terminan con éxito. Cada tarea lleva el veredicto de AllowTask
+// y cada paso el de las rutas (contrato y política del agente) o el de las
+// herramientas permitidas. Una rama se corta al volver a una etapa que ya
+// recorrió. No usa el store ni necesita Start.
+func (o *Orchestrator) DryRun(task *types.Task) *DryRunReport {
+	if task.ID == "" {
+		task.ID = newID("task")
+	}
+	o.pipeline.Apply(task)
+	o.policy.ApplyConstraints(task)
+	report := &DryRunReport{
+		Pipeline: o.pipeline.Name,
+		Policies: make([]string, 0),
+		Gates:    make([]string, 0),
+		Root:     o.simulate(task, map[string]bool{}),
+	}
+	for _, policy := range o.policy.GetPolicies() {
+		if policy.Enabled {
+			report.Policies = append(report.Policies, policy.ID)
+		}
+	}
+	for _, gate := range o.policy.GetGates() {
+		if gate.Required {
+			report.Gates = append(report.Gates, gate.ID)
+		}
+	}
+	return report
+}
+
+// simulate simula una tarea y, recursivamente, las que generaría
+func (o *Orchestrator) simulate(task *types.Task, visited map[string]bool) *DryRunTask {
+	node := &DryRunTask{Task: task, Agent: o.pipeline.AgentFor(task), Allowed: true}
+	if visited[task.Stage] {
+		node.Revisits = true
+		return node
+	}
+	verdict := o.policy.AllowTask(node.Agent, task)
+	node.Warnings = verdict.Warnings()
+	if !verdict.Allowed {
+		node.Allowed, node.Fails, node.Reason = false, true, "task blocked by policy: "+verdict.Reason()
+		return node
+	}
+
+	agent := o.agents[node.Agent]
+	simulator, ok := agent.(agents.Simulator)
+	if !ok {
+		node.Fails, node.Reason = true, fmt.Sprintf("no agent available for task type: %s", task.Type)
+		return node
+	}
+
+	task.RepoPath = o.workspace.GetRepoPath()
+	result, steps := simulator.Simulate(task)
+	runner := tools.NewRunner()
+	runner.SetAllowedCommands(node.Agent, agent.GetContract().AllowedTools)
+	for _, step := range steps {
+		node.Steps = append(node.Steps, o.checkStep(node.Agent, agent, runner, step))
+	}
+
+	if !result.Success {
+		node.Reason = "would fail: " + result.Error
+	} else {
+		verdict = o.policy.ValidateResult(node.Agent, task, result)
+		node.Warnings = append(node.Warnings, verdict.Warnings()...)
+		if !verdict.Allowed {
+			result.Success = false
+			result.State = types.StateFailed
+			node.Reason = "would fail: result failed policy validation: " + verdict.Reason()
+		}
+	}
+	node.Fails = !result.Success
+	if result.Success {
+		node.Approvals, node.Risk = o.policy.RequiredApprovals(result)
+	}
+
+	stage := o.pipeline.StageFor(task)
+	if stage == nil {
+		return node
+	}
+	for _, t := range stage.Transitions {
+		if t.On != pipeline.OnSuccess && t.On != pipeline.OnAlways {
+			node.Otherwise = append(node.Otherwise, t.String())
+		}
+	}
+
+	branch := make(map[string]bool, len(visited)+1)
+	for id := range visited {
+		branch[id] = true
+	}
+	branch[stage.ID] = true
+	failing := make(map[string]bool)
+	for i, child := range o.pipeline.NextTasks(task, result) {
+		if child.ID == "" {
+			child.ID = fmt.Sprintf("%s.%d", task.ID, i+1)
+		}
+		if child.ParentID == "" {
+			child.ParentID = task.ID
+		}
+		child.RunID = task.RunID
+		inheritConstraints(child, task)
+		if child.Priority == "" {
+			child.Priority = task.Priority
+		}
+		o.pipeline.Apply(child)
+		o.policy.ApplyConstraints(child)
+
+		// Una tarea cuya dependencia falla se omite
+		var simulated *DryRunTask
+		for _, dep := range child.DependsOn {
+			if failing[dep] {
+				simulated = &DryRunTask{Task: child, Agent: o.pipeline.AgentFor(child), Allowed: true, Fails: true,
+					Reason: fmt.Sprintf("would be skipped: dependency %s would not succeed", dep)}
+				break
+			}
+		}
+		if simulated == nil {
+			simulated = o.simulate(child, branch)
+		}
+		failing[child.ID] = simulated.Fails
+		node.Children = append(node.Children, simulated)
+	}
+	return node
+}
+
+// checkStep evalúa un paso: los comandos contra las herramientas del
+// contrato del agente y las escrituras contra sus rutas y su política
+func (o *Orchestrator) checkStep(agentName string, agent agents.Agent, runner *tools.Runner, step agents.Step) DryRunStep {
+	checked := DryRunStep{Step: step, Allowed: true}
+	if step.Command != "" {
+		checked.Allowed, checked.Reason = runner.ValidateCommand(agentName, step.Command, step.Args...)
+		return checked
+	}
+
+	if v, ok := agent.(interface{ ValidatePath(string) bool }); ok && !v.ValidatePath(step.Path) {
+		checked.Allowed = false
+		checked.Reason = fmt.Sprintf("path not allowed by the %s contract", agentName)
+		return checked
+	}
+	if policy := o.policy.GetPolicyForAgent(agentName); policy != nil && !o.policy.ValidatePath(agentName, step.Path, *policy) {
+		checked.Allowed = false
+		checked.Reason = fmt.Sprintf("path not allowed by policy %s", policy.ID)
+	}
+	return checked
+}
@@ -0,0 +1,154 @@
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/nanochip/multi-agent/pkg/policies"
+	"github.com/nanochip/multi-agent/pkg/types"
+	"github.com/nanochip/multi-agent/pkg/workspace"
+)
+
+// committedRepo crea un repositorio con un commit de los archivos indicados,
+// sin pasar por workspace.NewManager
+func committedRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add("."); err != nil {
+		t.Fatal(err)
+	}
+	author := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	if _, err := worktree.Commit("initial commit", &git.CommitOptions{Author: author}); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// repoSnapshot describe el estado del repositorio: HEAD, los worktrees de
+// git y cada archivo (también los de .git) con su contenido
+func repoSnapshot(t *testing.T, dir string) string {
+	t.Helper()
+	var b strings.Builder
+	head, err := exec.Command("git", "-C", dir, "rev-parse", "--symbolic-full-name", "HEAD", "HEAD").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git rev-parse: %v: %s", err, head)
+	}
+	worktrees, err := exec.Command("git", "-C", dir, "worktree", "list", "--porcelain").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git worktree list: %v: %s", err, worktrees)
+	}
+	fmt.Fprintf(&b, "HEAD:\n%s\nworktrees:\n%s\nfiles:\n", head, worktrees)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if d.IsDir() {
+			fmt.Fprintf(&b, "%s/\n", rel)
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s %x\n", rel, sha256.Sum256(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestDryRunGraphLeavesRepoUntouched(t *testing.T) {
+	dir := committedRepo(t, map[string]string{
+		"go.mod":           "module example.com/app\n\ngo 1.21\n",
+		"pkg/auth/auth.go": "package auth\n",
+	})
+	before := repoSnapshot(t, dir)
+
+	ws, err := workspace.OpenReadOnly(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := New(ws, policies.NewEngine())
+	t.Cleanup(o.Stop)
+
+	// El coder escribiría auth.go y un archivo de vendor/, que su contrato no permite
+	report := o.DryRun(&types.Task{
+		Type:      types.TaskPlan,
+		Objective: "fix bug in auth",
+		Inputs:    map[string]interface{}{"files": []interface{}{"pkg/auth/auth.go", "vendor/auth/patch.go"}},
+	})
+
+	root := report.Root
+	if root.Agent != "planner" || !root.Allowed || root.Fails || len(root.Children) == 0 {
+		t.Fatalf("root = %s allowed %v fails %v with %d children, want a successful plan", root.Agent, root.Allowed, root.Fails, len(root.Children))
+	}
+	var code *DryRunTask
+	for _, child := range root.Children {
+		if child.Task.ParentID != root.Task.ID || !strings.HasPrefix(child.Task.ID, root.Task.ID+".") {
+			t.Errorf("child %s has parent %s, want %s", child.Task.ID, child.Task.ParentID, root.Task.ID)
+		}
+		if child.Task.Type == types.TaskCode && code == nil {
+			code = child
+		}
+	}
+	if code == nil {
+		t.Fatalf("plan children = %d, want a code task", len(root.Children))
+	}
+	if code.Agent != "coder" || !code.Allowed || code.Fails {
+		t.Errorf("code task = %s allowed %v fails %v (%s), want an allowed coder task that succeeds", code.Agent, code.Allowed, code.Fails, code.Reason)
+	}
+	verdicts := make(map[string]DryRunStep)
+	for _, step := range code.Steps {
+		key := step.Path
+		if step.Command != "" {
+			key = step.Command
+		}
+		verdicts[key] = step
+	}
+	if step, ok := verdicts["vendor/auth/patch.go"]; !ok || step.Allowed || !strings.Contains(step.Reason, "coder contract") {
+		t.Errorf("write to vendor/ = %+v, want it denied by the coder contract", step)
+	}
+	if step, ok := verdicts["pkg/auth/auth.go"]; !ok || !step.Allowed {
+		t.Errorf("write to auth.go = %+v, want it allowed", step)
+	}
+	if step, ok := verdicts["git"]; !ok || !step.Allowed {
+		t.Errorf("git step = %+v, want it allowed", step)
+	}
+
+	// Nada en el repositorio cambia: ni HEAD, ni los worktrees, ni los archivos
+	if after := repoSnapshot(t, dir); after != before {
+		t.Errorf("dry run changed the repository:\nbefore:\n%s\nafter:\n%s", before, after)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".multi-agent")); !os.IsNotExist(err) {
+		t.Errorf("dry run created .multi-agent: %v", err)
+	}
+}
@@ -3,102 +3,250 @@ package orchestrator
 import (
 	"context"
 	"fmt"
+	"log"
 	"sync"
 	"time"
 
 	"github.com/nanochip/multi-agent/pkg/agents"
+	"github.com/nanochip/multi-agent/pkg/evaluation"
+	"github.com/nanochip/multi-agent/pkg/events"
+	"github.com/nanochip/multi-agent/pkg/pipeline"
 	"github.com/nanochip/multi-agent/pkg/policies"
+	"github.com/nanochip/multi-agent/pkg/store"
 	"github.com/nanochip/multi-agent/pkg/types"
 	"github.com/nanochip/multi-agent/pkg/workspace"
 )
 
 // Orchestrator coordina todos los agentes y gestiona el flujo de trabajo
 type Orchestrator struct {
-	workspace  workspace.Manager
-	policy     policies.Engine
-	taskQueue  chan *types.Task
+	workspace  *workspace.Manager
+	policy     *policies.Engine
+	pipeline   *pipeline.Pipeline
+	pool       *workerPool
+	queue      *taskQueue
 	taskState  map[string]*types.Task
 	results    map[string]*types.TaskResult
-	memory     []types.Decision
+	waiting    map[string]*types.Task
+	store      store.Store
+	events     *events.Bus
+	runs       map[string]*types.Run
+	members    map[string]map[string]*types.Task // tareas de cada ejecución seguida por este proceso
+	pending    map[string]map[string]bool        // tareas sin terminar de cada ejecución
+	budget     Budget
+	budgets    map[string]*runBudget
+	evaluator  *evaluation.Engine
+	retries    map[types.TaskType]RetryPolicy
+	timeout    time.Duration
+	timeouts   map[types.TaskType]time.Duration
+	timers     map[string]*time.Timer
+	runDone    map[string]chan struct{}
+	cancelled  map[string]bool
+	paused     map[string]bool
+	held       map[string]*types.Task
+	interrupts map[string]context.CancelFunc
+	approvals  map[string]*approval
+	awaitLimit time.Duration
 	mu         sync.RWMutex
 	agents     map[string]agents.Agent
+	isolate    bool
+	scheduling bool
+	spaces     map[string]*runSpace
+	spacesMu   sync.Mutex
 	ctx        context.Context
 	cancel     context.CancelFunc
 }
 
-// New crea un nuevo Orchestrator
-func New(ws workspace.Manager, policyEngine policies.Engine) *Orchestrator {
+// New crea un nuevo Orchestrator con un store en memoria
+func New(ws *workspace.Manager, policyEngine *policies.Engine) *Orchestrator {
+	return NewWithStore(ws, policyEngine, store.NewMemoryStore())
+}
+
+// NewWithStore crea un nuevo Orchestrator que persiste su estado en st
+func NewWithStore(ws *workspace.Manager, policyEngine *policies.Engine, st store.Store) *Orchestrator {
 	ctx, cancel := context.WithCancel(context.Background())
 	
 	o := &Orchestrator{
 		workspace: ws,
 		policy:    policyEngine,
-		taskQueue: make(chan *types.Task, 100),
+		pipeline:  pipeline.Default(),
+		pool:      newWorkerPool(DefaultPoolConfig()),
+		queue:     newTaskQueue(DefaultPoolConfig().QueueLimit),
 		taskState: make(map[string]*types.Task),
 		results:   make(map[string]*types.TaskResult),
-		memory:    make([]types.Decision, 0),
-		agents:    make(map[string]agents.Agent),
+		waiting:   make(map[string]*types.Task),
+		store:     st,
+		events:    events.NewBus(),
+		runs:      make(map[string]*types.Run),
+		members:   make(map[string]map[string]*types.Task),
+		pending:   make(map[string]map[string]bool),
+		budget:    DefaultBudget(),
+		budgets:   make(map[string]*runBudget),
+		evaluator: evaluation.NewEngine(),
+		retries:   make(map[types.TaskType]RetryPolicy),
+		timeout:   DefaultTaskTimeout,
+		timeouts:  make(map[types.TaskType]time.Duration),
+		timers:    make(map[string]*time.Timer),
+		runDone:   make(map[string]chan struct{}),
+		cancelled: make(map[string]bool),
+		paused:    make(map[string]bool),
+		held:      make(map[string]*types.Task),
+		interrupts: make(map[string]context.CancelFunc),
+		approvals: make(map[string]*approval),
+		awaitLimit: DefaultApprovalTimeout,
+		isolate:   true,
+		spaces:    make(map[string]*runSpace),
 		ctx:       ctx,
 		cancel:    cancel,
 	}
 	
-	// Registrar agentes
-	o.registerAgents()
+	// Registrar agentes sobre el workspace compartido
+	o.agents = o.newAgents(ws)
 	
 	return o
 }
 
-// registerAgents registra todos los agentes disponibles
-func (o *Orchestrator) registerAgents() {
-	o.agents["planner"] = agents.NewPlanner(o.workspace, o.policy)
-	o.agents["coder"] = agents.NewCoder(o.workspace, o.policy)
-	o.agents["tester"] = agents.NewTester(o.workspace, o.policy)
-	o.agents["auditor"] = agents.NewAuditor(o.workspace, o.policy)
-	o.agents["repairer"] = agents.NewRepairer(o.workspace, o.policy)
-	o.agents["optimizer"] = agents.NewOptimizer(o.workspace, o.policy)
+// SetPipeline reemplaza el pipeline que determina el flujo entre etapas.
+// Debe llamarse antes de Start.
+func (o *Orchestrator) SetPipeline(p *pipeline.Pipeline) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	for _, stage := range p.Stages {
+		if stage.Agent != "" && o.agents[stage.Agent] == nil {
+			return fmt.Errorf("stage %q: unknown agent %q", stage.ID, stage.Agent)
+		}
+	}
+	o.pipeline = p
+	return nil
+}
+
+// SetPoolConfig configura el pool de workers y los límites por agente.
+// Debe llamarse antes de Start.
+func (o *Orchestrator) SetPoolConfig(cfg PoolConfig) {
+	o.pool = newWorkerPool(cfg)
+	o.queue.limit = cfg.QueueLimit
+}
+
+// Events retorna el bus de eventos del ciclo de vida de tareas y ejecuciones
+func (o *Orchestrator) Events() *events.Bus {
+	return o.events
 }
 
 // Start inicia el orchestrator
 func (o *Orchestrator) Start() error {
-	go o.processQueue()
+	// Las órdenes escritas a partir de aquí son para este orchestrator; el
+	// offset se lee antes de retornar para no perder las que lleguen enseguida
+	_, offset, err := o.store.ReadControl(0)
+	if err != nil {
+		log.Printf("store: failed to read control requests: %v", err)
+	}
+	for i := 0; i < o.pool.workers; i++ {
+		go o.processQueue()
+	}
+	go o.watchControl(offset)
+	if o.scheduling {
+		go o.runSchedules()
+	}
 	return nil
 }
 
-// Stop detiene el orchestrator
+// Stop detiene el orchestrator y cierra el bus de eventos
 func (o *Orchestrator) Stop() {
 	o.cancel()
-	close(o.taskQueue)
+	o.stopTimers()
+	o.stopApprovals()
+	o.events.Close()
 }
 
-// SubmitTask envía una nueva tarea al sistema
+// SubmitTask envía una nueva tarea al sistema. Si la cola está llena
+// (PoolConfig.QueueLimit) espera a que haya sitio.
 func (o *Orchestrator) SubmitTask(task *types.Task) error {
+	return o.SubmitTaskContext(o.ctx, task)
+}
+
+// SubmitTaskContext es SubmitTask con un contexto que limita la espera.
+// Las dependencias deben existir ya: una tarea que espera a otra que nunca se
+// envía no terminaría nunca.
+func (o *Orchestrator) SubmitTaskContext(ctx context.Context, task *types.Task) error {
+	o.mu.Lock()
+	depID := o.unknownDependency(task)
+	o.mu.Unlock()
+	if depID != "" {
+		return fmt.Errorf("task depends on unknown task %s", depID)
+	}
+	if err := o.queue.waitForSpace(ctx); err != nil {
+		return err
+	}
+	return o.submit(task)
+}
+
+// submit registra y encola la tarea sin esperar: las tareas hijas nunca se
+// descartan ni bloquean al worker que las genera.
+// Si la tarea ya tiene ID (p. ej. hijas pre-registradas en el journal) se conserva.
+func (o *Orchestrator) submit(task *types.Task) error {
 	task.CreatedAt = time.Now()
+	
+	o.mu.Lock()
+	o.assignID(task)
+	o.mu.Unlock()
+	o.pipeline.Apply(task)
+	o.policy.ApplyConstraints(task)
+	
+	return o.admit(task, "")
+}
+
+// admit registra la tarea como pendiente (journal primero) y la encola
+// en cuanto sus dependencias terminan con éxito
+func (o *Orchestrator) admit(task *types.Task, from types.TaskState) error {
 	task.State = types.StatePending
+	o.writeJournal(store.JournalEntry{
+		TaskID: task.ID,
+		RunID:  task.RunID,
+		From:   from,
+		To:     types.StatePending,
+		Task:   task,
+	})
 	
 	o.mu.Lock()
-	task.ID = fmt.Sprintf("task-%d", len(o.taskState)+1)
 	o.taskState[task.ID] = task
+	o.track(task)
+	o.persistTask(task)
+	o.countTask(task)
+	ready, blocker := o.dependencyStatus(task)
+	if !ready && blocker == nil {
+		o.waiting[task.ID] = task
+	}
 	o.mu.Unlock()
 	
-	select {
-	case o.taskQueue <- task:
+	o.events.Publish(events.ForTask(events.TaskSubmitted, task))
+	
+	if blocker != nil {
+		o.skipTask(task, blocker)
 		return nil
-	case <-o.ctx.Done():
-		return o.ctx.Err()
-	default:
-		return fmt.Errorf("task queue full")
 	}
+	if !ready {
+		return nil
+	}
+	return o.enqueue(task)
 }
 
-// processQueue procesa la cola de tareas
+// enqueue coloca una tarea en la cola de ejecución
+func (o *Orchestrator) enqueue(task *types.Task) error {
+	if err := o.ctx.Err(); err != nil {
+		return err
+	}
+	o.queue.push(task)
+	return nil
+}
+
+// processQueue procesa la cola de tareas (un worker del pool)
 func (o *Orchestrator) processQueue() {
 	for {
-		select {
-		case task := <-o.taskQueue:
-			go o.executeTask(task)
-		case <-o.ctx.Done():
+		task, err := o.queue.pop(o.ctx)
+		if err != nil {
 			return
 		}
+		o.executeTask(task)
 	}
 }
 
@@ -106,26 +254,50 @@ func (o *Orchestrator) processQueue() {
 func (o *Orchestrator) executeTask(task *types.Task) {
 	startTime := time.Now()
 	
+	// Las tareas canceladas no se ejecutan y las pausadas esperan a Resume
+	if o.isCancelled(task) {
+		o.closeTask(task, types.StateCancelled, "cancelled by request")
+		return
+	}
+	if o.hold(task) {
+		return
+	}
+	
+	// No ejecutar nada de una ejecución detenida o fuera de plazo
+	if reason := o.checkDeadline(task.RunID); reason != "" {
+		o.closeTask(task, types.StateSkipped, "run halted: "+reason)
+		return
+	}
+	
 	// Actualizar estado
-	o.updateTaskState(task.ID, types.StateRunning, nil)
 	now := time.Now()
 	task.StartedAt = &now
+	o.transition(task, types.StateRunning, nil)
+	o.events.Publish(events.ForTask(events.TaskStarted, task))
 	
 	// Verificar políticas antes de ejecutar
-	if !o.policy.AllowTask(task) {
+	agentName := o.pipeline.AgentFor(task)
+	verdict := o.policy.AllowTask(agentName, task)
+	o.recordWarnings(task, verdict)
+	if !verdict.Allowed {
+		message := "task blocked by policy: " + verdict.Reason()
+		o.publishGateFailed(task, message)
 		result := &types.TaskResult{
 			TaskID:   task.ID,
 			State:    types.StateFailed,
 			Success:  false,
-			Error:    "task blocked by policy",
+			Error:    message,
+			Evidence: []types.Evidence{verdict.Evidence()},
 			Duration: time.Since(startTime),
 		}
-		o.recordResult(result)
+		o.completeTask(task, result)
 		return
 	}
 	
-	// Seleccionar agente
-	agent := o.selectAgent(task.Type)
+	// Seleccionar agente dentro del workspace de la ejecución
+	space := o.spaceFor(task)
+	task.RepoPath = space.workspace.GetRepoPath()
+	agent := space.agents[agentName]
 	if agent == nil {
 		result := &types.TaskResult{
 			TaskID:   task.ID,
@@ -134,157 +306,215 @@ func (o *Orchestrator) executeTask(task *types.Task) {
 			Error:    fmt.Sprintf("no agent available for task type: %s", task.Type),
 			Duration: time.Since(startTime),
 		}
-		o.recordResult(result)
+		o.completeTask(task, result)
 		return
 	}
 	
-	// Ejecutar agente
-	result := agent.Execute(o.ctx, task)
+	// Ejecutar agente con su slot de concurrencia y el lease del workspace
+	ctx, done := o.taskContext(task)
+	defer done()
+	release, err := o.pool.acquire(ctx, agentName, task.RepoPath)
+	if err != nil {
+		// Si el orchestrator se detiene la tarea queda para Recover
+		if o.ctx.Err() == nil {
+			o.completeTask(task, cancelledResult(task, startTime))
+		}
+		return
+	}
+	
+	// El tiempo máximo cuenta desde que el agente empieza a trabajar
+	timeout := o.timeoutFor(task)
+	execCtx, stop := ctx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		execCtx, stop = context.WithTimeout(ctx, timeout)
+	}
+	result := agent.Execute(execCtx, task)
+	timedOut := execCtx.Err() == context.DeadlineExceeded && o.ctx.Err() == nil
+	stop()
+	release()
 	result.Duration = time.Since(startTime)
+	if o.isCancelled(task) {
+		o.completeTask(task, cancelledResult(task, startTime))
+		return
+	}
+	if timedOut {
+		result = timedOutResult(result, timeout)
+	}
+	result.Evidence = append(result.Evidence, verdict.Evidence())
+	for i := range result.Evidence {
+		event := events.ForTask(events.EvidenceAttached, task)
+		event.Evidence = &result.Evidence[i]
+		o.events.Publish(event)
+	}
 	
-	// Validar resultado contra políticas (gates)
-	if result.Success && !o.policy.ValidateResult(result) {
-		result.Success = false
-		result.State = types.StateFailed
-		result.Error = "result failed policy validation"
+	// Validar resultado contra políticas (gates y reglas)
+	if result.Success {
+		verdict = o.policy.ValidateResult(agentName, task, result)
+		o.recordWarnings(task, verdict)
+		o.attachEvidence(task, result, verdict.Evidence())
+		if !verdict.Allowed {
+			result.Success = false
+			result.State = types.StateFailed
+			result.Error = "result failed policy validation: " + verdict.Reason()
+			o.publishGateFailed(task, result.Error)
+		}
 	}
 	
 	// Registrar decisión
 	if len(result.Decisions) > 0 {
-		o.mu.Lock()
-		o.memory = append(o.memory, result.Decisions...)
-		o.mu.Unlock()
+		for i := range result.Decisions {
+			result.Decisions[i].TaskID = task.ID
+		}
+		if err := o.store.AppendDecisions(result.Decisions...); err != nil {
+			log.Printf("store: failed to record decisions for %s: %v", task.ID, err)
+		}
+		for i := range result.Decisions {
+			event := events.ForTask(events.DecisionRecorded, task)
+			event.Decision = &result.Decisions[i]
+			o.events.Publish(event)
+		}
+	}
+	
+	// Un cambio de alto riesgo espera la aprobación de una persona
+	if result.Success {
+		if required, reasons := o.policy.RequiredApprovals(result); required > 0 {
+			o.awaitApproval(task, result, required, reasons)
+			return
+		}
 	}
 	
-	// Si falla y hay retries, reintentar
+	// Si falla y hay retries, reintentar según la política del tipo de tarea
 	if !result.Success && task.RetryCount < task.MaxRetries {
-		task.RetryCount++
-		task.State = types.StateRetrying
-		time.Sleep(time.Second * time.Duration(task.RetryCount))
-		go o.executeTask(task)
-		return
+		if delay, ok := o.retryDelay(task, result); ok {
+			task.RetryCount++
+			o.transition(task, types.StateRetrying, nil)
+			event := events.ForTask(events.TaskRetried, task)
+			event.Message = fmt.Sprintf("retrying in %v: %s", delay.Round(time.Millisecond), result.Error)
+			o.events.Publish(event)
+			o.scheduleRetry(task, delay)
+			return
+		}
 	}
 	
+	o.completeTask(task, result)
+}
+
+// completeTask registra el estado final de una tarea y envía las siguientes.
+// Las hijas se registran en el journal junto con la transición final para que
+// no se pierdan si el proceso muere antes de enviarlas.
+func (o *Orchestrator) completeTask(task *types.Task, result *types.TaskResult) {
+	nextTasks := o.getNextTasks(task, result)
+	if result.State == types.StateCancelled {
+		nextTasks = nil
+	}
+	
+	// Detener la ejecución si se agotó su presupuesto o no avanza
+	halt := o.checkBudget(task, result, nextTasks)
+	if halt != "" {
+		nextTasks = nil
+	}
+	
+	o.mu.Lock()
+	for _, nextTask := range nextTasks {
+		if nextTask.ParentID == "" {
+			nextTask.ParentID = task.ID
+		}
+		nextTask.RunID = task.RunID
+		inheritConstraints(nextTask, task)
+		if nextTask.Priority == "" {
+			nextTask.Priority = task.Priority
+		}
+		o.assignID(nextTask)
+		// Las hijas cuentan como pendientes antes de que el padre termine,
+		// para que la ejecución no se cierre mientras se envían
+		o.track(nextTask)
+	}
+	o.mu.Unlock()
+	
 	// Actualizar estado final
-	now = time.Now()
+	now := time.Now()
 	task.CompletedAt = &now
-	o.updateTaskState(task.ID, result.State, task.CompletedAt)
+	o.transition(task, result.State, nextTasks)
 	o.recordResult(result)
+	o.publishCompleted(task, result)
+	if result.State == types.StateFailed && len(nextTasks) == 0 {
+		o.deadLetter(task, result)
+	}
+	if halt != "" {
+		o.haltRun(task.RunID, halt)
+	}
 	
-	// Si hay subtareas, ejecutarlas
-	if nextTasks := o.getNextTasks(task, result); len(nextTasks) > 0 {
-		for _, nextTask := range nextTasks {
-			o.SubmitTask(nextTask)
+	// Si hay subtareas, ejecutarlas. Con todo el lote enviado, las
+	// dependencias que siguen sin existir ya no van a llegar.
+	for _, nextTask := range nextTasks {
+		if err := o.submit(nextTask); err != nil {
+			log.Printf("failed to submit %s (child of %s): %v", nextTask.ID, task.ID, err)
 		}
 	}
+	o.failUnknownDependencies(nextTasks)
+	
+	// Liberar (o descartar) las tareas que esperaban a esta
+	o.releaseDependents()
+	
+	// Cerrar la ejecución si ya no le quedan tareas
+	o.finishRun(task.RunID)
 }
 
-// selectAgent selecciona el agente apropiado para el tipo de tarea
-func (o *Orchestrator) selectAgent(taskType types.TaskType) agents.Agent {
-	switch taskType {
-	case types.TaskPlan:
-		return o.agents["planner"]
-	case types.TaskCode:
-		return o.agents["coder"]
-	case types.TaskTest:
-		return o.agents["tester"]
-	case types.TaskAudit:
-		return o.agents["auditor"]
-	case types.TaskRepair:
-		return o.agents["repairer"]
-	case types.TaskOptimize:
-		return o.agents["optimizer"]
-	default:
-		return nil
+// transition registra en el journal y luego aplica un cambio de estado
+func (o *Orchestrator) transition(task *types.Task, state types.TaskState, spawned []*types.Task) {
+	o.mu.RLock()
+	from := task.State
+	o.mu.RUnlock()
+	
+	o.writeJournal(store.JournalEntry{
+		TaskID:  task.ID,
+		RunID:   task.RunID,
+		From:    from,
+		To:      state,
+		Spawned: spawned,
+	})
+	o.updateTaskState(task.ID, state, task.CompletedAt)
+}
+
+// publishCompleted publica el estado final de una tarea
+func (o *Orchestrator) publishCompleted(task *types.Task, result *types.TaskResult) {
+	event := events.ForTask(events.TaskCompleted, task)
+	event.State = result.State
+	event.Message = result.Error
+	o.events.Publish(event)
+}
+
+// publishGateFailed publica el bloqueo o rechazo de una tarea por políticas
+func (o *Orchestrator) publishGateFailed(task *types.Task, reason string) {
+	event := events.ForTask(events.GateFailed, task)
+	event.Message = reason
+	o.events.Publish(event)
+}
+
+// recordWarnings registra como decisiones los avisos de un veredicto
+func (o *Orchestrator) recordWarnings(task *types.Task, verdict *policies.Verdict) {
+	for _, warning := range verdict.Warnings() {
+		o.recordDecision(task, types.Decision{
+			Agent:      "policy",
+			Reason:     warning,
+			Action:     policies.ActionWarn,
+			Timestamp:  time.Now(),
+			Confidence: 1,
+		})
 	}
 }
 
-// getNextTasks determina las siguientes tareas basadas en el resultado
+// attachEvidence añade evidencia al resultado de una tarea y la publica
+func (o *Orchestrator) attachEvidence(task *types.Task, result *types.TaskResult, evidence types.Evidence) {
+	result.Evidence = append(result.Evidence, evidence)
+	event := events.ForTask(events.EvidenceAttached, task)
+	event.Evidence = &result.Evidence[len(result.Evidence)-1]
+	o.events.Publish(event)
+}
+
+// getNextTasks determina las siguientes tareas según el pipeline
 func (o *Orchestrator) getNextTasks(task *types.Task, result *types.TaskResult) []*types.Task {
-	nextTasks := make([]*types.Task, 0)
-	
-	switch task.Type {
-	case types.TaskPlan:
-		// Después de planificar, ejecutar las subtareas
-		if subtasks, ok := result.Outputs["subtasks"].([]*types.Task); ok {
-			return subtasks
-		}
-		
-	case types.TaskCode:
-		// Después de codificar, ejecutar tests
-		if result.Success {
-			nextTasks = append(nextTasks, &types.Task{
-				Type:      types.TaskTest,
-				Objective: "test code changes",
-				Inputs:    map[string]interface{}{"task_id": task.ID},
-				ParentID:  task.ID,
-			})
-		}
-		
-	case types.TaskTest:
-		// Después de testear, decidir: repair, audit o continuar
-		if !result.Success {
-			// Si falla, reparar
-			nextTasks = append(nextTasks, &types.Task{
-				Type:      types.TaskRepair,
-				Objective: "repair failing tests",
-				Inputs:    map[string]interface{}{"test_result": result},
-				ParentID:  task.ID,
-			})
-		} else {
-			// Si pasa, auditar
-			nextTasks = append(nextTasks, &types.Task{
-				Type:      types.TaskAudit,
-				Objective: "audit code changes",
-				Inputs:    map[string]interface{}{"task_id": task.ID},
-				ParentID:  task.ID,
-			})
-		}
-		
-	case types.TaskRepair:
-		// Después de reparar, volver a testear
-		if result.Success {
-			nextTasks = append(nextTasks, &types.Task{
-				Type:      types.TaskTest,
-				Objective: "verify repair",
-				Inputs:    map[string]interface{}{"task_id": task.ID},
-				ParentID:  task.ID,
-			})
-		}
-		
-	case types.TaskAudit:
-		// Después de auditar, decidir: repair o optimize
-		if auditFailures, ok := result.Outputs["critical_findings"].([]types.AuditFinding); ok && len(auditFailures) > 0 {
-			// Si hay hallazgos críticos, reparar
-			nextTasks = append(nextTasks, &types.Task{
-				Type:      types.TaskRepair,
-				Objective: "repair audit findings",
-				Inputs:    map[string]interface{}{"audit_result": result},
-				ParentID:  task.ID,
-			})
-		} else if result.Success {
-			// Si todo ok, optimizar
-			nextTasks = append(nextTasks, &types.Task{
-				Type:      types.TaskOptimize,
-				Objective: "optimize code",
-				Inputs:    map[string]interface{}{"task_id": task.ID},
-				ParentID:  task.ID,
-			})
-		}
-		
-	case types.TaskOptimize:
-		// Después de optimizar, verificar que los tests aún pasan
-		if result.Success {
-			nextTasks = append(nextTasks, &types.Task{
-				Type:      types.TaskTest,
-				Objective: "verify optimization didn't break tests",
-				Inputs:    map[string]interface{}{"task_id": task.ID},
-				ParentID:  task.ID,
-			})
-		}
-	}
-	
-	return nextTasks
+	return o.pipeline.NextTasks(task, result)
 }
 
 // updateTaskState actualiza el estado de una tarea
@@ -297,6 +527,8 @@ func (o *Orchestrator) updateTaskState(taskID string, state types.TaskState, com
 		if completedAt != nil {
 			task.CompletedAt = completedAt
 		}
+		o.track(task)
+		o.persistTask(task)
 	}
 }
 
@@ -305,21 +537,51 @@ func (o *Orchestrator) recordResult(result *types.TaskResult) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 	o.results[result.TaskID] = result
+	if err := o.store.SaveResult(result); err != nil {
+		log.Printf("store: failed to save result for %s: %v", result.TaskID, err)
+	}
+}
+
+// persistTask guarda la tarea en el store (requiere o.mu tomado)
+func (o *Orchestrator) persistTask(task *types.Task) {
+	if err := o.store.SaveTask(task); err != nil {
+		log.Printf("store: failed to save task %s: %v", task.ID, err)
+	}
 }
 
-// GetTaskState retorna el estado de una tarea
+// GetTaskState retorna el estado de una tarea.
+// Si la tarea no pertenece a este proceso, se busca en el store.
 func (o *Orchestrator) GetTaskState(taskID string) (*types.Task, *types.TaskResult) {
 	o.mu.RLock()
-	defer o.mu.RUnlock()
-	
 	task := o.taskState[taskID]
 	result := o.results[taskID]
+	o.mu.RUnlock()
+	
+	if task == nil {
+		task, _ = o.store.GetTask(taskID)
+	}
+	if result == nil {
+		result, _ = o.store.GetResult(taskID)
+	}
 	return task, result
 }
 
+// ListTasks retorna el historial de tareas registradas en el store
+func (o *Orchestrator) ListTasks() ([]*types.Task, error) {
+	return o.store.ListTasks()
+}
+
 // GetMemory retorna la memoria de decisiones
 func (o *Orchestrator) GetMemory() []types.Decision {
-	o.mu.RLock()
-	defer o.mu.RUnlock()
-	return append([]types.Decision{}, o.memory...)
+	decisions, err := o.store.ListDecisions()
+	if err != nil {
+		log.Printf("store: failed to list decisions: %v", err)
+		return []types.Decision{}
+	}
+	return decisions
+}
+
+// GetEvidence retorna la evidencia adjuntada por una tarea
+func (o *Orchestrator) GetEvidence(taskID string) ([]types.Evidence, error) {
+	return o.store.GetEvidence(taskID)
 }
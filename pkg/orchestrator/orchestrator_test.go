@@ -0,0 +1,114 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/pipeline"
+	"github.com/nanochip/multi-agent/pkg/policies"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// fakeAgent es un agente de prueba que delega en run
+type fakeAgent struct {
+	run func(ctx context.Context, task *types.Task) *types.TaskResult
+}
+
+func (a *fakeAgent) Execute(ctx context.Context, task *types.Task) *types.TaskResult {
+	return a.run(ctx, task)
+}
+
+func (a *fakeAgent) GetContract() types.AgentContract {
+	return types.AgentContract{ID: "fake"}
+}
+
+// succeed es el resultado exitoso de una tarea
+func succeed(task *types.Task) *types.TaskResult {
+	return &types.TaskResult{TaskID: task.ID, State: types.StateSuccess, Success: true}
+}
+
+// fail es el resultado fallido de una tarea
+func fail(task *types.Task, message string) *types.TaskResult {
+	return &types.TaskResult{TaskID: task.ID, State: types.StateFailed, Error: message}
+}
+
+// testPipeline es un pipeline en el que el plan genera sus subtareas y
+// ninguna otra etapa genera tareas
+func testPipeline() *pipeline.Pipeline {
+	return &pipeline.Pipeline{Name: "test", Stages: []pipeline.Stage{
+		{ID: "plan", Type: types.TaskPlan, Transitions: []pipeline.Transition{{On: pipeline.OnSuccess, Spawn: "subtasks"}}},
+		{ID: "code", Type: types.TaskCode},
+		{ID: "test", Type: types.TaskTest},
+		{ID: "audit", Type: types.TaskAudit},
+		{ID: "optimize", Type: types.TaskOptimize},
+	}}
+}
+
+// testOrchestrator crea un orchestrator sin worktrees en el que todos los
+// agentes son run, con el pipeline de prueba. configure se aplica antes de
+// Start.
+func testOrchestrator(t *testing.T, run func(ctx context.Context, task *types.Task) *types.TaskResult, configure ...func(o *Orchestrator)) *Orchestrator {
+	t.Helper()
+	ws, _ := testRepo(t)
+	o := New(ws, policies.NewEngine())
+	o.SetIsolation(false)
+	if err := o.SetPipeline(testPipeline()); err != nil {
+		t.Fatal(err)
+	}
+	agent := &fakeAgent{run: run}
+	for name := range o.agents {
+		o.agents[name] = agent
+	}
+	for _, fn := range configure {
+		fn(o)
+	}
+	if err := o.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(o.Stop)
+	return o
+}
+
+// plan retorna el resultado de un plan que genera las subtareas indicadas.
+// Los IDs de las subtareas y sus dependencias son relativos al del plan:
+// "1" es <plan>.1.
+func plan(task *types.Task, subtasks ...*types.Task) *types.TaskResult {
+	spawned := make([]*types.Task, 0, len(subtasks))
+	for _, sub := range subtasks {
+		s := *sub
+		s.ID = task.ID + "." + sub.ID
+		s.DependsOn = make([]string, 0, len(sub.DependsOn))
+		for _, dep := range sub.DependsOn {
+			s.DependsOn = append(s.DependsOn, task.ID+"."+dep)
+		}
+		spawned = append(spawned, &s)
+	}
+	result := succeed(task)
+	result.Outputs = map[string]interface{}{"subtasks": spawned}
+	return result
+}
+
+// runObjective envía una tarea y espera a que termine su ejecución
+func runObjective(t *testing.T, o *Orchestrator, task *types.Task) *types.RunResult {
+	t.Helper()
+	if err := o.SubmitTask(task); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := o.Wait(ctx, task.RunID)
+	if err != nil {
+		t.Fatalf("Wait(%s): %v", task.RunID, err)
+	}
+	return result
+}
+
+// taskStates retorna el estado de cada tarea de una ejecución por ID
+func taskStates(result *types.RunResult) map[string]types.TaskState {
+	states := make(map[string]types.TaskState, len(result.Tasks))
+	for _, task := range result.Tasks {
+		states[task.ID] = task.State
+	}
+	return states
+}
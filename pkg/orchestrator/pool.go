@@ -0,0 +1,170 @@
+package orchestrator
+
+import (
+	"context"
+	"sync"
+)
+
+// PoolConfig configura la concurrencia del orchestrator
+type PoolConfig struct {
+	Workers         int            // tareas ejecutándose a la vez (global)
+	AgentLimits     map[string]int // máximo de tareas simultáneas por agente (0 = sin límite)
+	ExclusiveAgents []string       // agentes que modifican el workspace y requieren acceso exclusivo
+	QueueLimit      int            // tareas en cola a partir de las que SubmitTask espera (0 = sin límite)
+}
+
+// DefaultPoolConfig retorna la configuración por defecto
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		Workers:         4,
+		AgentLimits:     map[string]int{},
+		ExclusiveAgents: []string{"coder", "repairer", "optimizer", "release", "releaser"},
+		QueueLimit:      100,
+	}
+}
+
+// workerPool limita la concurrencia por agente y arbitra el acceso a cada
+// worktree: los agentes exclusivos toman un lease de escritura y el resto
+// uno compartido
+type workerPool struct {
+	workers    int
+	agentSlots map[string]chan struct{}
+	exclusive  map[string]bool
+	leases     map[string]*lease
+	mu         sync.Mutex
+}
+
+// newWorkerPool crea un pool a partir de la configuración
+func newWorkerPool(cfg PoolConfig) *workerPool {
+	p := &workerPool{
+		workers:    cfg.Workers,
+		agentSlots: make(map[string]chan struct{}),
+		exclusive:  make(map[string]bool),
+		leases:     make(map[string]*lease),
+	}
+	if p.workers <= 0 {
+		p.workers = 1
+	}
+	for agent, limit := range cfg.AgentLimits {
+		if limit > 0 {
+			p.agentSlots[agent] = make(chan struct{}, limit)
+		}
+	}
+	for _, agent := range cfg.ExclusiveAgents {
+		p.exclusive[agent] = true
+	}
+	return p
+}
+
+// acquire reserva un slot del agente y el lease del worktree indicado.
+// La función retornada libera ambos.
+func (p *workerPool) acquire(ctx context.Context, agent, worktree string) (func(), error) {
+	slots := p.agentSlots[agent]
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	lease := p.lease(worktree)
+	exclusive := p.exclusive[agent]
+	if err := lease.acquire(ctx, exclusive); err != nil {
+		if slots != nil {
+			<-slots
+		}
+		return nil, err
+	}
+
+	return func() {
+		lease.release(exclusive)
+		if slots != nil {
+			<-slots
+		}
+	}, nil
+}
+
+// lease retorna el lease asociado a un worktree
+func (p *workerPool) lease(worktree string) *lease {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	l, ok := p.leases[worktree]
+	if !ok {
+		l = newLease()
+		p.leases[worktree] = l
+	}
+	return l
+}
+
+// lease arbitra el acceso a un worktree como un RWMutex cuya espera se
+// puede cancelar: varios agentes compartidos o uno exclusivo. Un agente
+// exclusivo en espera tiene preferencia sobre los compartidos que lleguen
+// después, para que no se quede sin turno.
+type lease struct {
+	mu        sync.Mutex
+	readers   int
+	writer    bool
+	writers   int           // agentes exclusivos esperando
+	available chan struct{} // se cierra (y se reemplaza) cuando el lease cambia
+}
+
+// newLease crea un lease libre
+func newLease() *lease {
+	return &lease{available: make(chan struct{})}
+}
+
+// acquire toma el lease, esperando hasta que esté libre o ctx se cancele
+func (l *lease) acquire(ctx context.Context, exclusive bool) error {
+	l.mu.Lock()
+	if exclusive {
+		l.writers++
+	}
+	for {
+		if exclusive && !l.writer && l.readers == 0 {
+			l.writers--
+			l.writer = true
+			l.mu.Unlock()
+			return nil
+		}
+		if !exclusive && !l.writer && l.writers == 0 {
+			l.readers++
+			l.mu.Unlock()
+			return nil
+		}
+		available := l.available
+		l.mu.Unlock()
+
+		select {
+		case <-available:
+		case <-ctx.Done():
+			l.mu.Lock()
+			if exclusive {
+				// Los compartidos que esperaban a este ya pueden entrar
+				l.writers--
+				l.broadcast()
+			}
+			l.mu.Unlock()
+			return ctx.Err()
+		}
+		l.mu.Lock()
+	}
+}
+
+// release libera el lease tomado con acquire
+func (l *lease) release(exclusive bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if exclusive {
+		l.writer = false
+	} else {
+		l.readers--
+	}
+	l.broadcast()
+}
+
+// broadcast despierta a quienes esperan el lease (requiere l.mu tomado)
+func (l *lease) broadcast() {
+	close(l.available)
+	l.available = make(chan struct{})
+}
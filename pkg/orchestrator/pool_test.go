@@ -0,0 +1,190 @@
+package orchestrator
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// pending pide en segundo plano un slot del pool
+func pending(p *workerPool, agent, worktree string) <-chan func() {
+	ch := make(chan func(), 1)
+	go func() {
+		if release, err := p.acquire(context.Background(), agent, worktree); err == nil {
+			ch <- release
+		}
+	}()
+	return ch
+}
+
+// granted retorna la función que libera el slot si se obtiene antes de que
+// venza wait, o nil
+func granted(ch <-chan func(), wait time.Duration) func() {
+	select {
+	case release := <-ch:
+		return release
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+func TestPoolAgentLimits(t *testing.T) {
+	p := newWorkerPool(PoolConfig{Workers: 4, AgentLimits: map[string]int{"tester": 1}})
+
+	first := granted(pending(p, "tester", "a"), time.Second)
+	if first == nil {
+		t.Fatal("first tester should get a slot")
+	}
+	second := pending(p, "tester", "b")
+	if granted(second, 50*time.Millisecond) != nil {
+		t.Fatal("second tester should wait for the slot, even on another worktree")
+	}
+	if release := granted(pending(p, "auditor", "a"), time.Second); release == nil {
+		t.Error("agents without a limit should not wait")
+	} else {
+		release()
+	}
+	first()
+	if release := granted(second, time.Second); release == nil {
+		t.Error("waiting tester should get the released slot")
+	} else {
+		release()
+	}
+}
+
+func TestPoolExclusiveLease(t *testing.T) {
+	p := newWorkerPool(PoolConfig{Workers: 4, ExclusiveAgents: []string{"coder"}})
+
+	// Los agentes compartidos conviven en el mismo worktree
+	tester := granted(pending(p, "tester", "a"), time.Second)
+	auditor := granted(pending(p, "auditor", "a"), time.Second)
+	if tester == nil || auditor == nil {
+		t.Fatal("shared agents should run together")
+	}
+	waiting := pending(p, "coder", "a")
+	if granted(waiting, 50*time.Millisecond) != nil {
+		t.Fatal("an exclusive agent should wait for the shared leases")
+	}
+	tester()
+	auditor()
+
+	coder := granted(waiting, time.Second)
+	if coder == nil {
+		t.Fatal("coder should get the worktree once it is free")
+	}
+	shared := pending(p, "tester", "a")
+	if granted(shared, 50*time.Millisecond) != nil {
+		t.Fatal("a shared agent should wait for the exclusive lease")
+	}
+	if release := granted(pending(p, "tester", "b"), time.Second); release == nil {
+		t.Error("another worktree should not wait")
+	} else {
+		release()
+	}
+	coder()
+	if release := granted(shared, time.Second); release == nil {
+		t.Error("shared agent should get the worktree once the coder is done")
+	} else {
+		release()
+	}
+}
+
+func TestPoolDefaults(t *testing.T) {
+	if p := newWorkerPool(PoolConfig{Workers: 0, AgentLimits: map[string]int{"coder": 0}}); p.workers != 1 || p.agentSlots["coder"] != nil {
+		t.Errorf("workers = %d, slots = %v; want 1 worker and no limit", p.workers, p.agentSlots)
+	}
+}
+
+func TestWorkersLimitConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	o := testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		if task.Type == types.TaskPlan {
+			return plan(task,
+				&types.Task{ID: "1", Type: types.TaskTest},
+				&types.Task{ID: "2", Type: types.TaskTest},
+				&types.Task{ID: "3", Type: types.TaskTest},
+				&types.Task{ID: "4", Type: types.TaskTest},
+				&types.Task{ID: "5", Type: types.TaskAudit},
+			)
+		}
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(30 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return succeed(task)
+	}, func(o *Orchestrator) {
+		o.SetPoolConfig(PoolConfig{Workers: 2})
+	})
+
+	result := runObjective(t, o, &types.Task{Type: types.TaskPlan, Objective: "parallel"})
+	if result.Run.State != types.StateSuccess {
+		t.Fatalf("run state = %s, want success", result.Run.State)
+	}
+	if peak != 2 {
+		t.Errorf("peak concurrency = %d, want 2", peak)
+	}
+}
+
+func TestPoolAcquireRespectsContext(t *testing.T) {
+	p := newWorkerPool(PoolConfig{Workers: 4, AgentLimits: map[string]int{"coder": 1}, ExclusiveAgents: []string{"coder"}})
+
+	tester := granted(pending(p, "tester", "a"), time.Second)
+	if tester == nil {
+		t.Fatal("tester should get the worktree")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if release, err := p.acquire(ctx, "coder", "a"); err != context.DeadlineExceeded {
+		if release != nil {
+			release()
+		}
+		t.Fatalf("acquire(coder, a) = %v while the worktree was shared, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Al rendirse devuelve su slot del agente
+	if release := granted(pending(p, "coder", "b"), time.Second); release == nil {
+		t.Error("coder slot was not released after the context ended")
+	} else {
+		release()
+	}
+	tester()
+}
+
+func TestPoolExclusiveWaiterGivesUp(t *testing.T) {
+	p := newWorkerPool(PoolConfig{Workers: 4, ExclusiveAgents: []string{"coder"}})
+
+	tester := granted(pending(p, "tester", "a"), time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	gaveUp := make(chan error, 1)
+	go func() {
+		_, err := p.acquire(ctx, "coder", "a")
+		gaveUp <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// El coder en espera tiene preferencia sobre los compartidos que llegan
+	auditor := pending(p, "auditor", "a")
+	if granted(auditor, 50*time.Millisecond) != nil {
+		t.Fatal("a shared agent should not overtake a waiting exclusive one")
+	}
+	cancel()
+	if err := <-gaveUp; err != context.Canceled {
+		t.Fatalf("acquire(coder, a) = %v, want %v", err, context.Canceled)
+	}
+	if release := granted(auditor, time.Second); release == nil {
+		t.Error("shared agent should get the worktree once the coder gives up")
+	} else {
+		release()
+	}
+	tester()
+}
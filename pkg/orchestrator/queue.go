@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// priorityLevels es el número de prioridades distintas (ver types.Priority.Rank)
+const priorityLevels = 4
+
+// runTasks es la cola de una ejecución dentro de un nivel de prioridad
+type runTasks struct {
+	runID string
+	tasks []*types.Task
+}
+
+// taskQueue es la cola de tareas listas para ejecutarse. Sale primero la
+// tarea de mayor prioridad; dentro de una prioridad, las ejecuciones se
+// turnan (round-robin) para que una con muchas tareas no acapare los
+// workers. No tiene tope: el estado ya está en el store y SubmitTask aplica
+// backpressure con waitForSpace.
+type taskQueue struct {
+	mu     sync.Mutex
+	levels [priorityLevels][]*runTasks // por prioridad, ejecuciones en turno
+	size   int
+	limit  int           // tareas a partir de las que waitForSpace espera (0 = sin límite)
+	ready  chan struct{} // hay tareas
+	space  chan struct{} // se liberó sitio
+}
+
+// newTaskQueue crea una cola vacía
+func newTaskQueue(limit int) *taskQueue {
+	return &taskQueue{
+		limit: limit,
+		ready: make(chan struct{}, 1),
+		space: make(chan struct{}, 1),
+	}
+}
+
+// push encola una tarea al final de la cola de su ejecución
+func (q *taskQueue) push(task *types.Task) {
+	level := task.Priority.Rank()
+	if level < 0 {
+		level = types.PriorityNormal.Rank()
+	}
+
+	q.mu.Lock()
+	var queue *runTasks
+	for _, rq := range q.levels[level] {
+		if rq.runID == task.RunID {
+			queue = rq
+			break
+		}
+	}
+	if queue == nil {
+		queue = &runTasks{runID: task.RunID}
+		q.levels[level] = append(q.levels[level], queue)
+	}
+	queue.tasks = append(queue.tasks, task)
+	q.size++
+	q.mu.Unlock()
+
+	notify(q.ready)
+}
+
+// pop retorna la siguiente tarea, esperando si no hay ninguna
+func (q *taskQueue) pop(ctx context.Context) (*types.Task, error) {
+	for {
+		q.mu.Lock()
+		task := q.next()
+		remaining := q.size
+		q.mu.Unlock()
+
+		if task != nil {
+			// Despertar a otro worker si quedan tareas y a quien espere sitio
+			if remaining > 0 {
+				notify(q.ready)
+			}
+			notify(q.space)
+			return task, nil
+		}
+
+		select {
+		case <-q.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// next saca la tarea de la ejecución en turno del nivel más prioritario y
+// pasa el turno a la siguiente ejecución (requiere q.mu tomado)
+func (q *taskQueue) next() *types.Task {
+	for level := priorityLevels - 1; level >= 0; level-- {
+		runs := q.levels[level]
+		if len(runs) == 0 {
+			continue
+		}
+		rq := runs[0]
+		task := rq.tasks[0]
+		rq.tasks = rq.tasks[1:]
+		runs = runs[1:]
+		if len(rq.tasks) > 0 {
+			runs = append(runs, rq)
+		}
+		q.levels[level] = runs
+		q.size--
+		return task
+	}
+	return nil
+}
+
+// waitForSpace bloquea mientras la cola tenga limit tareas o más
+func (q *taskQueue) waitForSpace(ctx context.Context) error {
+	for {
+		q.mu.Lock()
+		full := q.limit > 0 && q.size >= q.limit
+		q.mu.Unlock()
+		if !full {
+			// Encadenar el aviso para el siguiente que espere
+			notify(q.space)
+			return nil
+		}
+
+		select {
+		case <-q.space:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// notify despierta a un posible receptor sin bloquear
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
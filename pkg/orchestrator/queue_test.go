@@ -0,0 +1,128 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// drain saca todas las tareas de la cola y retorna sus IDs en orden
+func drain(t *testing.T, q *taskQueue) string {
+	t.Helper()
+	ids := make([]string, 0)
+	for q.size > 0 {
+		task, err := q.pop(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, task.ID)
+	}
+	return strings.Join(ids, " ")
+}
+
+func TestQueuePriority(t *testing.T) {
+	q := newTaskQueue(0)
+	q.push(&types.Task{ID: "low", RunID: "r", Priority: types.PriorityLow})
+	q.push(&types.Task{ID: "default", RunID: "r"})
+	q.push(&types.Task{ID: "urgent", RunID: "r", Priority: types.PriorityUrgent})
+	q.push(&types.Task{ID: "normal", RunID: "r", Priority: types.PriorityNormal})
+	q.push(&types.Task{ID: "high", RunID: "r", Priority: types.PriorityHigh})
+	q.push(&types.Task{ID: "unknown", RunID: "r", Priority: "whenever"})
+
+	// Sin prioridad o con una desconocida cuenta como normal, en orden de llegada
+	if got, want := drain(t, q), "urgent high default normal unknown low"; got != want {
+		t.Errorf("order = %q, want %q", got, want)
+	}
+}
+
+func TestQueueRoundRobin(t *testing.T) {
+	q := newTaskQueue(0)
+	for _, id := range []string{"a1", "a2", "a3", "a4"} {
+		q.push(&types.Task{ID: id, RunID: "a"})
+	}
+	q.push(&types.Task{ID: "b1", RunID: "b"})
+	q.push(&types.Task{ID: "b2", RunID: "b"})
+	q.push(&types.Task{ID: "c1", RunID: "c"})
+	q.push(&types.Task{ID: "a-high", RunID: "a", Priority: types.PriorityHigh})
+
+	// Las ejecuciones se turnan dentro de una prioridad y una con muchas
+	// tareas no retrasa a las demás
+	if got, want := drain(t, q), "a-high a1 b1 c1 a2 b2 a3 a4"; got != want {
+		t.Errorf("order = %q, want %q", got, want)
+	}
+}
+
+func TestQueuePopWaits(t *testing.T) {
+	q := newTaskQueue(0)
+	popped := make(chan *types.Task, 1)
+	go func() {
+		if task, err := q.pop(context.Background()); err == nil {
+			popped <- task
+		}
+	}()
+	time.Sleep(20 * time.Millisecond)
+	q.push(&types.Task{ID: "late", RunID: "r"})
+	select {
+	case task := <-popped:
+		if task.ID != "late" {
+			t.Errorf("pop() = %s, want late", task.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pop() did not wake up on push")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := q.pop(ctx); err != context.DeadlineExceeded {
+		t.Errorf("pop() on an empty queue = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestQueueWaitForSpace(t *testing.T) {
+	q := newTaskQueue(2)
+	q.push(&types.Task{ID: "1", RunID: "r"})
+	if err := q.waitForSpace(context.Background()); err != nil {
+		t.Fatalf("waitForSpace() below the limit = %v", err)
+	}
+	q.push(&types.Task{ID: "2", RunID: "r"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.waitForSpace(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("waitForSpace() at the limit = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- q.waitForSpace(context.Background()) }()
+	select {
+	case err := <-done:
+		t.Fatalf("waitForSpace() returned %v while the queue was full", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if _, err := q.pop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("waitForSpace() = %v after a pop", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waitForSpace() did not wake up after a pop")
+	}
+}
+
+func TestQueueUnlimited(t *testing.T) {
+	q := newTaskQueue(0)
+	for i := 0; i < 100; i++ {
+		q.push(&types.Task{ID: "t", RunID: "r"})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := q.waitForSpace(ctx); err != nil {
+		t.Errorf("waitForSpace() without a limit = %v", err)
+	}
+}
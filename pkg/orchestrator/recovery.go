@@ -0,0 +1,181 @@
+package orchestrator
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/events"
+	"github.com/nanochip/multi-agent/pkg/store"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// RecoveryMode define qué hacer con el trabajo inconcluso de procesos anteriores
+type RecoveryMode string
+
+const (
+	// RecoverResume re-encola las tareas inconclusas (incluidas las abandonadas)
+	RecoverResume RecoveryMode = "resume"
+	// RecoverAbandon marca las tareas inconclusas como abandonadas
+	RecoverAbandon RecoveryMode = "abandon"
+	// RecoverIgnore no toca el trabajo inconcluso
+	RecoverIgnore RecoveryMode = "ignore"
+)
+
+// ParseRecoveryMode convierte un string en RecoveryMode
+func ParseRecoveryMode(s string) (RecoveryMode, error) {
+	switch mode := RecoveryMode(s); mode {
+	case RecoverResume, RecoverAbandon, RecoverIgnore:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown recovery mode: %q", s)
+}
+
+// Recover reconstruye el trabajo inconcluso a partir del journal.
+//
+// Se consideran inconclusas las tareas cuyo último estado registrado es
+// pending, running, retrying o awaiting-approval (se vuelven a ejecutar y
+// piden aprobación de nuevo), y las hijas registradas al completar su padre
+// que nunca llegaron a enviarse. Con RecoverResume también se retoman las
+// tareas abandonadas. Si runID no es vacío solo se procesa esa ejecución.
+//
+// Es idempotente dentro de un proceso: las tareas que este orchestrator ya
+// gestiona no se vuelven a encolar.
+func (o *Orchestrator) Recover(runID string, mode RecoveryMode) ([]*types.Task, error) {
+	if mode == RecoverIgnore {
+		return nil, nil
+	}
+
+	entries, err := o.store.ReadJournal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	snapshots := make(map[string]*types.Task)
+	states := make(map[string]types.TaskState)
+	order := make([]string, 0)
+
+	for _, entry := range entries {
+		if _, seen := states[entry.TaskID]; !seen {
+			order = append(order, entry.TaskID)
+		}
+		if entry.Task != nil {
+			snapshots[entry.TaskID] = entry.Task
+		}
+		states[entry.TaskID] = entry.To
+
+		for _, child := range entry.Spawned {
+			if _, seen := states[child.ID]; seen {
+				continue
+			}
+			order = append(order, child.ID)
+			snapshots[child.ID] = child
+			states[child.ID] = types.StatePending
+		}
+	}
+
+	recovered := make([]*types.Task, 0)
+	for _, id := range order {
+		state := states[id]
+		if state.IsTerminal() && !(mode == RecoverResume && state == types.StateAbandoned) {
+			continue
+		}
+
+		o.mu.RLock()
+		_, owned := o.taskState[id]
+		o.mu.RUnlock()
+		if owned {
+			continue
+		}
+
+		task, err := o.store.GetTask(id)
+		if err != nil {
+			task = snapshots[id]
+		}
+		if task == nil || (runID != "" && task.RunID != runID) {
+			continue
+		}
+
+		switch mode {
+		case RecoverAbandon:
+			now := time.Now()
+			task.CompletedAt = &now
+			o.writeJournal(store.JournalEntry{
+				TaskID: task.ID,
+				RunID:  task.RunID,
+				From:   state,
+				To:     types.StateAbandoned,
+			})
+			task.State = types.StateAbandoned
+			o.mu.Lock()
+			o.persistTask(task)
+			if _, tracked := o.members[task.RunID]; tracked {
+				o.track(task)
+			}
+			o.mu.Unlock()
+			o.events.Publish(events.ForTask(events.TaskCompleted, task))
+
+		case RecoverResume:
+			o.reopenRun(task)
+			task.StartedAt = nil
+			task.CompletedAt = nil
+			if err := o.admit(task, state); err != nil {
+				return recovered, fmt.Errorf("failed to re-enqueue %s: %w", task.ID, err)
+			}
+		}
+		recovered = append(recovered, task)
+	}
+
+	if mode == RecoverAbandon {
+		closed := make(map[string]bool)
+		for _, task := range recovered {
+			if !closed[task.RunID] {
+				closed[task.RunID] = true
+				o.finishRun(task.RunID)
+			}
+		}
+	}
+
+	return recovered, nil
+}
+
+// assignID asigna un ID único y la ejecución a una tarea nueva.
+// Una tarea sin padre conocido abre una ejecución nueva (requiere o.mu tomado).
+func (o *Orchestrator) assignID(task *types.Task) {
+	for task.ID == "" {
+		id := newID("task")
+		if _, taken := o.taskState[id]; taken {
+			continue
+		}
+		if _, err := o.store.GetTask(id); err == nil {
+			continue
+		}
+		task.ID = id
+	}
+	if task.RunID == "" {
+		if parent, ok := o.taskState[task.ParentID]; ok {
+			task.RunID = parent.RunID
+		} else {
+			o.startRun(task)
+		}
+	}
+}
+
+// newID genera un ID único entre procesos: prefijo, fecha y sufijo aleatorio
+func newID(prefix string) string {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// Sin fuente aleatoria: usar el reloj con resolución de nanosegundos
+		return fmt.Sprintf("%s-%x", prefix, time.Now().UnixNano())
+	}
+	return fmt.Sprintf("%s-%s-%x", prefix, time.Now().UTC().Format("20060102"), b)
+}
+
+// writeJournal añade una entrada al journal write-ahead
+func (o *Orchestrator) writeJournal(entry store.JournalEntry) {
+	entry.Time = time.Now()
+	if err := o.store.AppendJournal(entry); err != nil {
+		log.Printf("store: failed to journal %s -> %s: %v", entry.TaskID, entry.To, err)
+	}
+}
@@ -0,0 +1,92 @@
+package orchestrator
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/nanochip/multi-agent/pkg/agents"
+	"github.com/nanochip/multi-agent/pkg/policies"
+	"github.com/nanochip/multi-agent/pkg/store"
+	"github.com/nanochip/multi-agent/pkg/types"
+	"github.com/nanochip/multi-agent/pkg/workspace"
+)
+
+// testRepo crea un repositorio git vacío con su workspace y la ruta del store
+func testRepo(t *testing.T) (*workspace.Manager, string) {
+	t.Helper()
+	repo := t.TempDir()
+	if _, err := git.PlainInit(repo, false); err != nil {
+		t.Fatal(err)
+	}
+	ws, err := workspace.NewManager(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ws, filepath.Join(repo, ".multi-agent")
+}
+
+// fileOrchestrator crea un orchestrator sin arrancar sobre un store en disco
+func fileOrchestrator(t *testing.T, ws *workspace.Manager, root string) *Orchestrator {
+	t.Helper()
+	st, err := store.NewFileStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := NewWithStore(ws, policies.NewEngine(), st)
+	t.Cleanup(o.Stop)
+	return o
+}
+
+// failedTests es el resultado de un tester con un test que falla
+func failedTests() *types.TaskResult {
+	return &types.TaskResult{
+		TaskID: "task-tester",
+		State:  types.StateFailed,
+		Outputs: map[string]interface{}{
+			"test_result": &types.TestResult{
+				Failed:   1,
+				Coverage: 85,
+				Failures: []types.TestFailure{{Test: "TestGet", Package: "cache", Message: "runtime error: nil pointer dereference"}},
+			},
+		},
+	}
+}
+
+func TestRecoverResumedRepairKeepsFailureContext(t *testing.T) {
+	ws, root := testRepo(t)
+
+	// Un proceso envía la reparación y muere antes de ejecutarla
+	first := fileOrchestrator(t, ws, root)
+	task := &types.Task{
+		Type:      types.TaskRepair,
+		Objective: "repair failing tests",
+		Inputs:    map[string]interface{}{"test_result": failedTests()},
+	}
+	if err := first.SubmitTask(task); err != nil {
+		t.Fatal(err)
+	}
+
+	// Otro proceso la retoma desde el journal
+	second := fileOrchestrator(t, ws, root)
+	recovered, err := second.Recover("", RecoverResume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovered) != 1 || recovered[0].ID != task.ID {
+		t.Fatalf("Recover() = %v, want [%s]", recovered, task.ID)
+	}
+	if _, typed := recovered[0].Inputs["test_result"].(*types.TaskResult); typed {
+		t.Fatal("test_result should come back decoded from JSON")
+	}
+
+	result := agents.NewRepairer(ws, nil).Execute(context.Background(), recovered[0])
+	if got := result.Outputs["strategy"]; got != "repair_1_failures" {
+		t.Errorf("strategy = %v, want repair_1_failures", got)
+	}
+	fixes, _ := result.Outputs["applied_fixes"].([]string)
+	if len(fixes) != 1 || fixes[0] != "add nil pointer checks" {
+		t.Errorf("applied_fixes = %v, want [add nil pointer checks]", fixes)
+	}
+}
@@ -0,0 +1,196 @@
+package orchestrator
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/events"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// unclassified es la categoría de los fallos que evaluation.Engine no reconoce
+const unclassified = "unclassified"
+
+// RetryPolicy define cuándo y con qué espera se reintenta una tarea fallida.
+// El número de intentos lo sigue fijando task.MaxRetries.
+type RetryPolicy struct {
+	InitialDelay time.Duration // espera antes del primer reintento
+	MaxDelay     time.Duration // tope de la espera (0 = sin tope)
+	Multiplier   float64       // factor de crecimiento entre reintentos
+	Jitter       float64       // variación aleatoria relativa (0.2 = ±20%)
+	RetryOn      []string      // categorías reintentables (vacío = todas)
+	NeverRetry   []string      // categorías que nunca se reintentan
+}
+
+// DefaultRetryPolicy retorna la política por defecto: backoff exponencial y
+// sin reintentar errores de compilación, que van directo a reparación
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		InitialDelay: time.Second,
+		MaxDelay:     30 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+		NeverRetry:   []string{"compilation", "lint"},
+	}
+}
+
+// SetRetryPolicy configura la política de reintentos de un tipo de tarea.
+// Debe llamarse antes de Start.
+func (o *Orchestrator) SetRetryPolicy(taskType types.TaskType, policy RetryPolicy) {
+	o.retries[taskType] = policy
+}
+
+// retryPolicyFor retorna la política de la tarea: la de su etapa en el
+// pipeline, la de su tipo o, si no hay, la por defecto
+func (o *Orchestrator) retryPolicyFor(task *types.Task) RetryPolicy {
+	policy, ok := o.retries[task.Type]
+	if !ok {
+		policy = DefaultRetryPolicy()
+	}
+
+	stage := o.pipeline.StageFor(task)
+	if stage == nil || stage.Retry == nil {
+		return policy
+	}
+	r := stage.Retry
+	// Las duraciones ya se validaron al cargar el pipeline
+	if d, err := time.ParseDuration(r.InitialDelay); err == nil {
+		policy.InitialDelay = d
+	}
+	if d, err := time.ParseDuration(r.MaxDelay); err == nil {
+		policy.MaxDelay = d
+	}
+	if r.Multiplier != nil {
+		policy.Multiplier = *r.Multiplier
+	}
+	if r.Jitter != nil {
+		policy.Jitter = *r.Jitter
+	}
+	if r.RetryOn != nil {
+		policy.RetryOn = r.RetryOn
+	}
+	if r.NeverRetry != nil {
+		policy.NeverRetry = r.NeverRetry
+	}
+	return policy
+}
+
+// Delay calcula la espera antes del reintento número attempt (desde 1)
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.InitialDelay) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// Retryable decide si un fallo con las categorías dadas se reintenta.
+// Si no, retorna la categoría que lo impide.
+func (p RetryPolicy) Retryable(categories []string) (bool, string) {
+	for _, category := range categories {
+		for _, never := range p.NeverRetry {
+			if category == never {
+				return false, category
+			}
+		}
+	}
+	if len(p.RetryOn) == 0 {
+		return true, ""
+	}
+	for _, category := range categories {
+		for _, allowed := range p.RetryOn {
+			if category == allowed {
+				return true, ""
+			}
+		}
+	}
+	return false, strings.Join(categories, ",")
+}
+
+// failureCategories clasifica el fallo de un resultado con evaluation.Engine
+func (o *Orchestrator) failureCategories(result *types.TaskResult) []string {
+	seen := make(map[string]bool)
+	for _, classification := range o.evaluator.ParseResult(result) {
+		seen[classification.Pattern.Category] = true
+	}
+	if len(seen) == 0 {
+		return []string{unclassified}
+	}
+	categories := make([]string, 0, len(seen))
+	for category := range seen {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// retryDelay decide si la tarea fallida se reintenta y tras cuánto tiempo.
+// Cuando el tipo de fallo no es reintentable se registra la decisión y la
+// tarea sigue el flujo de fallo del pipeline (p. ej. reparación).
+func (o *Orchestrator) retryDelay(task *types.Task, result *types.TaskResult) (time.Duration, bool) {
+	policy := o.retryPolicyFor(task)
+	categories := o.failureCategories(result)
+
+	if ok, category := policy.Retryable(categories); !ok {
+		decision := types.Decision{
+			TaskID:     task.ID,
+			Agent:      "orchestrator",
+			Reason:     fmt.Sprintf("%s failure is not retryable for %s tasks", category, task.Type),
+			Action:     "skip-retry",
+			Timestamp:  time.Now(),
+			Confidence: 1,
+			Metadata:   map[string]interface{}{"categories": categories},
+		}
+		if err := o.store.AppendDecisions(decision); err != nil {
+			log.Printf("store: failed to record decisions for %s: %v", task.ID, err)
+		}
+		event := events.ForTask(events.DecisionRecorded, task)
+		event.Decision = &decision
+		o.events.Publish(event)
+		return 0, false
+	}
+
+	return policy.Delay(task.RetryCount + 1), true
+}
+
+// scheduleRetry vuelve a encolar la tarea cuando vence su espera, sin
+// ocupar un worker mientras tanto
+func (o *Orchestrator) scheduleRetry(task *types.Task, delay time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.timers[task.ID] = time.AfterFunc(delay, func() {
+		o.mu.Lock()
+		delete(o.timers, task.ID)
+		o.mu.Unlock()
+
+		if o.ctx.Err() != nil {
+			return
+		}
+		if err := o.enqueue(task); err != nil {
+			log.Printf("failed to re-enqueue %s: %v", task.ID, err)
+		}
+	})
+}
+
+// stopTimers cancela los reintentos programados
+func (o *Orchestrator) stopTimers() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for id, timer := range o.timers {
+		timer.Stop()
+		delete(o.timers, id)
+	}
+}
@@ -0,0 +1,207 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/pipeline"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		want   []time.Duration // esperas de los intentos 1, 2, ...
+	}{
+		{
+			name:   "exponential",
+			policy: RetryPolicy{InitialDelay: time.Second, Multiplier: 2},
+			want:   []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		{
+			name:   "capped",
+			policy: RetryPolicy{InitialDelay: time.Second, MaxDelay: 5 * time.Second, Multiplier: 3},
+			want:   []time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name:   "multiplier below 1 is constant",
+			policy: RetryPolicy{InitialDelay: time.Second, Multiplier: 0.5},
+			want:   []time.Duration{time.Second, time.Second, time.Second},
+		},
+		{
+			name:   "no multiplier is constant",
+			policy: RetryPolicy{InitialDelay: 500 * time.Millisecond},
+			want:   []time.Duration{500 * time.Millisecond, 500 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				if got := tt.policy.Delay(i + 1); got != want {
+					t.Errorf("Delay(%d) = %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryDelayJitter(t *testing.T) {
+	policy := RetryPolicy{InitialDelay: time.Second, MaxDelay: 4 * time.Second, Multiplier: 2, Jitter: 0.25}
+	varied := false
+	for i := 0; i < 200; i++ {
+		// El tope se aplica antes de la variación
+		got := policy.Delay(5)
+		if got < 3*time.Second || got > 5*time.Second {
+			t.Fatalf("Delay(5) = %v, want 4s ±25%%", got)
+		}
+		varied = varied || got != 4*time.Second
+	}
+	if !varied {
+		t.Error("jitter never changed the delay")
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     RetryPolicy
+		categories []string
+		want       bool
+		blocker    string
+	}{
+		{"default retries runtime", DefaultRetryPolicy(), []string{"runtime"}, true, ""},
+		{"default retries unclassified", DefaultRetryPolicy(), []string{unclassified}, true, ""},
+		{"default skips compilation", DefaultRetryPolicy(), []string{"compilation"}, false, "compilation"},
+		{"never retry wins", DefaultRetryPolicy(), []string{"runtime", "lint"}, false, "lint"},
+		{
+			name:       "never retry wins over retry on",
+			policy:     RetryPolicy{RetryOn: []string{"timeout"}, NeverRetry: []string{"timeout"}},
+			categories: []string{"timeout"},
+			blocker:    "timeout",
+		},
+		{
+			name:       "retry on accepts any listed category",
+			policy:     RetryPolicy{RetryOn: []string{"timeout"}},
+			categories: []string{"runtime", "timeout"},
+			want:       true,
+		},
+		{
+			name:       "retry on rejects the rest",
+			policy:     RetryPolicy{RetryOn: []string{"timeout"}},
+			categories: []string{"runtime", "test"},
+			blocker:    "runtime,test",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, blocker := tt.policy.Retryable(tt.categories)
+			if got != tt.want || blocker != tt.blocker {
+				t.Errorf("Retryable(%v) = %v, %q; want %v, %q", tt.categories, got, blocker, tt.want, tt.blocker)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyForStage(t *testing.T) {
+	ws, _ := testRepo(t)
+	o := fileOrchestrator(t, ws, t.TempDir())
+	o.SetRetryPolicy(types.TaskTest, RetryPolicy{InitialDelay: time.Second, Multiplier: 2, NeverRetry: []string{"lint"}})
+
+	p := testPipeline()
+	zero := 0.0
+	for i := range p.Stages {
+		switch p.Stages[i].Type {
+		case types.TaskAudit:
+			p.Stages[i].Retry = &pipeline.Retry{InitialDelay: "2s", MaxDelay: "10s", RetryOn: []string{"timeout"}}
+		case types.TaskCode:
+			p.Stages[i].Retry = &pipeline.Retry{Multiplier: &zero, Jitter: &zero}
+		}
+	}
+	if err := o.SetPipeline(p); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sin retry en la etapa se usa la política del tipo de tarea
+	if got := o.retryPolicyFor(&types.Task{Type: types.TaskTest}); got.InitialDelay != time.Second || len(got.NeverRetry) != 1 {
+		t.Errorf("test policy = %+v, want the one set for test tasks", got)
+	}
+	// La etapa sobrescribe solo los campos que indica
+	got := o.retryPolicyFor(&types.Task{Type: types.TaskAudit})
+	want := DefaultRetryPolicy()
+	want.InitialDelay, want.MaxDelay, want.RetryOn = 2*time.Second, 10*time.Second, []string{"timeout"}
+	if got.InitialDelay != want.InitialDelay || got.MaxDelay != want.MaxDelay || got.Multiplier != want.Multiplier ||
+		got.Jitter != want.Jitter || strings.Join(got.RetryOn, ",") != "timeout" || strings.Join(got.NeverRetry, ",") != "compilation,lint" {
+		t.Errorf("audit policy = %+v, want %+v", got, want)
+	}
+
+	// Un 0 explícito también sobrescribe: esperas fijas y sin jitter
+	got = o.retryPolicyFor(&types.Task{Type: types.TaskCode})
+	if got.Multiplier != 0 || got.Jitter != 0 {
+		t.Errorf("code policy = %+v, want multiplier and jitter 0", got)
+	}
+	for attempt := 1; attempt <= 3; attempt++ {
+		if d := got.Delay(attempt); d != got.InitialDelay {
+			t.Errorf("code Delay(%d) = %v, want a fixed %v", attempt, d, got.InitialDelay)
+		}
+	}
+}
+
+func TestFailedTaskRetries(t *testing.T) {
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	o := testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		if task.Type == types.TaskPlan {
+			return plan(task,
+				&types.Task{ID: "flaky", Type: types.TaskTest, MaxRetries: 2},
+				&types.Task{ID: "broken", Type: types.TaskCode, MaxRetries: 2},
+			)
+		}
+		mu.Lock()
+		attempts[task.ID]++
+		n := attempts[task.ID]
+		mu.Unlock()
+		switch {
+		case task.Type == types.TaskCode:
+			return fail(task, "undefined: cache.Get")
+		case n == 1:
+			return fail(task, "connection reset by peer")
+		}
+		return succeed(task)
+	}, func(o *Orchestrator) {
+		fast := RetryPolicy{InitialDelay: time.Millisecond, NeverRetry: []string{"compilation"}}
+		o.SetRetryPolicy(types.TaskTest, fast)
+		o.SetRetryPolicy(types.TaskCode, fast)
+	})
+
+	root := &types.Task{Type: types.TaskPlan, Objective: "retry"}
+	result := runObjective(t, o, root)
+	states := taskStates(result)
+
+	// Un fallo reintentable se reintenta hasta que pasa
+	flaky := root.ID + ".flaky"
+	if states[flaky] != types.StateSuccess || attempts[flaky] != 2 {
+		t.Errorf("flaky: state = %s after %d attempts, want success after 2", states[flaky], attempts[flaky])
+	}
+	// Un error de compilación no se reintenta
+	broken := root.ID + ".broken"
+	if states[broken] != types.StateFailed || attempts[broken] != 1 {
+		t.Errorf("broken: state = %s after %d attempts, want failed after 1", states[broken], attempts[broken])
+	}
+	decisions, err := o.store.ListDecisions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	skipped := false
+	for _, decision := range decisions {
+		if decision.TaskID == broken && decision.Action == "skip-retry" {
+			skipped = true
+		}
+	}
+	if !skipped {
+		t.Error("no skip-retry decision recorded for the compilation failure")
+	}
+}
@@ -0,0 +1,391 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/events"
+	"github.com/nanochip/multi-agent/pkg/store"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// TaskNode es un nodo del árbol de tareas de una ejecución
+type TaskNode struct {
+	Task     *types.Task
+	Children []*TaskNode
+}
+
+// ListRuns retorna todas las ejecuciones registradas en el store
+func (o *Orchestrator) ListRuns() ([]*types.Run, error) {
+	return o.store.ListRuns()
+}
+
+// GetRun retorna una ejecución junto con todas sus tareas
+func (o *Orchestrator) GetRun(runID string) (*types.Run, []*types.Task, error) {
+	run, err := o.store.GetRun(runID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("run %s: %w", runID, err)
+	}
+	tasks, err := o.runTasks(runID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return run, tasks, nil
+}
+
+// Done retorna un canal que se cierra cuando la ejecución termina, es decir,
+// cuando todo su árbol de tareas alcanza un estado final
+func (o *Orchestrator) Done(runID string) <-chan struct{} {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	done, ok := o.runDone[runID]
+	if !ok {
+		done = make(chan struct{})
+		o.runDone[runID] = done
+		if run := o.runs[runID]; run != nil && run.CompletedAt != nil {
+			close(done)
+		}
+	}
+	return done
+}
+
+// Wait bloquea hasta que la ejecución termina (o hasta que ctx se cancela)
+// y retorna su resultado agregado. Las ejecuciones de otros procesos se
+// siguen consultando el store periódicamente.
+func (o *Orchestrator) Wait(ctx context.Context, runID string) (*types.RunResult, error) {
+	if _, err := o.store.GetRun(runID); err != nil {
+		return nil, fmt.Errorf("run %s: %w", runID, err)
+	}
+
+	done := o.Done(runID)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return o.RunResult(runID)
+		case <-ticker.C:
+			if run, err := o.store.GetRun(runID); err == nil && run.CompletedAt != nil {
+				return o.RunResult(runID)
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// RunResult retorna el resultado agregado de una ejecución: su estado,
+// sus tareas y el resultado de cada una
+func (o *Orchestrator) RunResult(runID string) (*types.RunResult, error) {
+	run, tasks, err := o.GetRun(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]*types.TaskResult, len(tasks))
+	for _, task := range tasks {
+		if _, result := o.GetTaskState(task.ID); result != nil {
+			results[task.ID] = result
+		}
+	}
+	return &types.RunResult{Run: run, Tasks: tasks, Results: results}, nil
+}
+
+// TaskTree construye el árbol de tareas a partir de ParentID.
+// Las tareas cuyo padre no está en la lista quedan como raíces.
+func TaskTree(tasks []*types.Task) []*TaskNode {
+	nodes := make(map[string]*TaskNode, len(tasks))
+	for _, task := range tasks {
+		nodes[task.ID] = &TaskNode{Task: task}
+	}
+
+	roots := make([]*TaskNode, 0)
+	for _, task := range tasks {
+		node := nodes[task.ID]
+		if parent, ok := nodes[task.ParentID]; ok && task.ParentID != task.ID {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+// startRun abre una ejecución nueva con la tarea como raíz (requiere o.mu tomado)
+func (o *Orchestrator) startRun(task *types.Task) {
+	run := &types.Run{
+		ID:         newID("run"),
+		Objective:  task.Objective,
+		RootTaskID: task.ID,
+		State:      types.StateRunning,
+		StartedAt:  time.Now(),
+	}
+	task.RunID = run.ID
+	o.runs[run.ID] = run
+	o.persistRun(run)
+}
+
+// reopenRun marca como activa la ejecución de una tarea que se retoma
+func (o *Orchestrator) reopenRun(task *types.Task) {
+	tasks, err := o.runTasks(task.RunID)
+	if err != nil {
+		log.Printf("store: failed to load run %s: %v", task.RunID, err)
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, tracked := o.members[task.RunID]; !tracked {
+		for _, t := range tasks {
+			o.track(t)
+		}
+	}
+	run := o.loadRun(task.RunID, tasks)
+	run.State = types.StateRunning
+	run.CompletedAt = nil
+	run.Summary = nil
+	run.Reason = ""
+	o.persistRun(run)
+
+	// Retomar cuenta lo ya consumido, salvo las tareas que se vuelven a admitir
+	if _, ok := o.budgets[run.ID]; !ok {
+		b := o.budgetFor(run.ID)
+		for _, t := range tasks {
+			if t.State.IsTerminal() && t.State != types.StateAbandoned {
+				b.tasks++
+				if t.Type == types.TaskRepair {
+					b.repairs++
+				}
+			}
+		}
+	}
+
+	// Los que esperen a partir de ahora deben esperar al nuevo cierre
+	if done, ok := o.runDone[run.ID]; ok {
+		select {
+		case <-done:
+			delete(o.runDone, run.ID)
+		default:
+		}
+	}
+}
+
+// finishRun cierra la ejecución con su veredicto cuando todas sus tareas
+// han terminado, y libera su worktree. Las ejecuciones que sigue este
+// proceso se resuelven en memoria; las demás (p. ej. las abandonadas por
+// Recover) se consultan en el store.
+func (o *Orchestrator) finishRun(runID string) {
+	if runID == "" {
+		return
+	}
+
+	o.mu.RLock()
+	_, tracked := o.members[runID]
+	open := len(o.pending[runID]) > 0
+	o.mu.RUnlock()
+	if open {
+		return
+	}
+
+	var stored []*types.Task
+	if !tracked {
+		tasks, err := o.runTasks(runID)
+		if err != nil {
+			log.Printf("store: failed to load run %s: %v", runID, err)
+			return
+		}
+		for _, task := range tasks {
+			if !task.State.IsTerminal() {
+				return
+			}
+		}
+		stored = tasks
+	}
+
+	o.mu.Lock()
+	tasks := stored
+	if tracked {
+		// Otra tarea pudo admitirse desde la comprobación anterior
+		if len(o.pending[runID]) > 0 {
+			o.mu.Unlock()
+			return
+		}
+		tasks = o.runMembers(runID)
+	}
+	run := o.loadRun(runID, tasks)
+	if run.CompletedAt != nil {
+		o.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	run.CompletedAt = &now
+	run.State, run.Summary = runVerdict(tasks)
+	if b, ok := o.budgets[runID]; ok {
+		if b.halted != "" {
+			run.State = types.StateNeedsHuman
+			run.Reason = b.halted
+		}
+		delete(o.budgets, runID)
+	}
+	delete(o.members, runID)
+	delete(o.pending, runID)
+	o.persistRun(run)
+	o.closeRunDone(runID)
+	snapshot := *run
+	o.mu.Unlock()
+
+	o.events.Publish(events.Event{Type: events.RunCompleted, RunID: runID, State: snapshot.State, Run: &snapshot})
+
+	o.releaseRunSpace(runID)
+}
+
+// track registra la tarea en su ejecución y la cuenta como pendiente
+// mientras no alcance un estado final (requiere o.mu tomado)
+func (o *Orchestrator) track(task *types.Task) {
+	if task.RunID == "" {
+		return
+	}
+	members, ok := o.members[task.RunID]
+	if !ok {
+		members = make(map[string]*types.Task)
+		o.members[task.RunID] = members
+	}
+	members[task.ID] = task
+
+	pending, ok := o.pending[task.RunID]
+	if task.State.IsTerminal() {
+		delete(pending, task.ID)
+		return
+	}
+	if !ok {
+		pending = make(map[string]bool)
+		o.pending[task.RunID] = pending
+	}
+	pending[task.ID] = true
+}
+
+// runMembers retorna las tareas de una ejecución seguida por este proceso
+// ordenadas por fecha de creación (requiere o.mu tomado)
+func (o *Orchestrator) runMembers(runID string) []*types.Task {
+	tasks := make([]*types.Task, 0, len(o.members[runID]))
+	for _, task := range o.members[runID] {
+		tasks = append(tasks, task)
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+	return tasks
+}
+
+// closeRunDone despierta a quienes esperan la ejecución (requiere o.mu tomado)
+func (o *Orchestrator) closeRunDone(runID string) {
+	done, ok := o.runDone[runID]
+	if !ok {
+		done = make(chan struct{})
+		o.runDone[runID] = done
+	}
+	select {
+	case <-done:
+	default:
+		close(done)
+	}
+}
+
+// loadRun retorna la ejecución desde memoria o el store. Si no existe (datos
+// anteriores a las ejecuciones) se reconstruye a partir de sus tareas.
+// Requiere o.mu tomado.
+func (o *Orchestrator) loadRun(runID string, tasks []*types.Task) *types.Run {
+	if run, ok := o.runs[runID]; ok {
+		return run
+	}
+	run, err := o.store.GetRun(runID)
+	if err != nil {
+		run = &types.Run{ID: runID, State: types.StateRunning}
+		for _, root := range TaskTree(tasks) {
+			run.RootTaskID = root.Task.ID
+			run.Objective = root.Task.Objective
+			run.StartedAt = root.Task.CreatedAt
+			break
+		}
+	}
+	o.runs[runID] = run
+	return run
+}
+
+// runTasks retorna las tareas de una ejecución ordenadas por fecha de
+// creación. Las de una ejecución seguida por este proceso se leen una a una
+// del store; las demás, del índice de la ejecución en el store.
+func (o *Orchestrator) runTasks(runID string) ([]*types.Task, error) {
+	o.mu.RLock()
+	members, tracked := o.members[runID]
+	ids := make([]string, 0, len(members))
+	for id := range members {
+		ids = append(ids, id)
+	}
+	o.mu.RUnlock()
+	if !tracked {
+		return o.store.ListRunTasks(runID)
+	}
+
+	tasks := make([]*types.Task, 0, len(ids))
+	for _, id := range ids {
+		task, err := o.store.GetTask(id)
+		if errors.Is(err, store.ErrNotFound) {
+			continue // hija registrada que aún no se ha guardado
+		}
+		if err != nil {
+			return nil, fmt.Errorf("task %s: %w", id, err)
+		}
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].ID < tasks[j].ID
+		}
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+	return tasks, nil
+}
+
+// persistRun guarda la ejecución en el store (requiere o.mu tomado)
+func (o *Orchestrator) persistRun(run *types.Run) {
+	if err := o.store.SaveRun(run); err != nil {
+		log.Printf("store: failed to save run %s: %v", run.ID, err)
+	}
+}
+
+// runVerdict calcula el veredicto de una ejecución terminada a partir de sus
+// tareas hoja (las que no generaron otras): cancelled o abandoned si alguna lo
+// fue, failed si alguna falló o se omitió y success en otro caso.
+// También retorna el número de tareas por estado.
+func runVerdict(tasks []*types.Task) (types.TaskState, map[types.TaskState]int) {
+	summary := make(map[types.TaskState]int)
+	parents := make(map[string]bool)
+	for _, task := range tasks {
+		summary[task.State]++
+		parents[task.ParentID] = true
+	}
+
+	leaves := make(map[types.TaskState]bool)
+	for _, task := range tasks {
+		if !parents[task.ID] {
+			leaves[task.State] = true
+		}
+	}
+
+	switch {
+	case leaves[types.StateCancelled]:
+		return types.StateCancelled, summary
+	case leaves[types.StateAbandoned]:
+		return types.StateAbandoned, summary
+	case leaves[types.StateFailed], leaves[types.StateSkipped]:
+		return types.StateFailed, summary
+	}
+	return types.StateSuccess, summary
+}
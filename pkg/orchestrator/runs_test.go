@@ -0,0 +1,238 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/pipeline"
+	"github.com/nanochip/multi-agent/pkg/store"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// spyStore cuenta los listados de tareas y permite retrasar la admisión
+// de una tarea
+type spyStore struct {
+	store.Store
+	listTasks int32
+	slowAdmit string // sufijo del ID de la tarea cuya admisión se retrasa
+}
+
+func (s *spyStore) ListTasks() ([]*types.Task, error) {
+	atomic.AddInt32(&s.listTasks, 1)
+	return s.Store.ListTasks()
+}
+
+func (s *spyStore) AppendJournal(entries ...store.JournalEntry) error {
+	for _, entry := range entries {
+		if s.slowAdmit != "" && entry.To == types.StatePending && strings.HasSuffix(entry.TaskID, s.slowAdmit) {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	return s.Store.AppendJournal(entries...)
+}
+
+func TestRunStaysOpenWhileChildrenAreSubmitted(t *testing.T) {
+	spy := &spyStore{}
+	var o *Orchestrator
+	o = testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		switch task.Type {
+		case types.TaskPlan:
+			return plan(task,
+				&types.Task{ID: "parent", Type: types.TaskCode},
+				&types.Task{ID: "sibling", Type: types.TaskAudit},
+			)
+		case types.TaskCode:
+			// El hermano termina mientras se admite la hija del padre ya
+			// terminado
+			return plan(task, &types.Task{ID: "child", Type: types.TaskTest})
+		case types.TaskAudit:
+			parent := strings.TrimSuffix(task.ID, "sibling") + "parent"
+			for {
+				o.mu.RLock()
+				done := o.taskState[parent].State.IsTerminal()
+				o.mu.RUnlock()
+				if done {
+					return succeed(task)
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+		return fail(task, "child failed")
+	}, func(o *Orchestrator) {
+		spy.Store = o.store
+		spy.slowAdmit = ".parent.child"
+		o.store = spy
+		p := testPipeline()
+		for i := range p.Stages {
+			if p.Stages[i].Type == types.TaskCode {
+				p.Stages[i].Transitions = []pipeline.Transition{{On: pipeline.OnSuccess, Spawn: "subtasks"}}
+			}
+		}
+		if err := o.SetPipeline(p); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	root := &types.Task{Type: types.TaskPlan, Objective: "spawn"}
+	result := runObjective(t, o, root)
+	child := root.ID + ".parent.child"
+	if state := taskStates(result)[child]; state != types.StateFailed {
+		t.Errorf("%s: state = %q when the run closed, want failed", child, state)
+	}
+	if result.Run.State != types.StateFailed {
+		t.Errorf("run state = %s, want failed", result.Run.State)
+	}
+}
+
+func TestFinishRunDoesNotListTasks(t *testing.T) {
+	spy := &spyStore{}
+	o := testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		if task.Type == types.TaskPlan {
+			return plan(task, diamond()...)
+		}
+		return succeed(task)
+	}, func(o *Orchestrator) {
+		spy.Store = o.store
+		o.store = spy
+	})
+
+	root := &types.Task{Type: types.TaskPlan, Objective: "diamond"}
+	if err := o.SubmitTask(root); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-o.Done(root.RunID):
+	case <-time.After(10 * time.Second):
+		t.Fatal("run did not finish")
+	}
+	// Completar tareas no debe recorrer el historial del store
+	if n := atomic.LoadInt32(&spy.listTasks); n != 0 {
+		t.Errorf("store.ListTasks called %d times while the run executed, want 0", n)
+	}
+
+	// Consultar la ejecución cerrada tampoco: se usa el índice del store
+	result, err := o.RunResult(root.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, tasks, err := o.GetRun(root.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&spy.listTasks); n != 0 {
+		t.Errorf("store.ListTasks called %d times by GetRun and RunResult, want 0", n)
+	}
+	all, _ := spy.Store.ListTasks()
+	if len(tasks) != len(all) || len(result.Tasks) != len(all) || len(result.Results) != len(all) {
+		t.Errorf("GetRun() = %d tasks and RunResult() = %d tasks with %d results, want all %d", len(tasks), len(result.Tasks), len(result.Results), len(all))
+	}
+	for i := 1; i < len(tasks); i++ {
+		if tasks[i].CreatedAt.Before(tasks[i-1].CreatedAt) {
+			t.Errorf("GetRun() tasks are not ordered by creation: %s before %s", tasks[i-1].ID, tasks[i].ID)
+		}
+	}
+}
+
+func TestWait(t *testing.T) {
+	release := make(chan struct{})
+	o := testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		if task.Objective == "blocked" {
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return fail(task, "interrupted")
+			}
+		}
+		return succeed(task)
+	})
+	released := false
+	t.Cleanup(func() {
+		if !released {
+			close(release)
+		}
+	})
+
+	if _, err := o.Wait(context.Background(), "run-unknown"); err == nil {
+		t.Error("Wait() on an unknown run should fail")
+	}
+
+	root := &types.Task{Type: types.TaskCode, Objective: "blocked"}
+	if err := o.SubmitTask(root); err != nil {
+		t.Fatal(err)
+	}
+
+	// Se retorna al cancelar ctx aunque la ejecución siga abierta
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if result, err := o.Wait(ctx, root.RunID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() with an expired context = %v, %v; want the context error", result, err)
+	}
+
+	// Y al terminar, con el resultado agregado
+	type waited struct {
+		result *types.RunResult
+		err    error
+	}
+	done := make(chan waited, 1)
+	go func() {
+		result, err := o.Wait(context.Background(), root.RunID)
+		done <- waited{result, err}
+	}()
+	select {
+	case w := <-done:
+		t.Fatalf("Wait() = %v, %v before the run finished", w.result, w.err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	released = true
+	close(release)
+
+	select {
+	case w := <-done:
+		if w.err != nil {
+			t.Fatal(w.err)
+		}
+		if w.result.Run.State != types.StateSuccess || w.result.Run.CompletedAt == nil || w.result.Results[root.ID] == nil {
+			t.Errorf("Wait() = run %s with results %v, want the completed run", w.result.Run.State, w.result.Results)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Wait() did not return after the run finished")
+	}
+
+	// Una ejecución ya terminada retorna enseguida
+	if result, err := o.Wait(context.Background(), root.RunID); err != nil || result.Run.State != types.StateSuccess {
+		t.Errorf("Wait() on a finished run = %v, %v; want its result", result, err)
+	}
+}
+
+func TestWaitForRunOfAnotherProcess(t *testing.T) {
+	o := testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		return succeed(task)
+	})
+
+	// La ejecución la cierra otro proceso: solo cambia en el store
+	run := &types.Run{ID: "run-other", State: types.StateRunning, StartedAt: time.Now()}
+	if err := o.store.SaveRun(run); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		finished := *run
+		now := time.Now()
+		finished.State, finished.CompletedAt = types.StateFailed, &now
+		o.store.SaveRun(&finished)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := o.Wait(ctx, run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Run.State != types.StateFailed {
+		t.Errorf("Wait() = run %s, want the state stored by the other process", result.Run.State)
+	}
+}
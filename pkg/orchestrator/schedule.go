@@ -0,0 +1,220 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/cron"
+	"github.com/nanochip/multi-agent/pkg/store"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// scheduleInterval es cada cuánto se revisan los objetivos recurrentes
+const scheduleInterval = 15 * time.Second
+
+// scheduleIDPattern limita los IDs elegidos por el usuario a nombres de archivo seguros
+var scheduleIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ScheduleData son los datos disponibles en la plantilla del objetivo
+type ScheduleData struct {
+	Schedule string    // ID del objetivo recurrente
+	Time     time.Time // vencimiento que se atiende
+	Date     string    // Time como 2006-01-02
+}
+
+// EnableScheduler activa el envío de los objetivos recurrentes guardados en
+// el store. Solo un proceso por repositorio debería tenerlo activo (el
+// daemon). Debe llamarse antes de Start.
+func (o *Orchestrator) EnableScheduler() {
+	o.scheduling = true
+}
+
+// AddSchedule valida y guarda un objetivo recurrente. Si no tiene ID se le
+// asigna uno. Un daemon con el scheduler activo lo recoge en su siguiente
+// revisión.
+func (o *Orchestrator) AddSchedule(schedule *types.Schedule) error {
+	if schedule.ID == "" {
+		schedule.ID = newID("schedule")
+	}
+	if !scheduleIDPattern.MatchString(schedule.ID) {
+		return fmt.Errorf("invalid schedule id %q (use letters, digits, '.', '_' and '-')", schedule.ID)
+	}
+	if _, err := o.store.GetSchedule(schedule.ID); err == nil {
+		return fmt.Errorf("schedule %s already exists", schedule.ID)
+	}
+
+	spec, err := cron.Parse(schedule.Cron)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if spec.Next(now).IsZero() {
+		return fmt.Errorf("cron expression %q never fires", schedule.Cron)
+	}
+	if schedule.Priority.Rank() < 0 {
+		return fmt.Errorf("unknown priority %q", schedule.Priority)
+	}
+	if _, err := renderObjective(schedule, now); err != nil {
+		return err
+	}
+
+	schedule.CreatedAt = now
+	schedule.LastFire = nil
+	schedule.LastRunID = ""
+	schedule.Skipped = 0
+	if err := o.store.SaveSchedule(schedule); err != nil {
+		return fmt.Errorf("failed to save schedule %s: %w", schedule.ID, err)
+	}
+	return nil
+}
+
+// ListSchedules retorna los objetivos recurrentes
+func (o *Orchestrator) ListSchedules() ([]*types.Schedule, error) {
+	return o.store.ListSchedules()
+}
+
+// RemoveSchedule elimina un objetivo recurrente. Las ejecuciones que ya
+// envió siguen su curso.
+func (o *Orchestrator) RemoveSchedule(id string) error {
+	if err := o.store.DeleteSchedule(id); err != nil {
+		return fmt.Errorf("schedule %s: %w", id, err)
+	}
+	return nil
+}
+
+// NextFire retorna el próximo vencimiento de un objetivo recurrente
+func NextFire(schedule *types.Schedule) (time.Time, error) {
+	spec, err := cron.Parse(schedule.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return spec.Next(lastFire(schedule)), nil
+}
+
+// lastFire retorna desde cuándo se buscan vencimientos
+func lastFire(schedule *types.Schedule) time.Time {
+	if schedule.LastFire != nil {
+		return schedule.LastFire.Local()
+	}
+	return schedule.CreatedAt.Local()
+}
+
+// runSchedules atiende los objetivos recurrentes hasta que el orchestrator se detiene
+func (o *Orchestrator) runSchedules() {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+	for {
+		o.fireSchedules(time.Now())
+
+		select {
+		case <-ticker.C:
+		case <-o.ctx.Done():
+			return
+		}
+	}
+}
+
+// fireSchedules envía los objetivos recurrentes vencidos. Los vencimientos
+// perdidos (p. ej. con el daemon parado) se atienden una sola vez, y se
+// omiten mientras siga en curso la ejecución anterior del mismo objetivo.
+func (o *Orchestrator) fireSchedules(now time.Time) {
+	schedules, err := o.store.ListSchedules()
+	if err != nil {
+		log.Printf("store: failed to list schedules: %v", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		spec, err := cron.Parse(schedule.Cron)
+		if err != nil {
+			log.Printf("schedule %s: %v", schedule.ID, err)
+			continue
+		}
+		due := spec.Next(lastFire(schedule))
+		if due.IsZero() || due.After(now) {
+			continue
+		}
+		for next := spec.Next(due); !next.IsZero() && !next.After(now); next = spec.Next(due) {
+			due = next
+		}
+
+		schedule.LastFire = &due
+		if o.scheduleRunning(schedule) {
+			schedule.Skipped++
+			log.Printf("schedule %s: skipping %s, run %s is still running",
+				schedule.ID, due.Format(time.RFC3339), schedule.LastRunID)
+		} else if task, err := o.fireSchedule(schedule, due); err != nil {
+			log.Printf("schedule %s: %v", schedule.ID, err)
+		} else {
+			schedule.LastRunID = task.RunID
+		}
+
+		// No resucitar un objetivo eliminado mientras se enviaba
+		if _, err := o.store.GetSchedule(schedule.ID); errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err := o.store.SaveSchedule(schedule); err != nil {
+			log.Printf("store: failed to save schedule %s: %v", schedule.ID, err)
+		}
+	}
+}
+
+// scheduleRunning indica si sigue en curso la última ejecución del objetivo
+func (o *Orchestrator) scheduleRunning(schedule *types.Schedule) bool {
+	if schedule.LastRunID == "" {
+		return false
+	}
+	run, err := o.store.GetRun(schedule.LastRunID)
+	if err != nil {
+		return false
+	}
+	return run.CompletedAt == nil
+}
+
+// fireSchedule envía la tarea plan de un vencimiento
+func (o *Orchestrator) fireSchedule(schedule *types.Schedule, due time.Time) (*types.Task, error) {
+	objective, err := renderObjective(schedule, due)
+	if err != nil {
+		return nil, err
+	}
+
+	task := &types.Task{
+		Type:        types.TaskPlan,
+		Objective:   objective,
+		Inputs:      map[string]interface{}{"schedule": schedule.ID},
+		Constraints: make(map[string]interface{}, len(schedule.Constraints)),
+		MaxRetries:  3,
+		Priority:    schedule.Priority,
+	}
+	for k, v := range schedule.Constraints {
+		task.Constraints[k] = v
+	}
+	if err := o.SubmitTask(task); err != nil {
+		return nil, fmt.Errorf("failed to submit: %w", err)
+	}
+	log.Printf("schedule %s: submitted run %s: %s", schedule.ID, task.RunID, objective)
+	return task, nil
+}
+
+// renderObjective aplica la plantilla del objetivo a un vencimiento
+func renderObjective(schedule *types.Schedule, due time.Time) (string, error) {
+	tmpl, err := template.New(schedule.ID).Option("missingkey=error").Parse(schedule.Objective)
+	if err != nil {
+		return "", fmt.Errorf("invalid objective template: %w", err)
+	}
+	var out strings.Builder
+	data := ScheduleData{Schedule: schedule.ID, Time: due, Date: due.Format("2006-01-02")}
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("invalid objective template: %w", err)
+	}
+	objective := strings.TrimSpace(out.String())
+	if objective == "" {
+		return "", fmt.Errorf("objective template renders an empty objective")
+	}
+	return objective, nil
+}
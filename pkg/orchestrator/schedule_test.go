@@ -0,0 +1,159 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/store"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// saveSchedule guarda un objetivo recurrente creado en created
+func saveSchedule(t *testing.T, o *Orchestrator, id, expr string, created time.Time) {
+	t.Helper()
+	schedule := &types.Schedule{ID: id, Cron: expr, Objective: "audit {{.Date}} {{.Time.Hour}}h", CreatedAt: created}
+	if err := o.store.SaveSchedule(schedule); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// scheduleOf retorna el objetivo recurrente guardado
+func scheduleOf(t *testing.T, o *Orchestrator, id string) *types.Schedule {
+	t.Helper()
+	schedule, err := o.store.GetSchedule(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schedule
+}
+
+// scheduledRuns retorna las ejecuciones enviadas por los objetivos recurrentes
+func scheduledRuns(t *testing.T, o *Orchestrator) []*types.Run {
+	t.Helper()
+	runs, err := o.ListRuns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runs
+}
+
+func TestFireSchedulesCatchesUpOnce(t *testing.T) {
+	o := testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		return succeed(task)
+	})
+	local := func(hour, min int) time.Time {
+		return time.Date(2026, 1, 1, hour, min, 0, 0, time.Local)
+	}
+	saveSchedule(t, o, "hourly", "0 * * * *", local(7, 10))
+
+	// Antes del primer vencimiento no se envía nada
+	o.fireSchedules(local(7, 59))
+	if runs := scheduledRuns(t, o); len(runs) != 0 {
+		t.Fatalf("fireSchedules() before the first fire submitted %d runs", len(runs))
+	}
+
+	// Cinco vencimientos perdidos (08:00 a 12:00) se atienden una sola vez,
+	// con el más reciente
+	o.fireSchedules(local(12, 30))
+	runs := scheduledRuns(t, o)
+	if len(runs) != 1 {
+		t.Fatalf("fireSchedules() after downtime submitted %d runs, want 1", len(runs))
+	}
+	if want := "audit 2026-01-01 12h"; runs[0].Objective != want {
+		t.Errorf("objective = %q, want %q", runs[0].Objective, want)
+	}
+	schedule := scheduleOf(t, o, "hourly")
+	if schedule.LastFire == nil || !schedule.LastFire.Equal(local(12, 0)) || schedule.LastRunID != runs[0].ID || schedule.Skipped != 0 {
+		t.Errorf("schedule = last fire %v, run %s, skipped %d; want 12:00, %s and none", schedule.LastFire, schedule.LastRunID, schedule.Skipped, runs[0].ID)
+	}
+
+	// El mismo vencimiento no se vuelve a enviar
+	o.fireSchedules(local(12, 45))
+	if runs := scheduledRuns(t, o); len(runs) != 1 {
+		t.Errorf("fireSchedules() within the same hour submitted %d runs, want 1", len(runs))
+	}
+}
+
+func TestFireSchedulesSkipsWhileRunning(t *testing.T) {
+	release := make(chan struct{})
+	o := testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return succeed(task)
+	})
+	released := false
+	t.Cleanup(func() {
+		if !released {
+			close(release)
+		}
+	})
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.Local)
+	saveSchedule(t, o, "hourly", "0 * * * *", start.Add(-time.Minute))
+
+	o.fireSchedules(start)
+	first := scheduleOf(t, o, "hourly").LastRunID
+	if first == "" {
+		t.Fatal("first fire did not submit a run")
+	}
+
+	// Mientras la ejecución sigue en curso los vencimientos se omiten y se
+	// cuentan, pero avanzan
+	o.fireSchedules(start.Add(time.Hour))
+	o.fireSchedules(start.Add(2 * time.Hour))
+	schedule := scheduleOf(t, o, "hourly")
+	if schedule.Skipped != 2 || schedule.LastRunID != first || !schedule.LastFire.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("schedule = skipped %d, run %s, last fire %v; want 2 skipped on %s at 11:00", schedule.Skipped, schedule.LastRunID, schedule.LastFire, first)
+	}
+	if runs := scheduledRuns(t, o); len(runs) != 1 {
+		t.Fatalf("fireSchedules() while running submitted %d runs, want 1", len(runs))
+	}
+
+	released = true
+	close(release)
+	select {
+	case <-o.Done(first):
+	case <-time.After(10 * time.Second):
+		t.Fatal("run did not finish")
+	}
+
+	// Terminada la anterior, el siguiente vencimiento se envía
+	o.fireSchedules(start.Add(3 * time.Hour))
+	schedule = scheduleOf(t, o, "hourly")
+	if schedule.LastRunID == first || schedule.Skipped != 2 {
+		t.Errorf("schedule = run %s, skipped %d; want a new run and the 2 skipped kept", schedule.LastRunID, schedule.Skipped)
+	}
+	if runs := scheduledRuns(t, o); len(runs) != 2 {
+		t.Errorf("fireSchedules() after the run finished left %d runs, want 2", len(runs))
+	}
+}
+
+// removingStore elimina el objetivo recurrente al guardar una tarea suya,
+// como si se borrara mientras se envía su vencimiento
+type removingStore struct {
+	store.Store
+}
+
+func (s *removingStore) SaveTask(task *types.Task) error {
+	if id, ok := task.Inputs["schedule"].(string); ok {
+		s.DeleteSchedule(id)
+	}
+	return s.Store.SaveTask(task)
+}
+
+func TestFireSchedulesDoesNotResurrectRemoved(t *testing.T) {
+	o := testOrchestrator(t, func(ctx context.Context, task *types.Task) *types.TaskResult {
+		return succeed(task)
+	}, func(o *Orchestrator) {
+		o.store = &removingStore{Store: o.store}
+	})
+	now := time.Date(2026, 1, 1, 9, 30, 0, 0, time.Local)
+	saveSchedule(t, o, "once", "0 9 * * *", now.Add(-time.Hour))
+
+	o.fireSchedules(now)
+	if _, err := o.store.GetSchedule("once"); err == nil {
+		t.Error("schedule removed while firing was saved again")
+	}
+}
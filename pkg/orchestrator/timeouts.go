@@ -0,0 +1,97 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// ConstraintMaxExecutionTime es la restricción de tarea que limita su tiempo
+// de ejecución. Acepta segundos (300) o segundos por tipo de tarea
+// ({"test": 600, "default": 300}).
+const ConstraintMaxExecutionTime = "max_execution_time_seconds"
+
+// DefaultTaskTimeout es el tiempo máximo de una tarea sin otra configuración
+const DefaultTaskTimeout = 300 * time.Second
+
+// SetDefaultTimeout configura el tiempo máximo de las tareas que no tienen
+// uno propio (0 = sin límite). Debe llamarse antes de Start.
+func (o *Orchestrator) SetDefaultTimeout(d time.Duration) {
+	o.timeout = d
+}
+
+// SetTimeout configura el tiempo máximo de un tipo de tarea.
+// Debe llamarse antes de Start.
+func (o *Orchestrator) SetTimeout(taskType types.TaskType, d time.Duration) {
+	o.timeouts[taskType] = d
+}
+
+// timeoutFor retorna el tiempo máximo de la tarea: el de sus restricciones,
+// el de su tipo o el por defecto
+func (o *Orchestrator) timeoutFor(task *types.Task) time.Duration {
+	if value, ok := task.Constraints[ConstraintMaxExecutionTime]; ok {
+		if perType, ok := value.(map[string]interface{}); ok {
+			value, ok = perType[string(task.Type)]
+			if !ok {
+				value = perType["default"]
+			}
+		}
+		if d, err := constraintSeconds(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	if d, ok := o.timeouts[task.Type]; ok {
+		return d
+	}
+	return o.timeout
+}
+
+// constraintSeconds interpreta un valor de restricción en segundos. Las
+// restricciones llegan como int desde Go, float64 desde JSON o texto.
+func constraintSeconds(value interface{}) (time.Duration, error) {
+	var seconds float64
+	switch v := value.(type) {
+	case int:
+		seconds = float64(v)
+	case int64:
+		seconds = float64(v)
+	case float64:
+		seconds = v
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid seconds %q", v)
+		}
+		seconds = f
+	default:
+		return 0, fmt.Errorf("invalid seconds %v", value)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// inheritConstraints copia a la tarea hija las restricciones del padre que
+// ella no define
+func inheritConstraints(child, parent *types.Task) {
+	if len(parent.Constraints) == 0 {
+		return
+	}
+	if child.Constraints == nil {
+		child.Constraints = make(map[string]interface{}, len(parent.Constraints))
+	}
+	for key, value := range parent.Constraints {
+		if _, ok := child.Constraints[key]; !ok {
+			child.Constraints[key] = value
+		}
+	}
+}
+
+// timedOutResult marca como fallido por timeout el resultado de una tarea
+// que agotó su tiempo, conservando la evidencia reunida hasta entonces
+func timedOutResult(result *types.TaskResult, timeout time.Duration) *types.TaskResult {
+	result.Success = false
+	result.State = types.StateFailed
+	result.Error = fmt.Sprintf("timeout: task exceeded its execution time of %v", timeout)
+	return result
+}
@@ -0,0 +1,85 @@
+package orchestrator
+
+import (
+	"log"
+
+	"github.com/nanochip/multi-agent/pkg/agents"
+	"github.com/nanochip/multi-agent/pkg/types"
+	"github.com/nanochip/multi-agent/pkg/workspace"
+)
+
+// runSpace agrupa el workspace de una ejecución y los agentes que operan sobre él
+type runSpace struct {
+	workspace *workspace.Manager
+	agents    map[string]agents.Agent
+}
+
+// SetIsolation activa o desactiva el uso de un git worktree aislado por ejecución.
+// Debe llamarse antes de Start.
+func (o *Orchestrator) SetIsolation(enabled bool) {
+	o.isolate = enabled
+}
+
+// newAgents crea el conjunto de agentes disponibles sobre un workspace
+func (o *Orchestrator) newAgents(ws *workspace.Manager) map[string]agents.Agent {
+	return map[string]agents.Agent{
+		"planner":   agents.NewPlanner(ws, o.policy),
+		"coder":     agents.NewCoder(ws, o.policy),
+		"tester":    agents.NewTester(ws, o.policy),
+		"auditor":   agents.NewAuditor(ws, o.policy),
+		"repairer":  agents.NewRepairer(ws, o.policy),
+		"optimizer": agents.NewOptimizer(ws, o.policy),
+		"release":   agents.NewRelease(ws, o.policy),
+		"releaser":  agents.NewReleaser(ws, o.policy),
+	}
+}
+
+// spaceFor retorna el workspace de la ejecución de la tarea, creando su
+// worktree aislado la primera vez. Si no puede crearse se usa el compartido.
+func (o *Orchestrator) spaceFor(task *types.Task) *runSpace {
+	shared := &runSpace{workspace: o.workspace, agents: o.agents}
+	if !o.isolate || task.RunID == "" {
+		return shared
+	}
+
+	o.spacesMu.Lock()
+	defer o.spacesMu.Unlock()
+
+	if space, ok := o.spaces[task.RunID]; ok {
+		return space
+	}
+
+	space := shared
+	ws, err := o.workspace.NewWorktree(task.RunID)
+	if err != nil {
+		log.Printf("workspace: using shared worktree for run %s: %v", task.RunID, err)
+	} else {
+		space = &runSpace{workspace: ws, agents: o.newAgents(ws)}
+	}
+	o.spaces[task.RunID] = space
+	return space
+}
+
+// releaseRunSpace elimina el worktree de una ejecución terminada
+func (o *Orchestrator) releaseRunSpace(runID string) {
+	o.spacesMu.Lock()
+	space, ok := o.spaces[runID]
+	delete(o.spaces, runID)
+	o.spacesMu.Unlock()
+
+	if !ok {
+		// Worktree de otro proceso (p. ej. una ejecución abandonada)
+		if o.isolate {
+			if err := o.workspace.RemoveWorktree(runID); err != nil {
+				log.Printf("workspace: failed to remove worktree for run %s: %v", runID, err)
+			}
+		}
+		return
+	}
+	if !space.workspace.IsWorktree() {
+		return
+	}
+	if err := space.workspace.Remove(); err != nil {
+		log.Printf("workspace: failed to remove worktree for run %s: %v", runID, err)
+	}
+}
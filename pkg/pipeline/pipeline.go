@@ -0,0 +1,421 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Condiciones soportadas en las transiciones
+const (
+	OnSuccess = "success" // la tarea terminó con éxito
+	OnFailure = "failure" // la tarea falló
+	OnFinding = "finding" // el resultado contiene hallazgos que cumplen los filtros
+	OnAlways  = "always"  // siempre
+)
+
+// Pipeline define las etapas de un flujo y las transiciones entre ellas
+type Pipeline struct {
+	Name   string  `yaml:"name" json:"name"`
+	Stages []Stage `yaml:"stages" json:"stages"`
+}
+
+// Stage representa una etapa del pipeline
+type Stage struct {
+	ID          string         `yaml:"id" json:"id"`
+	Type        types.TaskType `yaml:"type" json:"type"`
+	Agent       string         `yaml:"agent,omitempty" json:"agent,omitempty"`             // por defecto, el agente del tipo de tarea
+	MaxRetries  *int           `yaml:"max_retries,omitempty" json:"max_retries,omitempty"` // sobrescribe el valor de la tarea
+	Objective   string         `yaml:"objective,omitempty" json:"objective,omitempty"`
+	Retry       *Retry         `yaml:"retry,omitempty" json:"retry,omitempty"` // sobrescribe la política del tipo de tarea
+	Transitions []Transition   `yaml:"transitions,omitempty" json:"transitions,omitempty"`
+}
+
+// Retry configura la política de reintentos de una etapa; los campos
+// ausentes conservan la del tipo de tarea y un 0 explícito también cuenta.
+// Las esperas usan el formato de time.ParseDuration ("500ms", "2s").
+type Retry struct {
+	InitialDelay string   `yaml:"initial_delay,omitempty" json:"initial_delay,omitempty"`
+	MaxDelay     string   `yaml:"max_delay,omitempty" json:"max_delay,omitempty"`
+	Multiplier   *float64 `yaml:"multiplier,omitempty" json:"multiplier,omitempty"`
+	Jitter       *float64 `yaml:"jitter,omitempty" json:"jitter,omitempty"`
+	RetryOn      []string `yaml:"retry_on,omitempty" json:"retry_on,omitempty"`       // categorías reintentables
+	NeverRetry   []string `yaml:"never_retry,omitempty" json:"never_retry,omitempty"` // categorías que no se reintentan
+}
+
+// Transition define qué hacer cuando una etapa termina.
+// Las transiciones se evalúan en orden y se aplica la primera que coincide.
+type Transition struct {
+	On          string         `yaml:"on" json:"on"`
+	Categories  []string       `yaml:"categories,omitempty" json:"categories,omitempty"`     // filtro para "finding"
+	MinSeverity types.Severity `yaml:"min_severity,omitempty" json:"min_severity,omitempty"` // filtro para "finding"
+	Next        string         `yaml:"next,omitempty" json:"next,omitempty"`                 // etapa siguiente
+	Spawn       string         `yaml:"spawn,omitempty" json:"spawn,omitempty"`               // output con subtareas a enviar
+	Objective   string         `yaml:"objective,omitempty" json:"objective,omitempty"`
+	ResultInput string         `yaml:"result_input,omitempty" json:"result_input,omitempty"` // input donde pasar el resultado
+}
+
+// DefaultAgents mapea cada tipo de tarea a su agente por defecto
+var DefaultAgents = map[types.TaskType]string{
+	types.TaskPlan:     "planner",
+	types.TaskCode:     "coder",
+	types.TaskTest:     "tester",
+	types.TaskAudit:    "auditor",
+	types.TaskRepair:   "repairer",
+	types.TaskOptimize: "optimizer",
+	types.TaskRelease:  "release",
+}
+
+// Default retorna el pipeline estándar:
+// plan → code → test → (repair ↔ test) → audit → (repair | optimize) → test
+func Default() *Pipeline {
+	return &Pipeline{
+		Name: "default",
+		Stages: []Stage{
+			{
+				ID:   "plan",
+				Type: types.TaskPlan,
+				Transitions: []Transition{
+					{On: OnSuccess, Spawn: "subtasks"},
+				},
+			},
+			{
+				ID:   "code",
+				Type: types.TaskCode,
+				Transitions: []Transition{
+					{On: OnSuccess, Next: "test", Objective: "test code changes"},
+				},
+			},
+			{
+				ID:   "test",
+				Type: types.TaskTest,
+				Transitions: []Transition{
+					{On: OnFailure, Next: "repair", Objective: "repair failing tests", ResultInput: "test_result"},
+					{On: OnSuccess, Next: "audit", Objective: "audit code changes"},
+				},
+			},
+			{
+				ID:   "repair",
+				Type: types.TaskRepair,
+				Transitions: []Transition{
+					{On: OnSuccess, Next: "test", Objective: "verify repair"},
+				},
+			},
+			{
+				ID:   "audit",
+				Type: types.TaskAudit,
+				Transitions: []Transition{
+					{
+						On:          OnFinding,
+						Categories:  []string{"security", "secret"},
+						MinSeverity: types.SeverityHigh,
+						Next:        "repair",
+						Objective:   "repair audit findings",
+						ResultInput: "audit_result",
+					},
+					{On: OnSuccess, Next: "optimize", Objective: "optimize code"},
+				},
+			},
+			{
+				ID:   "optimize",
+				Type: types.TaskOptimize,
+				Transitions: []Transition{
+					{On: OnSuccess, Next: "test", Objective: "verify optimization didn't break tests"},
+				},
+			},
+		},
+	}
+}
+
+// LoadFile carga un pipeline desde un archivo YAML o JSON (según la
+// extensión). Los campos desconocidos son un error.
+func LoadFile(path string) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline: %w", err)
+	}
+
+	p := &Pipeline{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(p)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(p)
+	}
+	// Un archivo vacío es un pipeline sin etapas, que Validate rechaza
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse pipeline %s: %w", path, err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid pipeline %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate verifica que el pipeline sea consistente
+func (p *Pipeline) Validate() error {
+	if len(p.Stages) == 0 {
+		return fmt.Errorf("pipeline has no stages")
+	}
+
+	ids := make(map[string]bool)
+	for _, stage := range p.Stages {
+		if stage.ID == "" {
+			return fmt.Errorf("stage with empty id")
+		}
+		if ids[stage.ID] {
+			return fmt.Errorf("duplicate stage %q", stage.ID)
+		}
+		ids[stage.ID] = true
+
+		if _, ok := DefaultAgents[stage.Type]; !ok {
+			return fmt.Errorf("stage %q: unknown task type %q", stage.ID, stage.Type)
+		}
+		if stage.MaxRetries != nil && *stage.MaxRetries < 0 {
+			return fmt.Errorf("stage %q: max_retries must be >= 0", stage.ID)
+		}
+		if r := stage.Retry; r != nil {
+			for name, value := range map[string]string{"initial_delay": r.InitialDelay, "max_delay": r.MaxDelay} {
+				if value == "" {
+					continue
+				}
+				if d, err := time.ParseDuration(value); err != nil || d < 0 {
+					return fmt.Errorf("stage %q: invalid retry %s %q", stage.ID, name, value)
+				}
+			}
+			if r.Multiplier != nil && *r.Multiplier < 0 {
+				return fmt.Errorf("stage %q: retry multiplier must be >= 0", stage.ID)
+			}
+			if r.Jitter != nil && (*r.Jitter < 0 || *r.Jitter > 1) {
+				return fmt.Errorf("stage %q: retry jitter must be between 0 and 1", stage.ID)
+			}
+		}
+	}
+
+	for _, stage := range p.Stages {
+		for i, t := range stage.Transitions {
+			switch t.On {
+			case OnSuccess, OnFailure, OnFinding, OnAlways:
+			default:
+				return fmt.Errorf("stage %q transition %d: unknown condition %q", stage.ID, i, t.On)
+			}
+			if (t.Next == "") == (t.Spawn == "") {
+				return fmt.Errorf("stage %q transition %d: exactly one of next or spawn is required", stage.ID, i)
+			}
+			if t.Next != "" && !ids[t.Next] {
+				return fmt.Errorf("stage %q transition %d: unknown next stage %q", stage.ID, i, t.Next)
+			}
+			if t.MinSeverity != "" && severityRank(t.MinSeverity) < 0 {
+				return fmt.Errorf("stage %q transition %d: unknown severity %q", stage.ID, i, t.MinSeverity)
+			}
+		}
+	}
+	return p.checkAlwaysCycles()
+}
+
+// checkAlwaysCycles rechaza los ciclos formados solo por transiciones
+// "always": ningún resultado puede cortarlos. Los ciclos con condiciones
+// (p. ej. test ↔ repair) son bucles de realimentación válidos.
+func (p *Pipeline) checkAlwaysCycles() error {
+	always := make(map[string]string)
+	for _, stage := range p.Stages {
+		for _, t := range stage.Transitions {
+			if t.On == OnAlways && t.Next != "" {
+				always[stage.ID] = t.Next
+			}
+			// Solo se aplica la primera transición que coincide
+			if t.On == OnAlways {
+				break
+			}
+		}
+	}
+	for _, stage := range p.Stages {
+		path := []string{stage.ID}
+		for id, ok := always[stage.ID]; ok; id, ok = always[id] {
+			path = append(path, id)
+			if id == stage.ID {
+				return fmt.Errorf("stages %s loop on always transitions", strings.Join(path, " → "))
+			}
+			if len(path) > len(p.Stages) {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Stage retorna la etapa con el ID indicado
+func (p *Pipeline) Stage(id string) *Stage {
+	for i := range p.Stages {
+		if p.Stages[i].ID == id {
+			return &p.Stages[i]
+		}
+	}
+	return nil
+}
+
+// StageFor retorna la etapa de una tarea: la indicada en task.Stage o,
+// si no hay, la primera etapa de su mismo tipo
+func (p *Pipeline) StageFor(task *types.Task) *Stage {
+	if task.Stage != "" {
+		return p.Stage(task.Stage)
+	}
+	for i := range p.Stages {
+		if p.Stages[i].Type == task.Type {
+			return &p.Stages[i]
+		}
+	}
+	return nil
+}
+
+// AgentFor retorna el nombre del agente que debe ejecutar la tarea
+func (p *Pipeline) AgentFor(task *types.Task) string {
+	if stage := p.StageFor(task); stage != nil && stage.Agent != "" {
+		return stage.Agent
+	}
+	return DefaultAgents[task.Type]
+}
+
+// Apply asigna la etapa a la tarea y aplica su configuración
+func (p *Pipeline) Apply(task *types.Task) {
+	stage := p.StageFor(task)
+	if stage == nil {
+		return
+	}
+	task.Stage = stage.ID
+	if stage.MaxRetries != nil {
+		task.MaxRetries = *stage.MaxRetries
+	}
+}
+
+// NextTasks determina las siguientes tareas según la primera transición que coincide
+func (p *Pipeline) NextTasks(task *types.Task, result *types.TaskResult) []*types.Task {
+	stage := p.StageFor(task)
+	if stage == nil {
+		return nil
+	}
+
+	for _, t := range stage.Transitions {
+		if !t.matches(result) {
+			continue
+		}
+
+		if t.Spawn != "" {
+			subtasks, _ := result.Outputs[t.Spawn].([]*types.Task)
+			return subtasks
+		}
+
+		next := p.Stage(t.Next)
+		objective := t.Objective
+		if objective == "" {
+			objective = next.Objective
+		}
+		if objective == "" {
+			objective = next.ID
+		}
+
+		inputs := map[string]interface{}{"task_id": task.ID}
+		if t.ResultInput != "" {
+			inputs = map[string]interface{}{t.ResultInput: result}
+		}
+
+		return []*types.Task{{
+			Type:      next.Type,
+			Stage:     next.ID,
+			Objective: objective,
+			Inputs:    inputs,
+			ParentID:  task.ID,
+		}}
+	}
+	return nil
+}
+
+// String describe la transición, p. ej. "on failure: repair"
+func (t Transition) String() string {
+	condition := t.On
+	if t.On == OnFinding {
+		if len(t.Categories) > 0 {
+			condition += " " + strings.Join(t.Categories, ",")
+		}
+		if t.MinSeverity != "" {
+			condition += " >= " + string(t.MinSeverity)
+		}
+	}
+	if t.Spawn != "" {
+		return fmt.Sprintf("on %s: spawn %s", condition, t.Spawn)
+	}
+	return fmt.Sprintf("on %s: %s", condition, t.Next)
+}
+
+// matches evalúa la condición de la transición sobre un resultado
+func (t Transition) matches(result *types.TaskResult) bool {
+	switch t.On {
+	case OnSuccess:
+		return result.Success
+	case OnFailure:
+		return !result.Success
+	case OnAlways:
+		return true
+	case OnFinding:
+		for _, finding := range findings(result) {
+			if t.matchesFinding(finding) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesFinding verifica si un hallazgo cumple los filtros de la transición
+func (t Transition) matchesFinding(finding types.AuditFinding) bool {
+	if t.MinSeverity != "" && severityRank(finding.Severity) < severityRank(t.MinSeverity) {
+		return false
+	}
+	if len(t.Categories) == 0 {
+		return true
+	}
+	for _, category := range t.Categories {
+		if category == finding.Category {
+			return true
+		}
+	}
+	return false
+}
+
+// findings extrae los hallazgos de auditoría de un resultado
+func findings(result *types.TaskResult) []types.AuditFinding {
+	if all, ok := result.Outputs["findings"].([]types.AuditFinding); ok {
+		return all
+	}
+	critical, _ := result.Outputs["critical_findings"].([]types.AuditFinding)
+	return critical
+}
+
+// severityRank ordena severidades (-1 si es desconocida)
+func severityRank(s types.Severity) int {
+	switch s {
+	case types.SeverityInfo:
+		return 0
+	case types.SeverityLow:
+		return 1
+	case types.SeverityMedium:
+		return 2
+	case types.SeverityHigh:
+		return 3
+	case types.SeverityCritical:
+		return 4
+	}
+	return -1
+}
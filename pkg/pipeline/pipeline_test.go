@@ -0,0 +1,202 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// withDefault retorna el pipeline por defecto tras modificarlo con fn
+func withDefault(fn func(p *Pipeline)) *Pipeline {
+	p := Default()
+	fn(p)
+	return p
+}
+
+func TestValidate(t *testing.T) {
+	negative, above, below := -1, 1.5, -0.5
+	tests := []struct {
+		name     string
+		pipeline *Pipeline
+		want     string // "" si es válido
+	}{
+		{"default", Default(), ""},
+		{"feedback loop", &Pipeline{Stages: []Stage{
+			{ID: "test", Type: types.TaskTest, Transitions: []Transition{{On: OnFailure, Next: "repair"}}},
+			{ID: "repair", Type: types.TaskRepair, Transitions: []Transition{{On: OnAlways, Next: "test"}}},
+		}}, ""},
+		{"no stages", &Pipeline{Name: "empty"}, "pipeline has no stages"},
+		{"empty id", &Pipeline{Stages: []Stage{{Type: types.TaskCode}}}, "stage with empty id"},
+		{"duplicate stage", &Pipeline{Stages: []Stage{{ID: "a", Type: types.TaskCode}, {ID: "a", Type: types.TaskTest}}}, `duplicate stage "a"`},
+		{"unknown task type", &Pipeline{Stages: []Stage{{ID: "a", Type: "deploy"}}}, `stage "a": unknown task type "deploy"`},
+		{"negative max_retries", withDefault(func(p *Pipeline) { p.Stages[1].MaxRetries = &negative }), `stage "code": max_retries must be >= 0`},
+		{"invalid retry delay", withDefault(func(p *Pipeline) { p.Stages[2].Retry = &Retry{InitialDelay: "soon"} }), `stage "test": invalid retry initial_delay "soon"`},
+		{"negative retry delay", withDefault(func(p *Pipeline) { p.Stages[2].Retry = &Retry{MaxDelay: "-1s"} }), `invalid retry max_delay "-1s"`},
+		{"jitter above 1", withDefault(func(p *Pipeline) { p.Stages[2].Retry = &Retry{Jitter: &above} }), "retry jitter must be between 0 and 1"},
+		{"negative multiplier", withDefault(func(p *Pipeline) { p.Stages[2].Retry = &Retry{Multiplier: &below} }), `stage "test": retry multiplier must be >= 0`},
+		{"unknown stage", withDefault(func(p *Pipeline) { p.Stages[1].Transitions[0].Next = "tests" }), `stage "code" transition 0: unknown next stage "tests"`},
+		{"unknown condition", withDefault(func(p *Pipeline) { p.Stages[1].Transitions[0].On = "done" }), `stage "code" transition 0: unknown condition "done"`},
+		{"next and spawn", withDefault(func(p *Pipeline) { p.Stages[1].Transitions[0].Spawn = "subtasks" }), "exactly one of next or spawn is required"},
+		{"neither next nor spawn", withDefault(func(p *Pipeline) { p.Stages[1].Transitions[0].Next = "" }), "exactly one of next or spawn is required"},
+		{"unknown severity", withDefault(func(p *Pipeline) { p.Stages[4].Transitions[0].MinSeverity = "severe" }), `stage "audit" transition 0: unknown severity "severe"`},
+		{"always self loop", &Pipeline{Stages: []Stage{
+			{ID: "optimize", Type: types.TaskOptimize, Transitions: []Transition{{On: OnAlways, Next: "optimize"}}},
+		}}, "stages optimize → optimize loop on always transitions"},
+		{"always cycle", &Pipeline{Stages: []Stage{
+			{ID: "plan", Type: types.TaskPlan, Transitions: []Transition{{On: OnSuccess, Next: "code"}}},
+			{ID: "code", Type: types.TaskCode, Transitions: []Transition{{On: OnAlways, Next: "test"}}},
+			{ID: "test", Type: types.TaskTest, Transitions: []Transition{{On: OnAlways, Next: "code"}}},
+		}}, "stages code → test → code loop on always transitions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.pipeline.Validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Validate() = %v, want nil", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("Validate() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextTasks(t *testing.T) {
+	p := Default()
+	subtasks := []*types.Task{{ID: "a", Type: types.TaskCode}, {ID: "b", Type: types.TaskTest}}
+	secret := types.AuditFinding{Category: "secret", Severity: types.SeverityCritical}
+	style := types.AuditFinding{Category: "style", Severity: types.SeverityHigh}
+
+	tests := []struct {
+		name      string
+		task      *types.Task
+		result    *types.TaskResult
+		stage     string // etapa de la tarea siguiente ("" = ninguna)
+		objective string
+		input     string // input con el resultado ("" = task_id)
+	}{
+		{"test failure goes to repair", &types.Task{Type: types.TaskTest}, &types.TaskResult{}, "repair", "repair failing tests", "test_result"},
+		{"test success goes to audit", &types.Task{Type: types.TaskTest}, &types.TaskResult{Success: true}, "audit", "audit code changes", ""},
+		{"blocking finding goes to repair", &types.Task{Type: types.TaskAudit},
+			&types.TaskResult{Success: true, Outputs: map[string]interface{}{"findings": []types.AuditFinding{style, secret}}}, "repair", "repair audit findings", "audit_result"},
+		{"critical findings without the full list", &types.Task{Type: types.TaskAudit},
+			&types.TaskResult{Outputs: map[string]interface{}{"critical_findings": []types.AuditFinding{secret}}}, "repair", "repair audit findings", "audit_result"},
+		{"finding outside the categories", &types.Task{Type: types.TaskAudit},
+			&types.TaskResult{Success: true, Outputs: map[string]interface{}{"findings": []types.AuditFinding{style}}}, "optimize", "optimize code", ""},
+		{"finding below the severity", &types.Task{Type: types.TaskAudit},
+			&types.TaskResult{Success: true, Outputs: map[string]interface{}{"findings": []types.AuditFinding{{Category: "security", Severity: types.SeverityMedium}}}}, "optimize", "optimize code", ""},
+		{"failed audit without findings", &types.Task{Type: types.TaskAudit}, &types.TaskResult{}, "", "", ""},
+		{"explicit stage", &types.Task{Type: types.TaskTest, Stage: "repair"}, &types.TaskResult{Success: true}, "test", "verify repair", ""},
+		{"no stage for the type", &types.Task{Type: types.TaskRelease}, &types.TaskResult{Success: true}, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.task.ID = "task-1"
+			next := p.NextTasks(tt.task, tt.result)
+			if tt.stage == "" {
+				if len(next) != 0 {
+					t.Fatalf("NextTasks() = %d tasks, want none", len(next))
+				}
+				return
+			}
+			if len(next) != 1 {
+				t.Fatalf("NextTasks() = %d tasks, want 1", len(next))
+			}
+			task := next[0]
+			if task.Stage != tt.stage || task.Type != p.Stage(tt.stage).Type || task.Objective != tt.objective || task.ParentID != "task-1" {
+				t.Errorf("next task = %s/%s %q from %s, want %s %q from task-1", task.Stage, task.Type, task.Objective, task.ParentID, tt.stage, tt.objective)
+			}
+			switch {
+			case tt.input == "" && task.Inputs["task_id"] != "task-1":
+				t.Errorf("inputs = %v, want task_id", task.Inputs)
+			case tt.input != "" && task.Inputs[tt.input] != tt.result:
+				t.Errorf("inputs = %v, want the result in %s", task.Inputs, tt.input)
+			}
+		})
+	}
+
+	// spawn envía las subtareas del output
+	plan := &types.TaskResult{Success: true, Outputs: map[string]interface{}{"subtasks": subtasks}}
+	if next := p.NextTasks(&types.Task{Type: types.TaskPlan}, plan); !reflect.DeepEqual(next, subtasks) {
+		t.Errorf("NextTasks() of a plan = %v, want its subtasks", next)
+	}
+}
+
+func TestNextTasksObjectiveFallback(t *testing.T) {
+	p := &Pipeline{Stages: []Stage{
+		{ID: "code", Type: types.TaskCode, Transitions: []Transition{{On: OnSuccess, Next: "test"}, {On: OnFailure, Next: "repair"}}},
+		{ID: "test", Type: types.TaskTest, Objective: "run the suite"},
+		{ID: "repair", Type: types.TaskRepair},
+	}}
+	task := &types.Task{ID: "task-1", Type: types.TaskCode}
+	if next := p.NextTasks(task, &types.TaskResult{Success: true}); next[0].Objective != "run the suite" {
+		t.Errorf("objective = %q, want the stage objective", next[0].Objective)
+	}
+	if next := p.NextTasks(task, &types.TaskResult{}); next[0].Objective != "repair" {
+		t.Errorf("objective = %q, want the stage id", next[0].Objective)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	p, err := LoadFile(write("p.json", `{"name": "short", "stages": [{"id": "code", "type": "code"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "short" || len(p.Stages) != 1 || p.Stages[0].Type != types.TaskCode {
+		t.Errorf("LoadFile() = %+v, want the JSON pipeline", p)
+	}
+
+	// Un 0 explícito se distingue de un campo ausente
+	p, err = LoadFile(write("retry.yaml", "stages:\n  - id: code\n    type: code\n    retry:\n      jitter: 0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := p.Stages[0].Retry; r.Jitter == nil || *r.Jitter != 0 || r.Multiplier != nil {
+		t.Errorf("LoadFile() retry = %+v, want an explicit jitter 0 and no multiplier", r)
+	}
+
+	tests := []struct {
+		name, file, content, want string
+	}{
+		{"missing file", "missing.yaml", "", "failed to read pipeline"},
+		{"invalid YAML", "bad.yaml", "stages: [", "failed to parse pipeline"},
+		{"unknown YAML field", "typo.yaml", "stages:\n  - id: code\n    type: code\n    transition: []\n", "field transition not found"},
+		{"unknown JSON field", "typo.json", `{"stages": [{"id": "code", "type": "code", "next": "test"}]}`, `unknown field "next"`},
+		{"empty file", "empty.yaml", "", "pipeline has no stages"},
+		{"invalid pipeline", "invalid.yml", "stages:\n  - id: code\n    type: code\n    transitions:\n      - on: success\n        next: test\n", `invalid pipeline`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if tt.file != "missing.yaml" {
+				path = write(tt.file, tt.content)
+			}
+			if _, err := LoadFile(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadFile() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExampleIsDefault(t *testing.T) {
+	example, err := LoadFile("../../pipeline.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(example, Default()) {
+		t.Errorf("pipeline.example.yaml differs from Default():\n%+v\nwant\n%+v", example, Default())
+	}
+}
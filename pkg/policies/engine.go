@@ -1,13 +1,22 @@
 package policies
 
 import (
+	"fmt"
+
+	"github.com/nanochip/multi-agent/pkg/glob"
 	"github.com/nanochip/multi-agent/pkg/types"
 )
 
+// DefaultCoverageThreshold es la cobertura mínima del gate coverage
+const DefaultCoverageThreshold = 70.0
+
 // Engine gestiona políticas y guardrails
 type Engine struct {
-	policies []types.Policy
-	gates    []Gate
+	policies    []types.Policy
+	rules       [][]rule // reglas interpretadas de cada política
+	gates       []Gate
+	approvals   int // aprobaciones humanas para cambios de alto riesgo
+	constraints Constraints
 }
 
 // Gate representa un gate obligatorio
@@ -17,13 +26,15 @@ type Gate struct {
 	Description string
 	Validator   func(*types.TaskResult) bool
 	Required    bool
+	Threshold   float64 // umbral del gate, si lo usa (p. ej. cobertura mínima)
 }
 
 // NewEngine crea un nuevo motor de políticas
 func NewEngine() *Engine {
 	engine := &Engine{
-		policies: make([]types.Policy, 0),
-		gates:    make([]Gate, 0),
+		policies:  make([]types.Policy, 0),
+		gates:     make([]Gate, 0),
+		approvals: 1,
 	}
 
 	// Configurar gates por defecto
@@ -69,10 +80,10 @@ func (e *Engine) setupDefaultGates() {
 			Name:        "Minimum Coverage",
 			Description: "Code coverage must meet minimum threshold",
 			Required:    true,
+			Threshold:   DefaultCoverageThreshold,
 			Validator: func(result *types.TaskResult) bool {
-				const minCoverage = 70.0
 				if testResult, ok := result.Outputs["test_result"].(*types.TestResult); ok {
-					return testResult.Coverage >= minCoverage
+					return testResult.Coverage >= e.gate("coverage").Threshold
 				}
 				return true
 			},
@@ -114,61 +125,147 @@ func (e *Engine) setupDefaultGates() {
 			ID:          "risk-review",
 			Name:        "Risk Review",
 			Description: "High-risk changes require review",
-			Required:    false, // Warning, no bloquea
+			Required:    false, // No rechaza: pide aprobación (ver RequiredApprovals)
 			Validator: func(result *types.TaskResult) bool {
-				if riskLevel, ok := result.Outputs["risk_level"].(string); ok {
-					if riskLevel == "high" {
-						// Log warning pero no bloquea
-						return true
-					}
-				}
-				return true
+				level, _ := AssessRisk(result)
+				return level != RiskHigh
 			},
 		},
 	}
 }
 
-// AllowTask verifica si una tarea está permitida según las políticas
-func (e *Engine) AllowTask(task *types.Task) bool {
-	for _, policy := range e.policies {
-		if !policy.Enabled {
+// AllowTask decide si una tarea que ejecutará agentID puede ejecutarse: no
+// debe tocar rutas prohibidas ni cumplir una regla deny de las que solo leen
+// la tarea. Las políticas con agent_id solo se aplican a ese agente.
+func (e *Engine) AllowTask(agentID string, task *types.Task) *Verdict {
+	verdict := newVerdict(PhaseTask, agentID)
+	vars := lazyVars(agentID, task, nil)
+	for i, policy := range e.policies {
+		summary := verdict.addPolicy(policy, agentID)
+		if summary < 0 {
 			continue
 		}
 
 		// Verificar restricciones de rutas si están en los inputs
-		if files, ok := task.Inputs["files"].([]interface{}); ok {
-			if forbiddenPaths, ok := policy.Metadata["forbidden_paths"].([]interface{}); ok {
-				// Validar que la tarea no toque rutas prohibidas
-				for _, file := range files {
-					if fileStr, ok := file.(string); ok {
-						for _, forbiddenPath := range forbiddenPaths {
-							if forbiddenPathStr, ok := forbiddenPath.(string); ok {
-								if matched, _ := pathMatches(fileStr, forbiddenPathStr); matched {
-									return false
-								}
-							}
-						}
-					}
+		if forbiddenPaths, ok := policy.Metadata["forbidden_paths"].([]interface{}); ok {
+			// Validar que la tarea no toque rutas prohibidas ni fuera del repositorio
+			files := stringList(task.Inputs["files"])
+			check := Check{Kind: CheckPath, Policy: policy.ID, ID: "forbidden_paths", Outcome: OutcomePass,
+				Reason: fmt.Sprintf("%d input file(s) outside forbidden_paths", len(files))}
+			for _, file := range files {
+				path := glob.Normalize(task.RepoPath, file)
+				if !glob.Inside(path) {
+					check.Outcome, check.Reason = OutcomeFail, fmt.Sprintf("%s is outside the repository", file)
+					break
+				}
+				if matchesAny(forbiddenPaths, path) {
+					check.Outcome, check.Reason = OutcomeFail, fmt.Sprintf("%s matches forbidden_paths", path)
+					break
 				}
 			}
+			verdict.add(check)
 		}
+
+		e.evaluateRules(verdict, i, vars, nil)
+		verdict.summarize(summary)
 	}
-	return true
+	return verdict
 }
 
-// ValidateResult valida un resultado contra todos los gates obligatorios
-func (e *Engine) ValidateResult(result *types.TaskResult) bool {
+// ValidateResult valida el resultado de una tarea contra los gates, el
+// límite de archivos modificados y las reglas que leen el resultado. Los
+// gates que no son obligatorios solo avisan.
+func (e *Engine) ValidateResult(agentID string, task *types.Task, result *types.TaskResult) *Verdict {
+	verdict := newVerdict(PhaseResult, agentID)
 	for _, gate := range e.gates {
-		if gate.Required && !gate.Validator(result) {
-			return false
+		check := Check{Kind: CheckGate, ID: gate.ID, Outcome: OutcomePass, Reason: gate.Description}
+		if !gate.Validator(result) {
+			check.Outcome = OutcomeFail
+			if !gate.Required {
+				check.Outcome = OutcomeWarn
+			}
 		}
+		verdict.add(check)
 	}
-	return true
+
+	files := len(stringList(result.Outputs["files_changed"]))
+	if max := e.constraints.MaxFileChanges; max > 0 {
+		check := Check{Kind: CheckConstraint, ID: "max_file_changes", Outcome: OutcomePass,
+			Reason: fmt.Sprintf("%d file(s) changed, max %d", files, max)}
+		if files > max {
+			check.Outcome = OutcomeFail
+		}
+		verdict.add(check)
+	}
+
+	vars := lazyVars(agentID, task, result)
+	for i, policy := range e.policies {
+		if summary := verdict.addPolicy(policy, agentID); summary >= 0 {
+			e.evaluateRules(verdict, i, vars, result)
+			verdict.summarize(summary)
+		}
+	}
+	return verdict
+}
+
+// lazyVars prepara las variables de las condiciones la primera vez que se piden
+func lazyVars(agentID string, task *types.Task, result *types.TaskResult) func() map[string]interface{} {
+	var vars map[string]interface{}
+	return func() map[string]interface{} {
+		if vars == nil {
+			vars = ruleVars(agentID, task, result)
+		}
+		return vars
+	}
+}
+
+// ApplyConstraints aplica a una tarea las restricciones globales: limita sus
+// reintentos y le fija el tiempo máximo si no tiene uno propio
+func (e *Engine) ApplyConstraints(task *types.Task) {
+	if max := e.constraints.MaxRetries; max != nil && task.MaxRetries > *max {
+		task.MaxRetries = *max
+	}
+	if seconds := e.constraints.MaxExecutionTimeSeconds; seconds > 0 {
+		// Misma clave que orchestrator.ConstraintMaxExecutionTime
+		if _, ok := task.Constraints["max_execution_time_seconds"]; !ok {
+			if task.Constraints == nil {
+				task.Constraints = make(map[string]interface{})
+			}
+			task.Constraints["max_execution_time_seconds"] = seconds
+		}
+	}
+}
+
+// SetRequiredApprovals fija cuántas aprobaciones humanas necesita un cambio
+// de alto riesgo (0 = ninguna)
+func (e *Engine) SetRequiredApprovals(n int) {
+	e.approvals = n
+}
+
+// RequiredApprovals retorna cuántas aprobaciones necesita el resultado antes
+// de continuar, y por qué. Solo las necesita si no pasa el gate risk-review.
+func (e *Engine) RequiredApprovals(result *types.TaskResult) (int, []string) {
+	if e.approvals <= 0 {
+		return 0, nil
+	}
+	for _, gate := range e.gates {
+		if gate.ID == "risk-review" && !gate.Validator(result) {
+			_, reasons := AssessRisk(result)
+			return e.approvals, reasons
+		}
+	}
+	return 0, nil
 }
 
 // AddPolicy añade una nueva política
 func (e *Engine) AddPolicy(policy types.Policy) {
 	e.policies = append(e.policies, policy)
+	e.rules = append(e.rules, compileRules(policy))
+}
+
+// GetPolicies retorna las políticas configuradas
+func (e *Engine) GetPolicies() []types.Policy {
+	return e.policies
 }
 
 // GetGates retorna los gates configurados
@@ -176,50 +273,46 @@ func (e *Engine) GetGates() []Gate {
 	return e.gates
 }
 
-// ValidatePath verifica si una ruta está permitida para un agente
+// gate retorna el gate con el ID indicado
+func (e *Engine) gate(id string) *Gate {
+	for i := range e.gates {
+		if e.gates[i].ID == id {
+			return &e.gates[i]
+		}
+	}
+	return nil
+}
+
+// ValidatePath verifica si una ruta está permitida para un agente. La ruta
+// debe ser relativa a la raíz del repositorio (ver glob.Normalize).
 func (e *Engine) ValidatePath(agentID string, path string, policy types.Policy) bool {
+	path = glob.Normalize("", path)
+	if !glob.Inside(path) {
+		return false
+	}
+
 	// Verificar rutas permitidas
 	if allowedPaths, ok := policy.Metadata["allowed_paths"].([]interface{}); ok {
-		allowed := false
-		for _, allowedPath := range allowedPaths {
-			if allowedPathStr, ok := allowedPath.(string); ok {
-				if matched, _ := pathMatches(path, allowedPathStr); matched {
-					allowed = true
-					break
-				}
-			}
-		}
-		if !allowed {
+		if !matchesAny(allowedPaths, path) {
 			return false
 		}
 	}
 
 	// Verificar rutas prohibidas
 	if forbiddenPaths, ok := policy.Metadata["forbidden_paths"].([]interface{}); ok {
-		for _, forbiddenPath := range forbiddenPaths {
-			if forbiddenPathStr, ok := forbiddenPath.(string); ok {
-				if matched, _ := pathMatches(path, forbiddenPathStr); matched {
-					return false
-				}
-			}
+		if matchesAny(forbiddenPaths, path) {
+			return false
 		}
 	}
 
 	return true
 }
 
-// pathMatches verifica si un path coincide con un patrón (simple glob)
-func pathMatches(path, pattern string) (bool, error) {
-	// Implementación simple de glob matching
-	// Por ahora, soporte básico de "*"
-	if pattern == "*" {
-		return true, nil
-	}
-	if pattern == path {
-		return true, nil
-	}
-	// Implementación más completa iría aquí
-	return false, nil
+// matchesAny indica si la ruta coincide con la lista de patrones de una
+// política. Un patrón mal formado no coincide con nada.
+func matchesAny(patterns []interface{}, path string) bool {
+	matched, err := glob.MatchAny(stringList(patterns), path)
+	return err == nil && matched
 }
 
 // GetPolicyForAgent retorna la política para un agente específico
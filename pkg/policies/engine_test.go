@@ -0,0 +1,365 @@
+package policies
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+func TestExamplePolicyPaths(t *testing.T) {
+	engine, err := LoadFile("../../policies.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		agent string
+		path  string
+		want  bool
+	}{
+		{"coder", "pkg/policies/engine.go", true},
+		{"coder", "./cmd/orchestrator/main.go", true},
+		{"coder", "src/a/b/c.go", true},
+		{"coder", "internal/x.go", true},
+		{"coder", "pkg/policies/engine_test.go", false},
+		{"coder", "vendor/gopkg.in/yaml.v3/yaml.go", false},
+		{"coder", "migrations/001_init.sql", false},
+		{"coder", "main.go", false},
+		{"coder", "../pkg/a.go", false},
+
+		{"tester", "pkg/policies/engine_test.go", true},
+		{"tester", "main_test.go", true},
+		{"tester", "pkg/policies/engine.go", false},
+		{"tester", "README.md", false},
+
+		{"auditor", "pkg/policies/engine.go", false},
+		{"auditor", "README.md", false},
+	}
+	for _, tt := range tests {
+		policy := engine.GetPolicyForAgent(tt.agent)
+		if policy == nil {
+			t.Fatalf("no policy for %s", tt.agent)
+		}
+		if got := engine.ValidatePath(tt.agent, tt.path, *policy); got != tt.want {
+			t.Errorf("%s: ValidatePath(%q) = %v, want %v", tt.agent, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestAllowTaskForbiddenPaths(t *testing.T) {
+	engine, err := LoadFile("../../policies.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		agent string
+		repo  string
+		files []interface{}
+		want  bool
+	}{
+		{"coder", "", []interface{}{"pkg/a.go", "cmd/b.go"}, true},
+		{"coder", "", []interface{}{"pkg/a.go", "pkg/a_test.go"}, false},
+		{"coder", "", []interface{}{"migrations/002.sql"}, false},
+		{"coder", "/repo", []interface{}{"/repo/pkg/a.go"}, true},
+		{"coder", "/repo", []interface{}{"/repo/vendor/x/a.go"}, false},
+		{"coder", "/repo", []interface{}{"/elsewhere/a.go"}, false},
+		{"tester", "", []interface{}{"pkg/a_test.go"}, true},
+		{"tester", "", []interface{}{"pkg/a.go"}, false},
+		// Las políticas de otros agentes no se aplican
+		{"auditor", "", []interface{}{"pkg/a.go", "pkg/a_test.go", "vendor/x.go"}, true},
+		{"coder", "", nil, true},
+	}
+	for _, tt := range tests {
+		task := &types.Task{ID: "t", RepoPath: tt.repo, Inputs: map[string]interface{}{}}
+		if tt.files != nil {
+			task.Inputs["files"] = tt.files
+		}
+		if got := engine.AllowTask(tt.agent, task).Allowed; got != tt.want {
+			t.Errorf("AllowTask(%s, %v) = %v, want %v", tt.agent, tt.files, got, tt.want)
+		}
+	}
+}
+
+func TestLoadFileRejectsInvalidPattern(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policies.yaml")
+	content := "policies:\n  - id: p\n    type: constraint\n    metadata:\n      allowed_paths: [\"src/**\", \"src/[a\"]\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadFile(file)
+	want := "policies[0].metadata.allowed_paths[1]: invalid pattern"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("LoadFile() error = %v, want %q", err, want)
+	}
+}
+
+func TestRules(t *testing.T) {
+	engine := NewEngine()
+	engine.AddPolicy(types.Policy{ID: "quality", Enabled: true, Rules: []types.PolicyRule{
+		{Condition: `task.stage == "hotfix"`, Action: ActionAllow},
+		{Condition: `result.test_result.coverage < 80 && task.type == "code"`, Action: ActionDeny, Message: "coverage below 80% on code changes"},
+		{Condition: `len(files_changed) > 10`, Action: ActionWarn, Message: "large change"},
+		{Condition: `len(files_changed) > 50`, Action: ActionDeny},
+		{Condition: `matches(files_changed, "migrations/**")`, Action: ActionDeny, Message: "migrations need a human"},
+	}})
+	engine.AddPolicy(types.Policy{ID: "tasks", Enabled: true, Rules: []types.PolicyRule{
+		{Condition: `task.type == "release" && task.priority != "urgent"`, Action: ActionDeny, Message: "releases must be urgent"},
+		{Condition: `agent == "optimizer"`, Action: ActionWarn, Message: "optimizer is experimental"},
+	}})
+	engine.AddPolicy(types.Policy{ID: "auditor-only", Enabled: true, Metadata: map[string]interface{}{"agent_id": "auditor"},
+		Rules: []types.PolicyRule{{Condition: `true`, Action: ActionDeny, Message: "auditor disabled"}}})
+	engine.AddPolicy(types.Policy{ID: "disabled", Enabled: false,
+		Rules: []types.PolicyRule{{Condition: `true`, Action: ActionDeny}}})
+
+	files := func(n int, name string) []string {
+		list := make([]string, n)
+		for i := range list {
+			list[i] = fmt.Sprintf("pkg/f%d.go", i)
+		}
+		if name != "" {
+			list = append(list, name)
+		}
+		return list
+	}
+	withCoverage := func(coverage float64, changed []string) *types.TaskResult {
+		return &types.TaskResult{Success: true, Outputs: map[string]interface{}{
+			"test_result":   &types.TestResult{Coverage: coverage},
+			"files_changed": changed,
+		}}
+	}
+
+	tests := []struct {
+		name     string
+		agent    string
+		task     *types.Task
+		result   *types.TaskResult
+		allowed  bool
+		reasons  string
+		warnings string
+	}{
+		{"plain task", "coder", &types.Task{Type: types.TaskCode, Objective: "x"}, nil, true, "", ""},
+		{"release not urgent", "release", &types.Task{Type: "release", Priority: types.PriorityHigh}, nil, false, "tasks: releases must be urgent", ""},
+		{"release urgent", "release", &types.Task{Type: "release", Priority: types.PriorityUrgent}, nil, true, "", ""},
+		{"warn on agent", "optimizer", &types.Task{Type: types.TaskOptimize}, nil, true, "", "tasks: optimizer is experimental"},
+		{"agent_id scope", "auditor", &types.Task{Type: types.TaskAudit}, nil, false, "auditor-only: auditor disabled", ""},
+
+		{"coverage ok", "coder", &types.Task{Type: types.TaskCode}, withCoverage(85, files(1, "")), true, "", ""},
+		{"low coverage", "coder", &types.Task{Type: types.TaskCode}, withCoverage(75, files(1, "")), false,
+			"quality: coverage below 80% on code changes", ""},
+		{"low coverage not code", "tester", &types.Task{Type: types.TaskTest}, withCoverage(75, files(1, "")), true, "", ""},
+		{"hotfix allowed first", "coder", &types.Task{Type: types.TaskCode, Stage: "hotfix"}, withCoverage(75, files(60, "migrations/1.sql")), true, "",
+			"gate risk-review: High-risk changes require review"},
+		{"warn then deny", "coder", &types.Task{Type: types.TaskCode}, withCoverage(90, files(60, "")), false,
+			"quality: len(files_changed) > 50", "gate risk-review: High-risk changes require review; quality: large change"},
+		{"migrations", "coder", &types.Task{Type: types.TaskCode}, withCoverage(90, files(1, "migrations/002.sql")), false,
+			"quality: migrations need a human", "gate risk-review: High-risk changes require review"},
+		{"no outputs", "coder", &types.Task{Type: types.TaskCode}, &types.TaskResult{Success: true}, true, "", ""},
+	}
+	for _, tt := range tests {
+		var v *Verdict
+		if tt.result == nil {
+			v = engine.AllowTask(tt.agent, tt.task)
+		} else {
+			v = engine.ValidateResult(tt.agent, tt.task, tt.result)
+		}
+		if v.Allowed != tt.allowed || v.Reason() != tt.reasons || strings.Join(v.Warnings(), "; ") != tt.warnings {
+			t.Errorf("%s: got allowed=%v reasons=%q warnings=%q, want %v %q %q",
+				tt.name, v.Allowed, v.Reason(), v.Warnings(), tt.allowed, tt.reasons, tt.warnings)
+		}
+	}
+}
+
+func TestRuleEvaluationErrors(t *testing.T) {
+	engine := NewEngine()
+	engine.AddPolicy(types.Policy{ID: "p", Enabled: true, Rules: []types.PolicyRule{
+		{Condition: `task.objective > 3`, Action: ActionDeny},
+		{Condition: `task.objective > 3`, Action: ActionWarn},
+		{Condition: `task.objective > 3`, Action: ActionAllow},
+	}})
+	v := engine.AllowTask("coder", &types.Task{Objective: "x"})
+	if v.Allowed || !strings.Contains(v.Reason(), "cannot compare string > number") || len(v.Warnings()) != 1 {
+		t.Errorf("got allowed=%v reasons=%q warnings=%q", v.Allowed, v.Reason(), v.Warnings())
+	}
+
+	// Una condición que no se puede interpretar rechaza todas las tareas
+	engine = NewEngine()
+	engine.AddPolicy(types.Policy{ID: "p", Enabled: true, Rules: []types.PolicyRule{{Condition: `task.type ==`, Action: ActionDeny}}})
+	if v := engine.AllowTask("coder", &types.Task{}); v.Allowed {
+		t.Error("an unparsable deny rule should block the task")
+	}
+}
+
+func TestLoadFileRejectsInvalidCondition(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policies.yaml")
+	content := "policies:\n  - id: p\n    type: rule\n    rules:\n      - condition: 'len(files_changed) >'\n        action: deny\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadFile(file)
+	want := "5:20: policies[0].rules[0].condition: invalid condition: column 21: unexpected end of expression"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("LoadFile() error = %v, want %q", err, want)
+	}
+}
+
+func TestVerdictChecks(t *testing.T) {
+	engine := NewEngine()
+	engine.AddPolicy(types.Policy{ID: "quality", Enabled: true, Rules: []types.PolicyRule{
+		{Condition: `task.stage == "hotfix"`, Action: ActionAllow},
+		{Condition: `len(files_changed) > 10`, Action: ActionWarn, Message: "large change"},
+		{Condition: `matches(files_changed, "migrations/**")`, Action: ActionDeny, Message: "migrations need a human"},
+		{Condition: `len(files_changed) > 50`, Action: ActionDeny},
+	}})
+	engine.AddPolicy(types.Policy{ID: "auditor-only", Enabled: true, Metadata: map[string]interface{}{"agent_id": "auditor"}})
+	engine.AddPolicy(types.Policy{ID: "disabled", Enabled: false})
+
+	changed := []string{"migrations/1.sql"}
+	for i := 0; i < 11; i++ {
+		changed = append(changed, fmt.Sprintf("pkg/f%d.go", i))
+	}
+	result := &types.TaskResult{Success: true, Outputs: map[string]interface{}{"files_changed": changed}}
+	v := engine.ValidateResult("coder", &types.Task{Type: types.TaskCode}, result)
+
+	want := []string{
+		"pass gate fmt-lint: Code must pass fmt and lint checks",
+		"pass gate tests-pass: All tests must pass",
+		"pass gate coverage: Code coverage must meet minimum threshold",
+		"pass gate secrets: No secrets should be exposed",
+		"pass gate dependencies: No critical CVEs in dependencies",
+		"warn gate risk-review: High-risk changes require review",
+		"fail quality: applies to all agents, 1 failed and 1 warned of 4 check(s)",
+		`pass quality.rules[0]: condition not met: task.stage == "hotfix"`,
+		"warn quality.rules[1]: large change",
+		"fail quality.rules[2]: migrations need a human",
+		"skip quality.rules[3]: not evaluated, decided by quality.rules[2]",
+		"skip auditor-only: applies only to agent auditor",
+		"skip disabled: disabled",
+	}
+	if len(v.Checks) != len(want) {
+		t.Fatalf("got %d checks, want %d: %+v", len(v.Checks), len(want), v.Checks)
+	}
+	for i, check := range v.Checks {
+		if got := fmt.Sprintf("%s %s: %s", check.Outcome, check.Name(), check.Reason); got != want[i] {
+			t.Errorf("check %d = %q, want %q", i, got, want[i])
+		}
+	}
+	if v.Allowed {
+		t.Error("verdict should deny")
+	}
+
+	// El veredicto se guarda como evidencia del resultado y se recupera igual
+	result.Evidence = append(result.Evidence, types.Evidence{Type: "log", Source: "go test"}, v.Evidence())
+	verdicts := VerdictsFromResult(result)
+	if len(verdicts) != 1 || verdicts[0].Phase != PhaseResult || verdicts[0].Agent != "coder" ||
+		verdicts[0].Allowed || len(verdicts[0].Checks) != len(want) || verdicts[0].Reason() != v.Reason() {
+		t.Errorf("VerdictsFromResult() = %+v, want %+v", verdicts, v)
+	}
+}
+
+func TestExampleFixtures(t *testing.T) {
+	engine, err := LoadFile("../../policies.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures, err := LoadFixtures("../../policies.example.fixtures.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no fixtures loaded")
+	}
+	for _, fixture := range fixtures {
+		if result := engine.TestFixture(fixture); !result.Passed() {
+			t.Errorf("%s: %s", fixture.Name, result.Mismatch)
+		}
+	}
+}
+
+func TestFixtureMismatches(t *testing.T) {
+	engine := NewEngine()
+	engine.AddPolicy(types.Policy{ID: "quality", Enabled: true, Rules: []types.PolicyRule{
+		{Condition: `task.type == "release"`, Action: ActionDeny, Message: "no releases"},
+		{Condition: `len(files_changed) > 2`, Action: ActionWarn, Message: "large change"},
+	}})
+
+	file := filepath.Join(t.TempDir(), "fixtures.yaml")
+	content := `agent: coder
+task: {type: code}
+expect: allow
+---
+agent: releaser
+task: {type: release}
+expect: allow
+---
+agent: releaser
+task: {type: release}
+expect: deny
+reason: coverage
+---
+agent: coder
+result:
+  outputs: {files_changed: [a.go, b.go, c.go]}
+expect: warn
+reason: large change
+---
+agent: coder
+result:
+  success: false
+  outputs: {files_changed: [a.go, b.go, c.go]}
+expect: allow
+`
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fixtures, err := LoadFixtures(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"",
+		"expected allow, got deny: quality: no releases",
+		`expected a reason containing "coverage", got: quality: no releases`,
+		"",
+		"", // un resultado fallido no se valida
+	}
+	if len(fixtures) != len(want) {
+		t.Fatalf("got %d fixtures, want %d", len(fixtures), len(want))
+	}
+	for i, fixture := range fixtures {
+		if got := engine.TestFixture(fixture).Mismatch; got != want[i] {
+			t.Errorf("fixture %d (line %d): mismatch = %q, want %q", i, fixture.Line, got, want[i])
+		}
+	}
+}
+
+func TestLoadFixturesErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "fixtures.yaml")
+	content := "task: {typ: code}\nexpect: maybe\n---\nagent: coder\nexpect: allow\nreason: x\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadFixtures(file)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		`1:1: agent: required`,
+		`1:7: task: unknown field "typ"`,
+		`2:9: expect: unknown value "maybe"`,
+		`4:1: fixture: a task or a result is required`,
+		`6:9: reason: only deny and warn have a reason`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadFixtures() error = %v, want %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package policies
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Campos admitidos en un fixture
+var (
+	fixtureFields  = []string{"name", "agent", "task", "result", "expect", "reason"}
+	fixtureExpects = []string{ActionAllow, ActionDeny, ActionWarn}
+)
+
+// outputTypes son los outputs que los gates y AssessRisk leen con su tipo
+// de Go; los de un fixture se convierten para que se evalúen igual que los
+// de un agente
+var outputTypes = map[string]func() interface{}{
+	"test_result":         func() interface{} { return new(types.TestResult) },
+	"lint_errors":         func() interface{} { return new([]types.AuditFinding) },
+	"secret_findings":     func() interface{} { return new([]types.AuditFinding) },
+	"dependency_findings": func() interface{} { return new([]types.AuditFinding) },
+	"findings":            func() interface{} { return new([]types.AuditFinding) },
+}
+
+// Fixture es un caso de prueba de un archivo de políticas: la tarea que
+// ejecuta un agente y, si se quiere probar su validación, el resultado, con
+// el veredicto esperado
+type Fixture struct {
+	Name   string // opcional
+	File   string
+	Line   int // línea del documento en el archivo
+	Agent  string
+	Task   *types.Task
+	Result *types.TaskResult // nil: solo se evalúa la tarea
+	Expect string            // allow, deny o warn
+	Reason string            // texto que debe aparecer en un motivo (opcional)
+}
+
+// FixtureResult es el resultado de probar un fixture
+type FixtureResult struct {
+	Fixture  *Fixture
+	Got      string     // allow, deny o warn
+	Verdicts []*Verdict // tarea y, si se evaluó, resultado
+	Mismatch string     // "" si coincide con lo esperado
+}
+
+// Passed indica si el fixture obtuvo el veredicto esperado
+func (r FixtureResult) Passed() bool {
+	return r.Mismatch == ""
+}
+
+// LoadFixtures lee los fixtures de un archivo YAML o JSON, uno por documento
+// (separados por ---). Los errores de esquema indican línea y columna.
+func LoadFixtures(path string) ([]*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	l := &loader{file: path}
+	fixtures := make([]*Fixture, 0)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		if fixture := l.fixture(doc.Content[0]); fixture != nil {
+			fixtures = append(fixtures, fixture)
+		}
+	}
+	if len(l.errs) > 0 {
+		sort.SliceStable(l.errs, func(i, j int) bool {
+			a, b := l.errs[i].(*SchemaError), l.errs[j].(*SchemaError)
+			return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+		})
+		return nil, errors.Join(l.errs...)
+	}
+	return fixtures, nil
+}
+
+// fixture valida un fixture
+func (l *loader) fixture(node *yaml.Node) *Fixture {
+	fields := l.mapping(node, "fixture", fixtureFields)
+	if fields == nil {
+		return nil
+	}
+	fixture := &Fixture{
+		Name:   l.str(fields["name"], "name"),
+		File:   l.file,
+		Line:   node.Line,
+		Agent:  l.str(fields["agent"], "agent"),
+		Task:   &types.Task{},
+		Expect: l.oneOf(fields["expect"], "expect", fixtureExpects),
+		Reason: l.str(fields["reason"], "reason"),
+	}
+	if fields["agent"] == nil {
+		l.fail(node, "agent", "required")
+	}
+	if fields["expect"] == nil {
+		l.fail(node, "expect", "required")
+	}
+	if fixture.Reason != "" && fixture.Expect == ActionAllow {
+		l.fail(fields["reason"], "reason", "only deny and warn have a reason")
+	}
+
+	if n := fields["task"]; n != nil {
+		l.object(n, "task", fixture.Task)
+	}
+	if n := fields["result"]; n != nil {
+		// Sin success explícito el resultado es un éxito, el único que se valida
+		fixture.Result = &types.TaskResult{Success: true}
+		if l.object(n, "result", fixture.Result) {
+			l.outputs(n, fixture.Result.Outputs)
+		}
+	}
+	if fields["task"] == nil && fields["result"] == nil {
+		l.fail(node, "fixture", "a task or a result is required")
+	}
+	return fixture
+}
+
+// object decodifica un mapping en un tipo con tags JSON; los campos
+// desconocidos son un error
+func (l *loader) object(node *yaml.Node, field string, v interface{}) bool {
+	values := l.freeform(node, field)
+	if values == nil {
+		return false
+	}
+	data, err := json.Marshal(values)
+	if err == nil {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(v)
+	}
+	if err != nil {
+		l.fail(node, field, "%s", strings.TrimPrefix(err.Error(), "json: "))
+		return false
+	}
+	return true
+}
+
+// outputs convierte los outputs que leen los gates a su tipo de Go
+func (l *loader) outputs(node *yaml.Node, outputs map[string]interface{}) {
+	names := make([]string, 0, len(outputTypes))
+	for key := range outputTypes {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	for _, key := range names {
+		value, ok := outputs[key]
+		if !ok {
+			continue
+		}
+		target := outputTypes[key]()
+		data, _ := json.Marshal(value)
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(target); err != nil {
+			l.fail(node, "result.outputs."+key, "%s", strings.TrimPrefix(err.Error(), "json: "))
+			continue
+		}
+		if findings, ok := target.(*[]types.AuditFinding); ok {
+			outputs[key] = *findings
+		} else {
+			outputs[key] = target
+		}
+	}
+}
+
+// TestFixture evalúa un fixture como lo haría el orchestrator: primero la
+// tarea y, si se permite y el resultado es un éxito, el resultado. Se espera
+// deny si algún veredicto rechaza, warn si se permite con avisos y allow si
+// se permite sin ellos.
+func (e *Engine) TestFixture(fixture *Fixture) FixtureResult {
+	out := FixtureResult{Fixture: fixture, Verdicts: make([]*Verdict, 0, 2)}
+	verdict := e.AllowTask(fixture.Agent, fixture.Task)
+	out.Verdicts = append(out.Verdicts, verdict)
+	if verdict.Allowed && fixture.Result != nil && fixture.Result.Success {
+		out.Verdicts = append(out.Verdicts, e.ValidateResult(fixture.Agent, fixture.Task, fixture.Result))
+	}
+
+	allowed, reasons, warnings := true, make([]string, 0), make([]string, 0)
+	for _, v := range out.Verdicts {
+		allowed = allowed && v.Allowed
+		reasons = append(reasons, v.Reasons()...)
+		warnings = append(warnings, v.Warnings()...)
+	}
+	messages := warnings
+	switch {
+	case !allowed:
+		out.Got, messages = ActionDeny, reasons
+	case len(warnings) > 0:
+		out.Got = ActionWarn
+	default:
+		out.Got = ActionAllow
+	}
+
+	switch {
+	case out.Got != fixture.Expect:
+		out.Mismatch = fmt.Sprintf("expected %s, got %s", fixture.Expect, out.Got)
+		if len(messages) > 0 {
+			out.Mismatch += ": " + strings.Join(messages, "; ")
+		}
+	case fixture.Reason != "" && !containsText(messages, fixture.Reason):
+		out.Mismatch = fmt.Sprintf("expected a reason containing %q, got: %s", fixture.Reason, strings.Join(messages, "; "))
+	}
+	return out
+}
+
+// containsText indica si algún mensaje contiene el texto
+func containsText(messages []string, text string) bool {
+	for _, message := range messages {
+		if strings.Contains(message, text) {
+			return true
+		}
+	}
+	return false
+}
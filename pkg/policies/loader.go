@@ -0,0 +1,477 @@
+package policies
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nanochip/multi-agent/pkg/expr"
+	"github.com/nanochip/multi-agent/pkg/glob"
+	"github.com/nanochip/multi-agent/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Constraints son las restricciones globales de un archivo de políticas
+type Constraints struct {
+	MaxRetries              *int // tope de reintentos de cada tarea
+	MaxExecutionTimeSeconds float64
+	MaxFileChanges          int // archivos modificados por resultado (0 = sin límite)
+}
+
+// SchemaError es un error de validación con su posición en el archivo
+type SchemaError struct {
+	File    string
+	Line    int
+	Column  int
+	Field   string // p. ej. policies[1].metadata.allowed_paths
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", e.File, e.Line, e.Column, e.Field, e.Message)
+}
+
+// Campos admitidos en cada nivel del archivo
+var (
+	fileFields       = []string{"policies", "gates", "constraints"}
+	policyFields     = []string{"id", "name", "description", "type", "enabled", "rules", "metadata"}
+	ruleFields       = []string{"condition", "action", "message", "metadata"}
+	gateFields       = []string{"id", "name", "description", "required", "threshold"}
+	constraintFields = []string{"max_retries", "max_execution_time_seconds", "max_file_changes", "required_approvals_for_high_risk"}
+	policyTypes      = []string{"gate", "constraint", "rule"}
+	ruleActions      = []string{ActionAllow, ActionDeny, ActionWarn}
+)
+
+// thresholdRanges son los gates que admiten umbral y sus valores válidos
+var thresholdRanges = map[string][2]float64{
+	"coverage": {0, 100},
+}
+
+// LoadFile construye un Engine a partir de un archivo de políticas YAML (ver
+// policies.example.yaml). Los gates del archivo ajustan los gates por defecto
+// y los que no aparecen conservan su configuración; las políticas sin
+// enabled están activas. Los errores de esquema indican línea y columna.
+func LoadFile(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policies: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse policies %s: %w", path, err)
+	}
+	engine := NewEngine()
+	if len(doc.Content) == 0 {
+		return engine, nil
+	}
+
+	l := &loader{file: path, engine: engine}
+	l.load(doc.Content[0])
+	if len(l.errs) > 0 {
+		sort.SliceStable(l.errs, func(i, j int) bool {
+			a, b := l.errs[i].(*SchemaError), l.errs[j].(*SchemaError)
+			return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+		})
+		return nil, errors.Join(l.errs...)
+	}
+	return engine, nil
+}
+
+// loader valida el árbol YAML y configura el Engine, acumulando los errores
+type loader struct {
+	file   string
+	engine *Engine
+	errs   []error
+}
+
+// load recorre el documento completo
+func (l *loader) load(root *yaml.Node) {
+	fields := l.mapping(root, "policies file", fileFields)
+
+	ids := make(map[string]bool)
+	for i, node := range l.sequence(fields["policies"], "policies") {
+		field := fmt.Sprintf("policies[%d]", i)
+		policy, ok := l.policy(node, field)
+		if !ok {
+			continue
+		}
+		if ids[policy.ID] {
+			l.fail(node, field+".id", "duplicate policy %q", policy.ID)
+			continue
+		}
+		ids[policy.ID] = true
+		l.engine.AddPolicy(policy)
+	}
+
+	gates := make(map[string]bool)
+	for i, node := range l.sequence(fields["gates"], "gates") {
+		l.gate(node, fmt.Sprintf("gates[%d]", i), gates)
+	}
+
+	if node := fields["constraints"]; node != nil {
+		l.constraints(node, "constraints")
+	}
+}
+
+// policy valida una política
+func (l *loader) policy(node *yaml.Node, field string) (types.Policy, bool) {
+	fields := l.mapping(node, field, policyFields)
+	if fields == nil {
+		return types.Policy{}, false
+	}
+	policy := types.Policy{
+		ID:          l.str(fields["id"], field+".id"),
+		Name:        l.str(fields["name"], field+".name"),
+		Description: l.str(fields["description"], field+".description"),
+		Type:        l.oneOf(fields["type"], field+".type", policyTypes),
+		Enabled:     true,
+		Rules:       make([]types.PolicyRule, 0),
+		Metadata:    make(map[string]interface{}),
+	}
+	if policy.ID == "" {
+		l.fail(node, field+".id", "required")
+		return policy, false
+	}
+	if n := fields["enabled"]; n != nil {
+		policy.Enabled = l.boolean(n, field+".enabled")
+	}
+
+	for i, n := range l.sequence(fields["rules"], field+".rules") {
+		ruleField := fmt.Sprintf("%s.rules[%d]", field, i)
+		rule := l.mapping(n, ruleField, ruleFields)
+		if rule == nil {
+			continue
+		}
+		r := types.PolicyRule{
+			Condition: l.str(rule["condition"], ruleField+".condition"),
+			Action:    l.oneOf(rule["action"], ruleField+".action", ruleActions),
+			Message:   l.str(rule["message"], ruleField+".message"),
+			Metadata:  l.freeform(rule["metadata"], ruleField+".metadata"),
+		}
+		if rule["condition"] == nil {
+			l.fail(n, ruleField+".condition", "required")
+		} else if _, err := expr.Parse(r.Condition); err != nil && r.Condition != "" {
+			l.fail(rule["condition"], ruleField+".condition", "invalid condition: %v", err)
+		}
+		if rule["action"] == nil {
+			l.fail(n, ruleField+".action", "required")
+		}
+		policy.Rules = append(policy.Rules, r)
+	}
+
+	if n := fields["metadata"]; n != nil {
+		metadata := l.mapping(n, field+".metadata", nil)
+		for _, key := range keys(n) {
+			value, ok := metadata[key]
+			if !ok {
+				continue
+			}
+			keyField := field + ".metadata." + key
+			switch key {
+			case "agent_id":
+				policy.Metadata[key] = l.str(value, keyField)
+			case "allowed_paths", "forbidden_paths":
+				policy.Metadata[key] = l.patterns(value, keyField)
+			case "allowed_tools":
+				policy.Metadata[key] = l.strings(value, keyField)
+			case "required_tests":
+				policy.Metadata[key] = l.boolean(value, keyField)
+			case "max_file_size_kb":
+				policy.Metadata[key] = l.integer(value, keyField)
+			default:
+				policy.Metadata[key] = l.decode(value, keyField)
+			}
+		}
+	}
+	return policy, true
+}
+
+// gate valida un gate y ajusta el gate por defecto del mismo ID
+func (l *loader) gate(node *yaml.Node, field string, seen map[string]bool) {
+	fields := l.mapping(node, field, gateFields)
+	if fields == nil {
+		return
+	}
+	id := l.str(fields["id"], field+".id")
+	if id == "" {
+		l.fail(node, field+".id", "required")
+		return
+	}
+	gate := l.engine.gate(id)
+	if gate == nil {
+		known := make([]string, 0, len(l.engine.gates))
+		for _, g := range l.engine.gates {
+			known = append(known, g.ID)
+		}
+		l.fail(fields["id"], field+".id", "unknown gate %q (expected one of %s)", id, strings.Join(known, ", "))
+		return
+	}
+	if seen[id] {
+		l.fail(fields["id"], field+".id", "duplicate gate %q", id)
+		return
+	}
+	seen[id] = true
+
+	if n := fields["name"]; n != nil {
+		gate.Name = l.str(n, field+".name")
+	}
+	if n := fields["description"]; n != nil {
+		gate.Description = l.str(n, field+".description")
+	}
+	if n := fields["required"]; n != nil {
+		gate.Required = l.boolean(n, field+".required")
+	}
+	if n := fields["threshold"]; n != nil {
+		limits, ok := thresholdRanges[id]
+		if !ok {
+			l.fail(n, field+".threshold", "gate %q has no threshold", id)
+			return
+		}
+		threshold, ok := l.number(n, field+".threshold")
+		if ok && (threshold < limits[0] || threshold > limits[1]) {
+			l.fail(n, field+".threshold", "must be between %g and %g", limits[0], limits[1])
+			return
+		}
+		gate.Threshold = threshold
+	}
+}
+
+// constraints valida las restricciones globales
+func (l *loader) constraints(node *yaml.Node, field string) {
+	constraints := l.mapping(node, field, constraintFields)
+	for _, key := range keys(node) {
+		value, ok := constraints[key]
+		if !ok {
+			continue
+		}
+		keyField := field + "." + key
+		switch key {
+		case "max_retries":
+			n := l.nonNegative(value, keyField)
+			l.engine.constraints.MaxRetries = &n
+		case "max_execution_time_seconds":
+			seconds, ok := l.number(value, keyField)
+			if ok && seconds <= 0 {
+				l.fail(value, keyField, "must be greater than 0")
+			} else {
+				l.engine.constraints.MaxExecutionTimeSeconds = seconds
+			}
+		case "max_file_changes":
+			l.engine.constraints.MaxFileChanges = l.nonNegative(value, keyField)
+		case "required_approvals_for_high_risk":
+			l.engine.SetRequiredApprovals(l.nonNegative(value, keyField))
+		}
+	}
+}
+
+// fail registra un error de esquema en la posición del nodo
+func (l *loader) fail(node *yaml.Node, field, format string, args ...interface{}) {
+	l.errs = append(l.errs, &SchemaError{
+		File:    l.file,
+		Line:    node.Line,
+		Column:  node.Column,
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// mapping retorna los campos de un mapping. Si known no es nil, los campos
+// desconocidos son un error.
+func (l *loader) mapping(node *yaml.Node, field string, known []string) map[string]*yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		l.fail(node, field, "expected a mapping, got %s", describe(node))
+		return nil
+	}
+	fields := make(map[string]*yaml.Node, len(node.Content)/2)
+	seen := make(map[string]bool, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch {
+		case known != nil && !contains(known, key.Value):
+			l.fail(key, field, "unknown field %q (expected one of %s)", key.Value, strings.Join(known, ", "))
+		case seen[key.Value]:
+			l.fail(key, field, "duplicate field %q", key.Value)
+		case value.Tag != "!!null":
+			fields[key.Value] = value
+		}
+		seen[key.Value] = true
+	}
+	return fields
+}
+
+// keys retorna las claves de un mapping en el orden del archivo
+func keys(node *yaml.Node) []string {
+	out := make([]string, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		out = append(out, node.Content[i].Value)
+	}
+	return out
+}
+
+// sequence retorna los elementos de una lista (nil si el nodo no existe)
+func (l *loader) sequence(node *yaml.Node, field string) []*yaml.Node {
+	if node == nil {
+		return nil
+	}
+	if node.Kind != yaml.SequenceNode {
+		l.fail(node, field, "expected a list, got %s", describe(node))
+		return nil
+	}
+	return node.Content
+}
+
+// scalar verifica que el nodo sea un escalar con alguno de los tags indicados
+func (l *loader) scalar(node *yaml.Node, field, expected string, tags ...string) bool {
+	if node.Kind == yaml.ScalarNode && contains(tags, node.Tag) {
+		return true
+	}
+	l.fail(node, field, "expected %s, got %s", expected, describe(node))
+	return false
+}
+
+// str retorna un texto ("" si el nodo no existe)
+func (l *loader) str(node *yaml.Node, field string) string {
+	if node == nil || !l.scalar(node, field, "a string", "!!str") {
+		return ""
+	}
+	return node.Value
+}
+
+// oneOf retorna un texto que debe ser uno de los valores indicados
+func (l *loader) oneOf(node *yaml.Node, field string, values []string) string {
+	s := l.str(node, field)
+	if s != "" && !contains(values, s) {
+		l.fail(node, field, "unknown value %q (expected one of %s)", s, strings.Join(values, ", "))
+		return ""
+	}
+	return s
+}
+
+// boolean retorna un booleano
+func (l *loader) boolean(node *yaml.Node, field string) bool {
+	if !l.scalar(node, field, "a boolean", "!!bool") {
+		return false
+	}
+	b, _ := strconv.ParseBool(strings.ToLower(node.Value))
+	return b
+}
+
+// integer retorna un entero
+func (l *loader) integer(node *yaml.Node, field string) int {
+	if !l.scalar(node, field, "an integer", "!!int") {
+		return 0
+	}
+	var n int
+	if err := node.Decode(&n); err != nil {
+		l.fail(node, field, "invalid integer %q", node.Value)
+	}
+	return n
+}
+
+// nonNegative retorna un entero mayor o igual que cero
+func (l *loader) nonNegative(node *yaml.Node, field string) int {
+	n := l.integer(node, field)
+	if n < 0 {
+		l.fail(node, field, "must be >= 0")
+		return 0
+	}
+	return n
+}
+
+// number retorna un número entero o decimal, y si es válido
+func (l *loader) number(node *yaml.Node, field string) (float64, bool) {
+	if !l.scalar(node, field, "a number", "!!int", "!!float") {
+		return 0, false
+	}
+	var f float64
+	if err := node.Decode(&f); err != nil {
+		l.fail(node, field, "invalid number %q", node.Value)
+		return 0, false
+	}
+	return f, true
+}
+
+// strings retorna una lista de textos como []interface{}, la forma en que
+// las políticas leen su metadata
+func (l *loader) strings(node *yaml.Node, field string) []interface{} {
+	items := make([]interface{}, 0)
+	for i, item := range l.sequence(node, field) {
+		if s := l.str(item, fmt.Sprintf("%s[%d]", field, i)); s != "" {
+			items = append(items, s)
+		}
+	}
+	return items
+}
+
+// patterns retorna una lista de patrones de rutas válidos (ver pkg/glob)
+func (l *loader) patterns(node *yaml.Node, field string) []interface{} {
+	items := make([]interface{}, 0)
+	for i, item := range l.sequence(node, field) {
+		itemField := fmt.Sprintf("%s[%d]", field, i)
+		s := l.str(item, itemField)
+		if s == "" {
+			continue
+		}
+		if err := glob.Validate(strings.TrimPrefix(s, "!")); err != nil {
+			l.fail(item, itemField, "%v", err)
+			continue
+		}
+		items = append(items, s)
+	}
+	return items
+}
+
+// freeform retorna un mapping sin esquema (nil si el nodo no existe)
+func (l *loader) freeform(node *yaml.Node, field string) map[string]interface{} {
+	if node == nil {
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		l.fail(node, field, "expected a mapping, got %s", describe(node))
+		return nil
+	}
+	values, _ := l.decode(node, field).(map[string]interface{})
+	return values
+}
+
+// decode convierte un nodo sin esquema en valores de Go
+func (l *loader) decode(node *yaml.Node, field string) interface{} {
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		l.fail(node, field, "%v", err)
+	}
+	return v
+}
+
+// describe nombra el tipo de un nodo para los mensajes de error
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.AliasNode:
+		return "an alias"
+	}
+	switch node.Tag {
+	case "!!str":
+		return fmt.Sprintf("string %q", node.Value)
+	case "!!null":
+		return "null"
+	}
+	return fmt.Sprintf("%s %s", strings.TrimPrefix(node.Tag, "!!"), node.Value)
+}
+
+// contains indica si values contiene s
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
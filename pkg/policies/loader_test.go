@@ -0,0 +1,222 @@
+package policies
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// writePolicies escribe un archivo de políticas temporal y retorna su ruta
+func writePolicies(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "policies.yaml")
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadFileSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "unknown top-level field",
+			content: "gate:\n  - id: coverage\n",
+			want:    `1:1: policies file: unknown field "gate" (expected one of policies, gates, constraints)`,
+		},
+		{
+			name:    "unknown policy field",
+			content: "policies:\n  - id: p\n    typ: rule\n",
+			want:    `3:5: policies[0]: unknown field "typ"`,
+		},
+		{
+			name:    "repeated field",
+			content: "policies:\n  - id: p\n    id: q\n",
+			want:    `3:5: policies[0]: duplicate field "id"`,
+		},
+		{
+			name:    "list expected",
+			content: "policies: {}\n",
+			want:    "1:11: policies: expected a list, got a mapping",
+		},
+		{
+			name:    "boolean expected",
+			content: "policies:\n  - id: p\n    enabled: \"yes\"\n",
+			want:    `3:14: policies[0].enabled: expected a boolean, got string "yes"`,
+		},
+		{
+			name:    "integer expected",
+			content: "constraints:\n  max_retries: three\n",
+			want:    `2:16: constraints.max_retries: expected an integer, got string "three"`,
+		},
+		{
+			name:    "number expected",
+			content: "gates:\n  - id: coverage\n    threshold: [80]\n",
+			want:    "3:16: gates[0].threshold: expected a number, got a list",
+		},
+		{
+			name:    "missing policy id",
+			content: "policies:\n  - name: x\n",
+			want:    "2:5: policies[0].id: required",
+		},
+		{
+			name:    "duplicate policy id",
+			content: "policies:\n  - id: p\n  - id: p\n",
+			want:    `3:5: policies[1].id: duplicate policy "p"`,
+		},
+		{
+			name:    "duplicate gate id",
+			content: "gates:\n  - id: coverage\n  - id: coverage\n",
+			want:    `3:9: gates[1].id: duplicate gate "coverage"`,
+		},
+		{
+			name:    "unknown gate",
+			content: "gates:\n  - id: speed\n",
+			want:    `2:9: gates[0].id: unknown gate "speed" (expected one of fmt-lint, tests-pass, coverage`,
+		},
+		{
+			name:    "unknown rule action",
+			content: "policies:\n  - id: p\n    rules:\n      - condition: 'true'\n        action: block\n",
+			want:    `5:17: policies[0].rules[0].action: unknown value "block" (expected one of allow, deny, warn)`,
+		},
+		{
+			name:    "coverage above 100",
+			content: "gates:\n  - id: coverage\n    threshold: 120\n",
+			want:    "3:16: gates[0].threshold: must be between 0 and 100",
+		},
+		{
+			name:    "coverage below 0",
+			content: "gates:\n  - id: coverage\n    threshold: -0.5\n",
+			want:    "3:16: gates[0].threshold: must be between 0 and 100",
+		},
+		{
+			name:    "threshold on a gate without one",
+			content: "gates:\n  - id: risk-review\n    threshold: 5\n",
+			want:    `3:16: gates[0].threshold: gate "risk-review" has no threshold`,
+		},
+		{
+			name:    "negative constraint",
+			content: "constraints:\n  max_file_changes: -1\n",
+			want:    "2:21: constraints.max_file_changes: must be >= 0",
+		},
+		{
+			name:    "zero execution time",
+			content: "constraints:\n  max_execution_time_seconds: 0\n",
+			want:    "2:31: constraints.max_execution_time_seconds: must be greater than 0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writePolicies(t, tt.content)
+			engine, err := LoadFile(file)
+			if err == nil {
+				t.Fatalf("LoadFile() = %v, want an error", engine)
+			}
+			if want := file + ":" + tt.want; !strings.Contains(err.Error(), want) {
+				t.Errorf("LoadFile() error = %v, want %q", err, want)
+			}
+		})
+	}
+}
+
+func TestLoadFileReportsEveryErrorInFileOrder(t *testing.T) {
+	// Las restricciones se validan después de las políticas, pero van antes en
+	// el archivo
+	file := writePolicies(t, "constraints:\n  max_retries: -1\npolicies:\n  - id: p\n    enabled: 1\n")
+	_, err := LoadFile(file)
+	if err == nil {
+		t.Fatal("LoadFile() should fail")
+	}
+	want := file + ":2:16: constraints.max_retries: must be >= 0\n" +
+		file + ":5:14: policies[0].enabled: expected a boolean, got int 1"
+	if err.Error() != want {
+		t.Errorf("LoadFile() error =\n%v\nwant\n%s", err, want)
+	}
+
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("LoadFile() error %T does not wrap a *SchemaError", err)
+	}
+	if schemaErr.File != file || schemaErr.Line != 2 || schemaErr.Column != 16 || schemaErr.Field != "constraints.max_retries" {
+		t.Errorf("first error = %+v, want constraints.max_retries at 2:16", schemaErr)
+	}
+}
+
+func TestLoadFileConstraints(t *testing.T) {
+	engine, err := LoadFile(writePolicies(t, `
+gates:
+  - id: coverage
+    threshold: 85.5
+    required: false
+constraints:
+  max_retries: 2
+  max_execution_time_seconds: 90
+  max_file_changes: 1
+  required_approvals_for_high_risk: 2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Los reintentos se limitan y el tiempo máximo solo se fija si falta
+	task := &types.Task{MaxRetries: 5}
+	engine.ApplyConstraints(task)
+	if task.MaxRetries != 2 || task.Constraints["max_execution_time_seconds"] != 90.0 {
+		t.Errorf("ApplyConstraints() = %d retries and %v seconds, want 2 and 90", task.MaxRetries, task.Constraints["max_execution_time_seconds"])
+	}
+	own := &types.Task{MaxRetries: 1, Constraints: map[string]interface{}{"max_execution_time_seconds": 30}}
+	engine.ApplyConstraints(own)
+	if own.MaxRetries != 1 || own.Constraints["max_execution_time_seconds"] != 30 {
+		t.Errorf("ApplyConstraints() = %d retries and %v seconds, want the task's own 1 and 30", own.MaxRetries, own.Constraints["max_execution_time_seconds"])
+	}
+
+	result := &types.TaskResult{Outputs: map[string]interface{}{"files_changed": []string{"a.go", "b.go"}}}
+	verdict := engine.ValidateResult("coder", task, result)
+	if verdict.Allowed || !strings.Contains(verdict.Reason(), "2 file(s) changed, max 1") {
+		t.Errorf("ValidateResult() = %v (%s), want the file limit to reject", verdict.Allowed, verdict.Reason())
+	}
+
+	result.Outputs["risk_level"] = RiskHigh
+	if n, _ := engine.RequiredApprovals(result); n != 2 {
+		t.Errorf("RequiredApprovals() = %d, want 2", n)
+	}
+
+	for _, gate := range engine.GetGates() {
+		if gate.ID == "coverage" && (gate.Threshold != 85.5 || gate.Required) {
+			t.Errorf("coverage gate = %v%% required %v, want 85.5%% and optional", gate.Threshold, gate.Required)
+		}
+		if gate.ID == "tests-pass" && !gate.Required {
+			t.Error("tests-pass gate should keep its default")
+		}
+	}
+}
+
+func TestLoadFileDefaults(t *testing.T) {
+	engine, err := LoadFile(writePolicies(t, "# sin políticas\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(engine.GetPolicies()) != 0 || len(engine.GetGates()) != len(NewEngine().GetGates()) {
+		t.Errorf("LoadFile() of an empty file = %d policies and %d gates, want the defaults", len(engine.GetPolicies()), len(engine.GetGates()))
+	}
+
+	task := &types.Task{MaxRetries: 5}
+	engine.ApplyConstraints(task)
+	if task.MaxRetries != 5 || task.Constraints != nil {
+		t.Errorf("ApplyConstraints() without constraints changed the task: %+v", task)
+	}
+	if n, _ := engine.RequiredApprovals(&types.TaskResult{Outputs: map[string]interface{}{"risk_level": RiskHigh}}); n != 1 {
+		t.Errorf("RequiredApprovals() = %d, want the default 1", n)
+	}
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil || !strings.Contains(err.Error(), "failed to read policies") {
+		t.Errorf("LoadFile() of a missing file = %v, want a read error", err)
+	}
+}
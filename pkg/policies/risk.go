@@ -0,0 +1,100 @@
+package policies
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// Niveles de riesgo de un resultado
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+// Umbrales de archivos modificados a partir de los que sube el riesgo
+const (
+	mediumRiskFiles = 5
+	highRiskFiles   = 20
+)
+
+// highRiskPaths son archivos y directorios cuyo cambio afecta al build, las
+// dependencias, el despliegue o los datos
+var highRiskPaths = []string{
+	"go.mod", "go.sum", "Dockerfile", "Makefile",
+	".github/", "deploy/", "migrations/",
+}
+
+// AssessRisk calcula el nivel de riesgo de un resultado y sus motivos. Manda
+// el "risk_level" que informe el agente; si no, se estima a partir de los
+// archivos modificados y de los hallazgos de auditoría.
+func AssessRisk(result *types.TaskResult) (string, []string) {
+	if level, ok := result.Outputs["risk_level"].(string); ok && level != "" {
+		return level, []string{"risk level reported by the agent"}
+	}
+
+	reasons := make([]string, 0)
+	level := RiskLow
+
+	files := stringList(result.Outputs["files_changed"])
+	for _, file := range files {
+		if sensitivePath(file) {
+			reasons = append(reasons, fmt.Sprintf("changes %s", file))
+			level = RiskHigh
+		}
+	}
+	switch {
+	case len(files) >= highRiskFiles:
+		reasons = append(reasons, fmt.Sprintf("%d files changed", len(files)))
+		level = RiskHigh
+	case len(files) >= mediumRiskFiles && level == RiskLow:
+		reasons = append(reasons, fmt.Sprintf("%d files changed", len(files)))
+		level = RiskMedium
+	}
+
+	if findings, ok := result.Outputs["findings"].([]types.AuditFinding); ok {
+		for _, finding := range findings {
+			if finding.Severity == types.SeverityCritical || finding.Severity == types.SeverityHigh {
+				reasons = append(reasons, fmt.Sprintf("%s %s finding: %s", finding.Severity, finding.Category, finding.Message))
+				level = RiskHigh
+			}
+		}
+	}
+	return level, reasons
+}
+
+// sensitivePath indica si el archivo está entre las rutas de alto riesgo
+func sensitivePath(file string) bool {
+	file = path.Clean(strings.TrimPrefix(file, "./"))
+	for _, p := range highRiskPaths {
+		if strings.HasSuffix(p, "/") {
+			if strings.HasPrefix(file, p) || strings.Contains(file, "/"+p) {
+				return true
+			}
+		} else if path.Base(file) == p {
+			return true
+		}
+	}
+	return false
+}
+
+// stringList convierte un output de lista ([]string o, tras pasar por JSON,
+// []interface{}) en []string
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
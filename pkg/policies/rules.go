@@ -0,0 +1,162 @@
+package policies
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/nanochip/multi-agent/pkg/expr"
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// Acciones de una regla
+const (
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+	ActionWarn  = "warn"
+)
+
+// rule es una regla con su condición ya interpretada
+type rule struct {
+	types.PolicyRule
+	condition *expr.Expr
+	err       error // la condición no es válida
+}
+
+// compileRules interpreta las condiciones de las reglas de una política
+func compileRules(policy types.Policy) []rule {
+	rules := make([]rule, len(policy.Rules))
+	for i, r := range policy.Rules {
+		rules[i].PolicyRule = r
+		rules[i].condition, rules[i].err = expr.Parse(r.Condition)
+	}
+	return rules
+}
+
+// onResult indica si la regla lee el resultado. Esas reglas se evalúan en
+// ValidateResult; las que solo leen task y agent, en AllowTask.
+func (r rule) onResult() bool {
+	if r.err != nil {
+		return false
+	}
+	for _, name := range r.condition.Idents() {
+		if name != "task" && name != "agent" {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateRules añade a v las reglas de la política i. Sin resultado se
+// evalúan las reglas que solo leen la tarea; con resultado, el resto. Gana
+// la primera regla allow o deny que se cumple (en orden, también entre
+// fases); las warn avisan y siguen. Si la condición no puede evaluarse una
+// regla deny rechaza y una warn avisa.
+func (e *Engine) evaluateRules(v *Verdict, i int, vars func() map[string]interface{}, result *types.TaskResult) {
+	policy := e.policies[i]
+	decided := ""
+	for n, r := range e.rules[i] {
+		// Las reglas de la tarea ya se aplicaron en AllowTask; al validar
+		// el resultado solo cuentan sus allow, para respetar el orden
+		if r.onResult() != (result != nil) && (result == nil || r.Action != ActionAllow) {
+			continue
+		}
+		check := Check{Kind: CheckRule, Policy: policy.ID, ID: fmt.Sprintf("rules[%d]", n)}
+		if decided != "" {
+			check.Outcome, check.Reason = OutcomeSkip, "not evaluated, decided by "+decided
+			v.add(check)
+			continue
+		}
+
+		matched, err := false, r.err
+		if err == nil {
+			matched, err = r.condition.EvalBool(vars())
+		}
+		message := r.Message
+		if message == "" {
+			message = r.Condition
+		}
+		switch {
+		case err != nil:
+			check.Outcome, check.Reason = OutcomePass, fmt.Sprintf("invalid condition %q: %v", r.Condition, err)
+			if r.Action == ActionDeny {
+				check.Outcome = OutcomeFail
+			} else if r.Action == ActionWarn {
+				check.Outcome = OutcomeWarn
+			}
+		case !matched:
+			check.Outcome, check.Reason = OutcomePass, "condition not met: "+r.Condition
+		case r.Action == ActionDeny:
+			check.Outcome, check.Reason = OutcomeFail, message
+		case r.Action == ActionWarn:
+			check.Outcome, check.Reason = OutcomeWarn, message
+		case r.Action == ActionAllow:
+			check.Outcome, check.Reason = OutcomePass, "allowed: "+message
+		default:
+			check.Outcome, check.Reason = OutcomeSkip, fmt.Sprintf("unknown action %q", r.Action)
+		}
+		v.add(check)
+
+		// La primera regla allow o deny que se cumple decide la política
+		if err == nil && matched && (r.Action == ActionAllow || r.Action == ActionDeny) {
+			decided = check.Name()
+		}
+	}
+}
+
+// ruleVars retorna las variables de las condiciones: task (con agent), agent,
+// result (con sus outputs) y, sueltos, los outputs del resultado y los inputs
+// de la tarea
+func ruleVars(agentID string, task *types.Task, result *types.TaskResult) map[string]interface{} {
+	vars := make(map[string]interface{})
+	if task != nil {
+		for k, v := range task.Inputs {
+			vars[k] = jsonValue(v)
+		}
+	}
+	var outputs map[string]interface{}
+	if result != nil {
+		outputs, _ = jsonValue(result.Outputs).(map[string]interface{})
+		for k, v := range outputs {
+			vars[k] = v
+		}
+	}
+
+	taskVars, _ := jsonValue(task).(map[string]interface{})
+	if taskVars != nil {
+		taskVars["agent"] = agentID
+	}
+	vars["task"] = taskVars
+	vars["agent"] = agentID
+
+	var resultVars map[string]interface{}
+	if result != nil {
+		resultVars = map[string]interface{}{
+			"success":  result.Success,
+			"state":    string(result.State),
+			"error":    result.Error,
+			"duration": result.Duration.Seconds(),
+		}
+		for k, v := range outputs {
+			if _, ok := resultVars[k]; !ok {
+				resultVars[k] = v
+			}
+		}
+		resultVars["outputs"] = outputs
+	}
+	vars["result"] = resultVars
+	return vars
+}
+
+// jsonValue convierte un valor a su forma JSON genérica (mapas, listas,
+// float64...), la que leen las condiciones
+func jsonValue(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,196 @@
+package policies
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// Fases de un veredicto
+const (
+	PhaseTask   = "task"   // AllowTask, antes de ejecutar la tarea
+	PhaseResult = "result" // ValidateResult, con el resultado del agente
+)
+
+// Resultados de una comprobación
+const (
+	OutcomePass = "pass"
+	OutcomeFail = "fail"
+	OutcomeWarn = "warn"
+	OutcomeSkip = "skip" // no se evaluó
+)
+
+// Tipos de comprobación
+const (
+	CheckPolicy     = "policy"
+	CheckPath       = "path"
+	CheckRule       = "rule"
+	CheckGate       = "gate"
+	CheckConstraint = "constraint"
+)
+
+// EvidenceSource es el Source de la evidencia que guarda un veredicto
+const EvidenceSource = "policy"
+
+// Verdict es la decisión de las políticas sobre una tarea o un resultado,
+// con cada política, regla y gate evaluados
+type Verdict struct {
+	Phase   string    `json:"phase"`
+	Agent   string    `json:"agent"`
+	Allowed bool      `json:"allowed"`
+	Checks  []Check   `json:"checks"`
+	Time    time.Time `json:"time"`
+}
+
+// Check es una comprobación del veredicto
+type Check struct {
+	Kind    string `json:"kind"`             // policy, path, rule, gate o constraint
+	Policy  string `json:"policy,omitempty"` // política a la que pertenece
+	ID      string `json:"id"`               // política, rules[i], forbidden_paths, gate o restricción
+	Outcome string `json:"outcome"`
+	Reason  string `json:"reason"`
+}
+
+// Name identifica la comprobación en los mensajes
+func (c Check) Name() string {
+	switch {
+	case c.Kind == CheckGate:
+		return "gate " + c.ID
+	case c.Policy != "" && c.Policy != c.ID:
+		return c.Policy + "." + c.ID
+	}
+	return c.ID
+}
+
+// subject es a quién se atribuye el motivo en Reason y Warnings
+func (c Check) subject() string {
+	if c.Policy != "" {
+		return c.Policy
+	}
+	return c.Name()
+}
+
+// newVerdict crea un veredicto favorable
+func newVerdict(phase, agentID string) *Verdict {
+	return &Verdict{Phase: phase, Agent: agentID, Allowed: true, Checks: make([]Check, 0), Time: time.Now()}
+}
+
+// add añade una comprobación; una que falla rechaza
+func (v *Verdict) add(check Check) {
+	if check.Outcome == OutcomeFail {
+		v.Allowed = false
+	}
+	v.Checks = append(v.Checks, check)
+}
+
+// addPolicy añade la comprobación de una política y retorna su posición
+// para resumirla con summarize tras sus reglas, o -1 si no se aplica
+func (v *Verdict) addPolicy(policy types.Policy, agentID string) int {
+	check := Check{Kind: CheckPolicy, Policy: policy.ID, ID: policy.ID, Outcome: OutcomeSkip}
+	owner, _ := policy.Metadata["agent_id"].(string)
+	switch {
+	case !policy.Enabled:
+		check.Reason = "disabled"
+	case owner != "" && owner != agentID:
+		check.Reason = fmt.Sprintf("applies only to agent %s", owner)
+	default:
+		check.Outcome = OutcomePass
+		check.Reason = "applies to all agents"
+		if owner != "" {
+			check.Reason = "applies to agent " + owner
+		}
+	}
+	v.add(check)
+	if check.Outcome == OutcomeSkip {
+		return -1
+	}
+	return len(v.Checks) - 1
+}
+
+// summarize da a la política de la posición i el peor resultado de sus
+// comprobaciones
+func (v *Verdict) summarize(i int) {
+	policy := v.Checks[i].Policy
+	counts := map[string]int{}
+	for _, check := range v.Checks[i+1:] {
+		if check.Policy == policy {
+			counts[check.Outcome]++
+		}
+	}
+	switch {
+	case counts[OutcomeFail] > 0:
+		v.Checks[i].Outcome = OutcomeFail
+	case counts[OutcomeWarn] > 0:
+		v.Checks[i].Outcome = OutcomeWarn
+	}
+	if n := len(v.Checks) - i - 1; n == 0 {
+		v.Checks[i].Reason += ", nothing to check"
+	} else {
+		v.Checks[i].Reason += fmt.Sprintf(", %d failed and %d warned of %d check(s)", counts[OutcomeFail], counts[OutcomeWarn], n)
+	}
+}
+
+// Reasons retorna los motivos del rechazo
+func (v *Verdict) Reasons() []string {
+	return v.messages(OutcomeFail)
+}
+
+// Warnings retorna los avisos, que no rechazan
+func (v *Verdict) Warnings() []string {
+	return v.messages(OutcomeWarn)
+}
+
+// Reason retorna los motivos del rechazo en una línea
+func (v *Verdict) Reason() string {
+	return strings.Join(v.Reasons(), "; ")
+}
+
+// messages retorna los motivos de las comprobaciones (no de las políticas,
+// que los resumen) con un resultado
+func (v *Verdict) messages(outcome string) []string {
+	messages := make([]string, 0)
+	for _, check := range v.Checks {
+		if check.Kind != CheckPolicy && check.Outcome == outcome {
+			messages = append(messages, check.subject()+": "+check.Reason)
+		}
+	}
+	return messages
+}
+
+// Evidence retorna el veredicto como evidencia de un resultado
+func (v *Verdict) Evidence() types.Evidence {
+	content, _ := json.Marshal(v)
+	description := fmt.Sprintf("Policy verdict (%s): allowed", v.Phase)
+	if !v.Allowed {
+		description = fmt.Sprintf("Policy verdict (%s): denied: %s", v.Phase, v.Reason())
+	}
+	return types.Evidence{
+		Type:        "report",
+		Source:      EvidenceSource,
+		Content:     content,
+		Timestamp:   v.Time,
+		Description: description,
+	}
+}
+
+// VerdictsFromResult retorna los veredictos guardados como evidencia en un
+// resultado, en el orden en que se emitieron
+func VerdictsFromResult(result *types.TaskResult) []*Verdict {
+	verdicts := make([]*Verdict, 0)
+	if result == nil {
+		return verdicts
+	}
+	for _, evidence := range result.Evidence {
+		if evidence.Source != EvidenceSource {
+			continue
+		}
+		var v Verdict
+		if err := json.Unmarshal(evidence.Content, &v); err == nil {
+			verdicts = append(verdicts, &v)
+		}
+	}
+	return verdicts
+}
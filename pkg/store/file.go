@@ -0,0 +1,639 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// FileStore implementa Store sobre el sistema de archivos.
+//
+// Estructura en disco (normalmente bajo .multi-agent/):
+//
+//	runs/<id>.json       ejecuciones (objetivo, estado y veredicto)
+//	tasks/<id>.json      estado de cada tarea
+//	runtasks/<id>.txt    IDs de las tareas de cada ejecución, uno por línea
+//	results/<id>.json    resultado de cada tarea (sin evidencia)
+//	evidence/<id>.json   evidencia asociada a cada tarea
+//	decisions.jsonl      memoria de decisiones, una por línea
+//	journal.jsonl        journal write-ahead de transiciones de estado
+//	deadletters/<id>.json  tareas fallidas sin recuperación (dead-letter queue)
+//	schedules/<id>.json  objetivos recurrentes (cron)
+//	control.jsonl        órdenes de control (cancelar, pausar, reanudar)
+type FileStore struct {
+	root    string
+	mu      sync.Mutex
+	indexed map[string]string // ejecución de cada tarea ya añadida al índice
+}
+
+// NewFileStore crea un store en disco bajo el directorio root
+func NewFileStore(root string) (*FileStore, error) {
+	for _, dir := range []string{"runs", "tasks", "results", "evidence", "deadletters", "schedules"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create store dir: %w", err)
+		}
+	}
+	s := &FileStore{root: root, indexed: make(map[string]string)}
+	if err := s.buildRunIndex(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Open abre el store por defecto de un repositorio (<repo>/.multi-agent)
+func Open(repoPath string) (*FileStore, error) {
+	return NewFileStore(filepath.Join(repoPath, ".multi-agent"))
+}
+
+// Root retorna el directorio raíz del store
+func (s *FileStore) Root() string {
+	return s.root
+}
+
+// SaveTask guarda el estado de una tarea y la añade al índice de su ejecución
+func (s *FileStore) SaveTask(task *types.Task) error {
+	if err := s.indexTask(task); err != nil {
+		return err
+	}
+	return s.writeJSON("tasks", task.ID, task)
+}
+
+// GetTask retorna una tarea por ID
+func (s *FileStore) GetTask(id string) (*types.Task, error) {
+	var task types.Task
+	if err := s.readJSON("tasks", id, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ListTasks retorna todas las tareas ordenadas por fecha de creación
+func (s *FileStore) ListTasks() ([]*types.Task, error) {
+	entries, err := os.ReadDir(filepath.Join(s.root, "tasks"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	tasks := make([]*types.Task, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		task, err := s.GetTask(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	sortTasks(tasks)
+	return tasks, nil
+}
+
+// ListRunTasks retorna las tareas de una ejecución ordenadas por fecha de
+// creación, a partir del índice de la ejecución
+func (s *FileStore) ListRunTasks(runID string) ([]*types.Task, error) {
+	path, err := s.runIndexPath(runID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	data, err := os.ReadFile(path)
+	s.mu.Unlock()
+	if os.IsNotExist(err) {
+		return []*types.Task{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run index %s: %w", runID, err)
+	}
+
+	// El índice puede repetir IDs (varios procesos) o conservar los de una
+	// tarea que cambió de ejecución; cuenta lo que dice cada tarea
+	tasks := make([]*types.Task, 0)
+	seen := make(map[string]bool)
+	for _, id := range strings.Fields(string(data)) {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		task, err := s.GetTask(id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if task.RunID == runID {
+			tasks = append(tasks, task)
+		}
+	}
+	sortTasks(tasks)
+	return tasks, nil
+}
+
+// indexTask añade la tarea al índice de su ejecución la primera vez que este
+// proceso la guarda en ella. El ID se escribe antes que la tarea para que
+// una tarea guardada siempre aparezca en el índice.
+func (s *FileStore) indexTask(task *types.Task) error {
+	if task.RunID == "" {
+		return nil
+	}
+	path, err := s.runIndexPath(task.RunID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.indexed[task.ID] == task.RunID {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to index task %s: %w", task.ID, err)
+	}
+	if err := appendLine(path, task.ID); err != nil {
+		return fmt.Errorf("failed to index task %s: %w", task.ID, err)
+	}
+	s.indexed[task.ID] = task.RunID
+	return nil
+}
+
+// buildRunIndex crea el índice de ejecuciones de un store escrito antes de
+// que existiera. Se construye aparte y se publica con un rename para que un
+// fallo a medias se repita en la próxima apertura.
+func (s *FileStore) buildRunIndex() error {
+	dir := filepath.Join(s.root, "runtasks")
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+
+	tasks, err := s.ListTasks()
+	if err != nil {
+		return err
+	}
+	tmp := dir + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return fmt.Errorf("failed to build run index: %w", err)
+	}
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return fmt.Errorf("failed to build run index: %w", err)
+	}
+	for _, task := range tasks {
+		if task.RunID == "" {
+			continue
+		}
+		path, err := s.runIndexPath(task.RunID)
+		if err != nil {
+			continue
+		}
+		if err := appendLine(filepath.Join(tmp, filepath.Base(path)), task.ID); err != nil {
+			return fmt.Errorf("failed to build run index: %w", err)
+		}
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return fmt.Errorf("failed to build run index: %w", err)
+	}
+	return nil
+}
+
+// runIndexPath construye la ruta del índice de tareas de una ejecución
+func (s *FileStore) runIndexPath(runID string) (string, error) {
+	path, err := s.path("runtasks", runID)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(path, ".json") + ".txt", nil
+}
+
+// appendLine añade una línea al final de un archivo, creándolo si no existe
+func appendLine(path, line string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SaveDeadLetter guarda una tarea de la dead-letter queue
+func (s *FileStore) SaveDeadLetter(letter *types.DeadLetter) error {
+	return s.writeJSON("deadletters", letter.Task.ID, letter)
+}
+
+// GetDeadLetter retorna la entrada de la dead-letter queue de una tarea
+func (s *FileStore) GetDeadLetter(taskID string) (*types.DeadLetter, error) {
+	var letter types.DeadLetter
+	if err := s.readJSON("deadletters", taskID, &letter); err != nil {
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// ListDeadLetters retorna la dead-letter queue ordenada por fecha
+func (s *FileStore) ListDeadLetters() ([]*types.DeadLetter, error) {
+	entries, err := os.ReadDir(filepath.Join(s.root, "deadletters"))
+	if os.IsNotExist(err) {
+		return []*types.DeadLetter{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	letters := make([]*types.DeadLetter, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		letter, err := s.GetDeadLetter(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	sortDeadLetters(letters)
+	return letters, nil
+}
+
+// SaveSchedule guarda un objetivo recurrente
+func (s *FileStore) SaveSchedule(schedule *types.Schedule) error {
+	return s.writeJSON("schedules", schedule.ID, schedule)
+}
+
+// GetSchedule retorna un objetivo recurrente
+func (s *FileStore) GetSchedule(id string) (*types.Schedule, error) {
+	var schedule types.Schedule
+	if err := s.readJSON("schedules", id, &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// ListSchedules retorna los objetivos recurrentes ordenados por ID
+func (s *FileStore) ListSchedules() ([]*types.Schedule, error) {
+	entries, err := os.ReadDir(filepath.Join(s.root, "schedules"))
+	if os.IsNotExist(err) {
+		return []*types.Schedule{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+
+	// os.ReadDir ya retorna las entradas ordenadas por nombre
+	schedules := make([]*types.Schedule, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		schedule, err := s.GetSchedule(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+// DeleteSchedule elimina un objetivo recurrente
+func (s *FileStore) DeleteSchedule(id string) error {
+	path, err := s.path("schedules", id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to delete schedule %s: %w", id, err)
+	}
+	return nil
+}
+
+// SaveRun guarda el estado de una ejecución
+func (s *FileStore) SaveRun(run *types.Run) error {
+	return s.writeJSON("runs", run.ID, run)
+}
+
+// GetRun retorna una ejecución por ID
+func (s *FileStore) GetRun(id string) (*types.Run, error) {
+	var run types.Run
+	if err := s.readJSON("runs", id, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListRuns retorna todas las ejecuciones ordenadas por fecha de inicio
+func (s *FileStore) ListRuns() ([]*types.Run, error) {
+	entries, err := os.ReadDir(filepath.Join(s.root, "runs"))
+	if os.IsNotExist(err) {
+		return []*types.Run{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	runs := make([]*types.Run, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		run, err := s.GetRun(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	sortRuns(runs)
+	return runs, nil
+}
+
+// SaveResult guarda el resultado de una tarea; la evidencia se guarda aparte
+func (s *FileStore) SaveResult(result *types.TaskResult) error {
+	stored := *result
+	stored.Evidence = nil
+	if err := s.writeJSON("results", result.TaskID, &stored); err != nil {
+		return err
+	}
+	if len(result.Evidence) > 0 {
+		return s.SaveEvidence(result.TaskID, result.Evidence)
+	}
+	return nil
+}
+
+// GetResult retorna el resultado de una tarea junto con su evidencia
+func (s *FileStore) GetResult(taskID string) (*types.TaskResult, error) {
+	var result types.TaskResult
+	if err := s.readJSON("results", taskID, &result); err != nil {
+		return nil, err
+	}
+	evidence, err := s.GetEvidence(taskID)
+	if err != nil && err != ErrNotFound {
+		return nil, err
+	}
+	result.Evidence = evidence
+	return &result, nil
+}
+
+// SaveEvidence guarda la evidencia de una tarea
+func (s *FileStore) SaveEvidence(taskID string, evidence []types.Evidence) error {
+	return s.writeJSON("evidence", taskID, evidence)
+}
+
+// GetEvidence retorna la evidencia de una tarea
+func (s *FileStore) GetEvidence(taskID string) ([]types.Evidence, error) {
+	var evidence []types.Evidence
+	if err := s.readJSON("evidence", taskID, &evidence); err != nil {
+		return nil, err
+	}
+	return evidence, nil
+}
+
+// AppendDecisions añade decisiones al log de decisiones
+func (s *FileStore) AppendDecisions(decisions ...types.Decision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(s.root, "decisions.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open decisions log: %w", err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, decision := range decisions {
+		if err := enc.Encode(decision); err != nil {
+			return fmt.Errorf("failed to write decision: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListDecisions retorna todas las decisiones registradas
+func (s *FileStore) ListDecisions() ([]types.Decision, error) {
+	decisions := make([]types.Decision, 0)
+	err := s.readLines("decisions.jsonl", func(line []byte) error {
+		var decision types.Decision
+		if err := json.Unmarshal(line, &decision); err != nil {
+			return fmt.Errorf("failed to parse decision: %w", err)
+		}
+		decisions = append(decisions, decision)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decisions, nil
+}
+
+// AppendJournal añade entradas al journal y las sincroniza a disco
+func (s *FileStore) AppendJournal(entries ...JournalEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.root, 0755); err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(s.root, "journal.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to write journal entry: %w", err)
+		}
+	}
+	// El journal es write-ahead: debe llegar a disco antes de aplicar la transición
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+	return nil
+}
+
+// ReadJournal retorna todas las entradas del journal en orden.
+// Una última línea truncada (proceso muerto a mitad de escritura) se ignora.
+func (s *FileStore) ReadJournal() ([]JournalEntry, error) {
+	entries := make([]JournalEntry, 0)
+	var pending error
+	err := s.readLines("journal.jsonl", func(line []byte) error {
+		if pending != nil {
+			return pending
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			pending = fmt.Errorf("failed to parse journal entry: %w", err)
+			return nil
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// AppendControl añade órdenes de control para el orchestrator en ejecución
+func (s *FileStore) AppendControl(requests ...ControlRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(s.root, "control.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open control log: %w", err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, request := range requests {
+		if err := enc.Encode(request); err != nil {
+			return fmt.Errorf("failed to write control request: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReadControl retorna las órdenes de control escritas a partir de offset
+// (en bytes; 0 = desde el principio) y el offset desde el que seguir
+// leyendo. Una última línea a medio escribir se leerá en la próxima consulta
+// y una línea inválida se descarta.
+func (s *FileStore) ReadControl(offset int64) ([]ControlRequest, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := make([]ControlRequest, 0)
+	f, err := os.Open(filepath.Join(s.root, "control.jsonl"))
+	if os.IsNotExist(err) {
+		return requests, offset, nil
+	}
+	if err != nil {
+		return nil, offset, fmt.Errorf("failed to open control log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, fmt.Errorf("failed to read control log: %w", err)
+	}
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// Sin salto de línea final: la orden aún se está escribiendo
+			return requests, offset, nil
+		}
+		if err != nil {
+			return nil, offset, fmt.Errorf("failed to read control log: %w", err)
+		}
+		offset += int64(len(line))
+
+		var request ControlRequest
+		if len(bytes.TrimSpace(line)) > 0 && json.Unmarshal(line, &request) == nil {
+			requests = append(requests, request)
+		}
+	}
+}
+
+// readLines recorre un archivo JSONL; un archivo inexistente se trata como vacío
+func (s *FileStore) readLines(name string, fn func(line []byte) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(filepath.Join(s.root, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// writeJSON escribe un documento de forma atómica (archivo temporal + rename)
+func (s *FileStore) writeJSON(kind, id string, v interface{}) error {
+	path, err := s.path(kind, id)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s %s: %w", kind, id, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// El directorio puede haber desaparecido (p. ej. un checkout que limpia el worktree)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to write %s %s: %w", kind, id, err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s %s: %w", kind, id, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write %s %s: %w", kind, id, err)
+	}
+	return nil
+}
+
+// readJSON lee un documento; retorna ErrNotFound si no existe
+func (s *FileStore) readJSON(kind, id string, v interface{}) error {
+	path, err := s.path(kind, id)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s %s: %w", kind, id, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s %s: %w", kind, id, err)
+	}
+	return nil
+}
+
+// path construye la ruta de un documento validando el ID
+func (s *FileStore) path(kind, id string) (string, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid id: %q", id)
+	}
+	return filepath.Join(s.root, kind, id+".json"), nil
+}
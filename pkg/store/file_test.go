@@ -0,0 +1,158 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// appendRaw añade bytes tal cual al log de control
+func appendRaw(t *testing.T, s *FileStore, data string) {
+	t.Helper()
+	f, err := os.OpenFile(filepath.Join(s.root, "control.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileStoreReadControlOffset(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests, offset, err := s.ReadControl(0); err != nil || len(requests) != 0 || offset != 0 {
+		t.Fatalf("ReadControl(0) without a log = %v, %d, %v; want nothing", requests, offset, err)
+	}
+
+	if err := s.AppendControl(ControlRequest{Action: "pause", Target: "run-1"}, ControlRequest{Action: "cancel", Target: "task-1"}); err != nil {
+		t.Fatal(err)
+	}
+	requests, offset, err := s.ReadControl(0)
+	if err != nil || len(requests) != 2 || requests[1].Target != "task-1" {
+		t.Fatalf("ReadControl(0) = %v, %v; want both requests", requests, err)
+	}
+
+	// Solo se leen las órdenes nuevas
+	if err := s.AppendControl(ControlRequest{Action: "resume", Target: "run-1"}); err != nil {
+		t.Fatal(err)
+	}
+	requests, offset, err = s.ReadControl(offset)
+	if err != nil || len(requests) != 1 || requests[0].Action != "resume" {
+		t.Fatalf("ReadControl(offset) = %v, %v; want only the resume", requests, err)
+	}
+
+	// Una línea a medio escribir espera a estar completa
+	appendRaw(t, s, `{"action":"cancel",`)
+	requests, next, err := s.ReadControl(offset)
+	if err != nil || len(requests) != 0 || next != offset {
+		t.Fatalf("ReadControl() of a partial line = %v, %d, %v; want nothing at offset %d", requests, next, err, offset)
+	}
+	appendRaw(t, s, `"target":"run-2"}`+"\nnot json\n")
+	requests, next, err = s.ReadControl(offset)
+	if err != nil || len(requests) != 1 || requests[0].Target != "run-2" {
+		t.Fatalf("ReadControl() of the completed line = %v, %v; want the cancel of run-2", requests, err)
+	}
+	// La línea inválida se descarta
+	if requests, _, err := s.ReadControl(next); err != nil || len(requests) != 0 {
+		t.Errorf("ReadControl() after an invalid line = %v, %v; want nothing", requests, err)
+	}
+}
+
+func TestMemoryStoreReadControlOffset(t *testing.T) {
+	s := NewMemoryStore()
+	s.AppendControl(ControlRequest{Action: "pause", Target: "run-1"})
+	_, offset, _ := s.ReadControl(0)
+	s.AppendControl(ControlRequest{Action: "resume", Target: "run-1"})
+
+	requests, next, err := s.ReadControl(offset)
+	if err != nil || len(requests) != 1 || requests[0].Action != "resume" || next != 2 {
+		t.Errorf("ReadControl(%d) = %v, %d, %v; want only the resume", offset, requests, next, err)
+	}
+	if requests, _, _ := s.ReadControl(10); len(requests) != 0 {
+		t.Errorf("ReadControl() past the end = %v, want nothing", requests)
+	}
+}
+
+func TestFileStoreListRunTasks(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewFileStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Now()
+	save := func(id, runID string, offset time.Duration) {
+		t.Helper()
+		if err := s.SaveTask(&types.Task{ID: id, RunID: runID, CreatedAt: base.Add(offset)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save("task-b", "run-1", time.Second)
+	save("task-a", "run-1", 0)
+	save("task-c", "run-2", 0)
+	save("task-b", "run-1", time.Second) // guardar de nuevo no duplica
+	save("task-d", "", 0)
+
+	ids := func(runID string) string {
+		t.Helper()
+		tasks, err := s.ListRunTasks(runID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list := make([]string, 0, len(tasks))
+		for _, task := range tasks {
+			list = append(list, task.ID)
+		}
+		return strings.Join(list, ",")
+	}
+	if got := ids("run-1"); got != "task-a,task-b" {
+		t.Errorf("ListRunTasks(run-1) = %s, want task-a,task-b", got)
+	}
+	if got := ids("run-3"); got != "" {
+		t.Errorf("ListRunTasks() of an unknown run = %s, want nothing", got)
+	}
+
+	// Una tarea que cambia de ejecución solo aparece en la nueva
+	save("task-c", "run-1", 2*time.Second)
+	if got := ids("run-1"); got != "task-a,task-b,task-c" {
+		t.Errorf("ListRunTasks(run-1) = %s, want task-a,task-b,task-c", got)
+	}
+	if got := ids("run-2"); got != "" {
+		t.Errorf("ListRunTasks(run-2) = %s, want nothing after task-c moved", got)
+	}
+
+	// Un store anterior al índice lo construye al abrirse
+	if err := os.RemoveAll(filepath.Join(root, "runtasks")); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = NewFileStore(root); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids("run-1"); got != "task-a,task-b,task-c" {
+		t.Errorf("ListRunTasks(run-1) after rebuilding the index = %s, want task-a,task-b,task-c", got)
+	}
+}
+
+func TestMemoryStoreListRunTasks(t *testing.T) {
+	s := NewMemoryStore()
+	base := time.Now()
+	s.SaveTask(&types.Task{ID: "task-b", RunID: "run-1", CreatedAt: base.Add(time.Second)})
+	s.SaveTask(&types.Task{ID: "task-a", RunID: "run-1", CreatedAt: base})
+	s.SaveTask(&types.Task{ID: "task-c", RunID: "run-2", CreatedAt: base})
+	s.SaveTask(&types.Task{ID: "task-c", RunID: "run-1", CreatedAt: base.Add(2 * time.Second)})
+
+	tasks, err := s.ListRunTasks("run-1")
+	if err != nil || len(tasks) != 3 || tasks[0].ID != "task-a" || tasks[2].ID != "task-c" {
+		t.Errorf("ListRunTasks(run-1) = %v, %v; want task-a, task-b and task-c", tasks, err)
+	}
+	if tasks, _ := s.ListRunTasks("run-2"); len(tasks) != 0 {
+		t.Errorf("ListRunTasks(run-2) = %v, want nothing after task-c moved", tasks)
+	}
+}
@@ -0,0 +1,380 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nanochip/multi-agent/pkg/types"
+)
+
+// ErrNotFound se retorna cuando el elemento solicitado no existe en el store
+var ErrNotFound = errors.New("not found")
+
+// Store persiste tareas, resultados, evidencia y decisiones del orchestrator
+type Store interface {
+	SaveTask(task *types.Task) error
+	GetTask(id string) (*types.Task, error)
+	ListTasks() ([]*types.Task, error)
+	ListRunTasks(runID string) ([]*types.Task, error)
+
+	SaveResult(result *types.TaskResult) error
+	GetResult(taskID string) (*types.TaskResult, error)
+
+	SaveEvidence(taskID string, evidence []types.Evidence) error
+	GetEvidence(taskID string) ([]types.Evidence, error)
+
+	AppendDecisions(decisions ...types.Decision) error
+	ListDecisions() ([]types.Decision, error)
+
+	AppendJournal(entries ...JournalEntry) error
+	ReadJournal() ([]JournalEntry, error)
+
+	SaveRun(run *types.Run) error
+	GetRun(id string) (*types.Run, error)
+	ListRuns() ([]*types.Run, error)
+
+	AppendControl(requests ...ControlRequest) error
+	ReadControl(offset int64) ([]ControlRequest, int64, error)
+
+	SaveDeadLetter(letter *types.DeadLetter) error
+	GetDeadLetter(taskID string) (*types.DeadLetter, error)
+	ListDeadLetters() ([]*types.DeadLetter, error)
+
+	SaveSchedule(schedule *types.Schedule) error
+	GetSchedule(id string) (*types.Schedule, error)
+	ListSchedules() ([]*types.Schedule, error)
+	DeleteSchedule(id string) error
+}
+
+// ControlRequest es una orden para el orchestrator en ejecución (p. ej. desde
+// la CLI): cancelar, pausar, reanudar, aprobar o rechazar una tarea o una
+// ejecución
+type ControlRequest struct {
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`             // "cancel", "pause", "resume", "approve", "reject"
+	Target   string    `json:"target"`             // ID de tarea o de ejecución
+	Approver string    `json:"approver,omitempty"` // quién aprueba o rechaza
+	Comment  string    `json:"comment,omitempty"`
+}
+
+// JournalEntry registra una transición de estado antes de aplicarla (write-ahead)
+type JournalEntry struct {
+	Time    time.Time       `json:"time"`
+	TaskID  string          `json:"task_id"`
+	RunID   string          `json:"run_id,omitempty"`
+	From    types.TaskState `json:"from,omitempty"`
+	To      types.TaskState `json:"to"`
+	Task    *types.Task     `json:"task,omitempty"`    // snapshot al enviar la tarea
+	Spawned []*types.Task   `json:"spawned,omitempty"` // tareas hijas generadas al terminar
+}
+
+// MemoryStore implementa Store en memoria (sin persistencia entre procesos)
+type MemoryStore struct {
+	mu        sync.RWMutex
+	tasks     map[string]types.Task
+	runTasks  map[string]map[string]bool // IDs de las tareas de cada ejecución
+	results   map[string]types.TaskResult
+	evidence  map[string][]types.Evidence
+	decisions []types.Decision
+	journal   []JournalEntry
+	runs      map[string]types.Run
+	control   []ControlRequest
+	dead      map[string]types.DeadLetter
+	schedules map[string]types.Schedule
+}
+
+// NewMemoryStore crea un nuevo store en memoria
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tasks:     make(map[string]types.Task),
+		runTasks:  make(map[string]map[string]bool),
+		results:   make(map[string]types.TaskResult),
+		evidence:  make(map[string][]types.Evidence),
+		decisions: make([]types.Decision, 0),
+		journal:   make([]JournalEntry, 0),
+		runs:      make(map[string]types.Run),
+		dead:      make(map[string]types.DeadLetter),
+		schedules: make(map[string]types.Schedule),
+	}
+}
+
+// SaveTask guarda una copia de la tarea
+func (s *MemoryStore) SaveTask(task *types.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.tasks[task.ID]; ok && old.RunID != task.RunID {
+		delete(s.runTasks[old.RunID], task.ID)
+	}
+	if task.RunID != "" {
+		ids, ok := s.runTasks[task.RunID]
+		if !ok {
+			ids = make(map[string]bool)
+			s.runTasks[task.RunID] = ids
+		}
+		ids[task.ID] = true
+	}
+	s.tasks[task.ID] = *task
+	return nil
+}
+
+// GetTask retorna una tarea por ID
+func (s *MemoryStore) GetTask(id string) (*types.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &task, nil
+}
+
+// ListTasks retorna todas las tareas ordenadas por fecha de creación
+func (s *MemoryStore) ListTasks() ([]*types.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tasks := make([]*types.Task, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		t := task
+		tasks = append(tasks, &t)
+	}
+	sortTasks(tasks)
+	return tasks, nil
+}
+
+// ListRunTasks retorna las tareas de una ejecución ordenadas por fecha de creación
+func (s *MemoryStore) ListRunTasks(runID string) ([]*types.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tasks := make([]*types.Task, 0, len(s.runTasks[runID]))
+	for id := range s.runTasks[runID] {
+		t := s.tasks[id]
+		tasks = append(tasks, &t)
+	}
+	sortTasks(tasks)
+	return tasks, nil
+}
+
+// SaveResult guarda una copia del resultado
+func (s *MemoryStore) SaveResult(result *types.TaskResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[result.TaskID] = *result
+	return nil
+}
+
+// GetResult retorna el resultado de una tarea
+func (s *MemoryStore) GetResult(taskID string) (*types.TaskResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result, ok := s.results[taskID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &result, nil
+}
+
+// SaveEvidence guarda la evidencia de una tarea
+func (s *MemoryStore) SaveEvidence(taskID string, evidence []types.Evidence) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evidence[taskID] = append([]types.Evidence{}, evidence...)
+	return nil
+}
+
+// GetEvidence retorna la evidencia de una tarea
+func (s *MemoryStore) GetEvidence(taskID string) ([]types.Evidence, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	evidence, ok := s.evidence[taskID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]types.Evidence{}, evidence...), nil
+}
+
+// AppendDecisions añade decisiones a la memoria
+func (s *MemoryStore) AppendDecisions(decisions ...types.Decision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.decisions = append(s.decisions, decisions...)
+	return nil
+}
+
+// ListDecisions retorna todas las decisiones registradas
+func (s *MemoryStore) ListDecisions() ([]types.Decision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]types.Decision{}, s.decisions...), nil
+}
+
+// AppendJournal añade entradas al journal
+func (s *MemoryStore) AppendJournal(entries ...JournalEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.journal = append(s.journal, entries...)
+	return nil
+}
+
+// ReadJournal retorna todas las entradas del journal en orden
+func (s *MemoryStore) ReadJournal() ([]JournalEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]JournalEntry{}, s.journal...), nil
+}
+
+// SaveRun guarda una copia de la ejecución
+func (s *MemoryStore) SaveRun(run *types.Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[run.ID] = *run
+	return nil
+}
+
+// GetRun retorna una ejecución por ID
+func (s *MemoryStore) GetRun(id string) (*types.Run, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &run, nil
+}
+
+// ListRuns retorna todas las ejecuciones ordenadas por fecha de inicio
+func (s *MemoryStore) ListRuns() ([]*types.Run, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	runs := make([]*types.Run, 0, len(s.runs))
+	for _, run := range s.runs {
+		r := run
+		runs = append(runs, &r)
+	}
+	sortRuns(runs)
+	return runs, nil
+}
+
+// AppendControl añade órdenes de control
+func (s *MemoryStore) AppendControl(requests ...ControlRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.control = append(s.control, requests...)
+	return nil
+}
+
+// ReadControl retorna las órdenes de control a partir de la número offset
+// y el offset desde el que seguir leyendo
+func (s *MemoryStore) ReadControl(offset int64) ([]ControlRequest, int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if offset > int64(len(s.control)) {
+		offset = int64(len(s.control))
+	}
+	return append([]ControlRequest{}, s.control[offset:]...), int64(len(s.control)), nil
+}
+
+// sortTasks ordena tareas por fecha de creación (y por ID en caso de empate)
+func sortTasks(tasks []*types.Task) {
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].ID < tasks[j].ID
+		}
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+}
+
+// sortRuns ordena ejecuciones por fecha de inicio (y por ID en caso de empate)
+func sortRuns(runs []*types.Run) {
+	sort.Slice(runs, func(i, j int) bool {
+		if runs[i].StartedAt.Equal(runs[j].StartedAt) {
+			return runs[i].ID < runs[j].ID
+		}
+		return runs[i].StartedAt.Before(runs[j].StartedAt)
+	})
+}
+
+// SaveDeadLetter guarda una tarea de la dead-letter queue
+func (s *MemoryStore) SaveDeadLetter(letter *types.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dead[letter.Task.ID] = *letter
+	return nil
+}
+
+// GetDeadLetter retorna la entrada de la dead-letter queue de una tarea
+func (s *MemoryStore) GetDeadLetter(taskID string) (*types.DeadLetter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	letter, ok := s.dead[taskID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &letter, nil
+}
+
+// ListDeadLetters retorna la dead-letter queue ordenada por fecha
+func (s *MemoryStore) ListDeadLetters() ([]*types.DeadLetter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	letters := make([]*types.DeadLetter, 0, len(s.dead))
+	for _, letter := range s.dead {
+		l := letter
+		letters = append(letters, &l)
+	}
+	sortDeadLetters(letters)
+	return letters, nil
+}
+
+func sortDeadLetters(letters []*types.DeadLetter) {
+	sort.Slice(letters, func(i, j int) bool {
+		if letters[i].CreatedAt.Equal(letters[j].CreatedAt) {
+			return letters[i].Task.ID < letters[j].Task.ID
+		}
+		return letters[i].CreatedAt.Before(letters[j].CreatedAt)
+	})
+}
+
+// SaveSchedule guarda un objetivo recurrente
+func (s *MemoryStore) SaveSchedule(schedule *types.Schedule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedules[schedule.ID] = *schedule
+	return nil
+}
+
+// GetSchedule retorna un objetivo recurrente
+func (s *MemoryStore) GetSchedule(id string) (*types.Schedule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	schedule, ok := s.schedules[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &schedule, nil
+}
+
+// ListSchedules retorna los objetivos recurrentes ordenados por ID
+func (s *MemoryStore) ListSchedules() ([]*types.Schedule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	schedules := make([]*types.Schedule, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		sc := schedule
+		schedules = append(schedules, &sc)
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].ID < schedules[j].ID })
+	return schedules, nil
+}
+
+// DeleteSchedule elimina un objetivo recurrente
+func (s *MemoryStore) DeleteSchedule(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.schedules[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.schedules, id)
+	return nil
+}
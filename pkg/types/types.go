@@ -27,9 +27,29 @@ const (
 	StateSuccess   TaskState = "success"
 	StateFailed    TaskState = "failed"
 	StateRetrying  TaskState = "retrying"
+	StatePaused    TaskState = "paused"
 	StateCancelled TaskState = "cancelled"
+	StateAbandoned TaskState = "abandoned"
+	StateSkipped   TaskState = "skipped"
+
+	// StateAwaitingApproval es el estado de una tarea (y su ejecución) con un
+	// cambio de alto riesgo que espera la decisión de una persona
+	StateAwaitingApproval TaskState = "awaiting-approval"
+
+	// StateNeedsHuman es el veredicto de una ejecución detenida por agotar su
+	// presupuesto o repetir el mismo fallo
+	StateNeedsHuman TaskState = "needs-human"
 )
 
+// IsTerminal indica si el estado es final (la tarea no volverá a ejecutarse)
+func (s TaskState) IsTerminal() bool {
+	switch s {
+	case StateSuccess, StateFailed, StateCancelled, StateAbandoned, StateSkipped, StateNeedsHuman:
+		return true
+	}
+	return false
+}
+
 // Severity representa la severidad de un hallazgo
 type Severity string
 
@@ -41,6 +61,32 @@ const (
 	SeverityInfo     Severity = "info"
 )
 
+// Priority representa la prioridad de una tarea. Las tareas de mayor
+// prioridad se ejecutan antes; "" equivale a PriorityNormal.
+type Priority string
+
+const (
+	PriorityUrgent Priority = "urgent" // p. ej. un hotfix
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low" // p. ej. optimizaciones nocturnas
+)
+
+// Rank ordena las prioridades (mayor = antes; -1 si es desconocida)
+func (p Priority) Rank() int {
+	switch p {
+	case PriorityLow:
+		return 0
+	case PriorityNormal, "":
+		return 1
+	case PriorityHigh:
+		return 2
+	case PriorityUrgent:
+		return 3
+	}
+	return -1
+}
+
 // Task representa una tarea en el sistema
 type Task struct {
 	ID          string                 `json:"id"`
@@ -55,6 +101,49 @@ type Task struct {
 	RetryCount  int                    `json:"retry_count"`
 	MaxRetries  int                    `json:"max_retries"`
 	ParentID    string                 `json:"parent_id,omitempty"`
+	DependsOn   []string               `json:"depends_on,omitempty"`
+	RunID       string                 `json:"run_id,omitempty"`
+	Stage       string                 `json:"stage,omitempty"`
+	RepoPath    string                 `json:"repo_path,omitempty"` // worktree donde se ejecuta la tarea
+	Priority    Priority               `json:"priority,omitempty"`
+}
+
+// Run agrupa todas las tareas generadas a partir de un mismo objetivo
+type Run struct {
+	ID          string            `json:"id"`
+	Objective   string            `json:"objective"`
+	RootTaskID  string            `json:"root_task_id"`
+	State       TaskState         `json:"state"` // running mientras quedan tareas; después, el veredicto final
+	StartedAt   time.Time         `json:"started_at"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+	Summary     map[TaskState]int `json:"summary,omitempty"` // número de tareas por estado final
+	Reason      string            `json:"reason,omitempty"`  // por qué se detuvo (needs-human)
+}
+
+// Schedule es un objetivo recurrente: cada vez que vence su expresión cron se
+// envía una tarea plan con el objetivo resultante de la plantilla
+type Schedule struct {
+	ID          string                 `json:"id"`
+	Cron        string                 `json:"cron"`
+	Objective   string                 `json:"objective"` // plantilla text/template
+	Constraints map[string]interface{} `json:"constraints,omitempty"`
+	Priority    Priority               `json:"priority,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	LastFire    *time.Time             `json:"last_fire,omitempty"` // último vencimiento atendido, se enviara o no
+	LastRunID   string                 `json:"last_run_id,omitempty"`
+	Skipped     int                    `json:"skipped,omitempty"` // vencimientos omitidos por seguir en curso la ejecución anterior
+}
+
+// RunResult es el resultado agregado de una ejecución terminada
+type RunResult struct {
+	Run     *Run                   `json:"run"`
+	Tasks   []*Task                `json:"tasks"`
+	Results map[string]*TaskResult `json:"results"` // por ID de tarea
+}
+
+// Success indica si el veredicto final de la ejecución es éxito
+func (r *RunResult) Success() bool {
+	return r.Run != nil && r.Run.State == StateSuccess
 }
 
 // TaskResult representa el resultado de una tarea
@@ -78,8 +167,24 @@ type Evidence struct {
 	Description string          `json:"description,omitempty"`
 }
 
+// TextContent codifica texto plano (logs, salidas de comandos) como contenido de evidencia
+func TextContent(text string) json.RawMessage {
+	data, _ := json.Marshal(text)
+	return data
+}
+
+// Text retorna el contenido de la evidencia como texto
+func (e Evidence) Text() string {
+	var text string
+	if err := json.Unmarshal(e.Content, &text); err == nil {
+		return text
+	}
+	return string(e.Content)
+}
+
 // Decision representa una decisión tomada por un agente
 type Decision struct {
+	TaskID     string                 `json:"task_id,omitempty"`
 	Agent      string                 `json:"agent"`
 	Reason     string                 `json:"reason"`
 	Action     string                 `json:"action"`
@@ -88,6 +193,39 @@ type Decision struct {
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// FailureClass es la clasificación de un fallo según los patrones conocidos
+type FailureClass struct {
+	Pattern     string   `json:"pattern"`
+	Category    string   `json:"category"`
+	Severity    Severity `json:"severity"`
+	Matches     []string `json:"matches,omitempty"`
+	Remediation string   `json:"remediation,omitempty"`
+}
+
+// DeadLetterStatus representa el estado de una tarea en la dead-letter queue
+type DeadLetterStatus string
+
+const (
+	DeadLetterOpen     DeadLetterStatus = "open"     // pendiente de revisión
+	DeadLetterRequeued DeadLetterStatus = "requeued" // se volvió a encolar
+	DeadLetterWontFix  DeadLetterStatus = "wont-fix" // se descartó
+)
+
+// DeadLetter es una tarea que falló sin que el pipeline pudiera recuperarla,
+// con todo lo necesario para revisarla
+type DeadLetter struct {
+	Task            *Task            `json:"task"`
+	Result          *TaskResult      `json:"result"`
+	Evidence        []Evidence       `json:"evidence,omitempty"`
+	Classifications []FailureClass   `json:"classifications,omitempty"`
+	Decisions       []Decision       `json:"decisions,omitempty"`
+	Status          DeadLetterStatus `json:"status"`
+	CreatedAt       time.Time        `json:"created_at"`
+	ResolvedAt      *time.Time       `json:"resolved_at,omitempty"`
+	Comment         string           `json:"comment,omitempty"`
+	RequeuedAs      string           `json:"requeued_as,omitempty"` // ID de la tarea que la reemplaza
+}
+
 // AuditFinding representa un hallazgo de auditoría
 type AuditFinding struct {
 	ID          string                 `json:"id"`
@@ -1,14 +1,18 @@
 package workspace
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/nanochip/multi-agent/pkg/types"
 )
 
@@ -19,8 +23,13 @@ type Manager struct {
 	baseBranch    string
 	currentBranch string
 	tmpDir        string
+	parent        *Manager // repositorio principal si este Manager es un worktree aislado
+	readOnly      bool     // abierto con OpenReadOnly
 }
 
+// ErrReadOnly se retorna al intentar cambiar un workspace abierto con OpenReadOnly
+var ErrReadOnly = errors.New("workspace is read-only")
+
 // NewManager crea un nuevo workspace manager
 func NewManager(repoPath string) (*Manager, error) {
 	repo, err := git.PlainOpen(repoPath)
@@ -41,8 +50,108 @@ func NewManager(repoPath string) (*Manager, error) {
 	}, nil
 }
 
+// OpenReadOnly abre el repositorio solo para leerlo, p. ej. para un dry run:
+// no crea .multi-agent/ y los métodos que cambiarían ramas, worktrees o
+// commits retornan ErrReadOnly. RunCommand no se restringe.
+func OpenReadOnly(repoPath string) (*Manager, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repo: %w", err)
+	}
+	return &Manager{
+		repoPath:   repoPath,
+		repo:       repo,
+		baseBranch: "main",
+		tmpDir:     filepath.Join(repoPath, ".multi-agent", "branches"),
+		readOnly:   true,
+	}, nil
+}
+
+// NewWorktree crea (o reutiliza) un git worktree aislado en .multi-agent/branches/<id>
+// sobre la rama agent/<id> y retorna un Manager que opera sobre él. Así cada
+// ejecución puede cambiar de rama y modificar archivos sin afectar a las demás.
+func (m *Manager) NewWorktree(id string) (*Manager, error) {
+	if m.readOnly {
+		return nil, ErrReadOnly
+	}
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("invalid worktree id: %q", id)
+	}
+
+	path, err := filepath.Abs(filepath.Join(m.tmpDir, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve worktree path: %w", err)
+	}
+	branch := "agent/" + id
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		args := []string{"worktree", "add"}
+		if _, err := m.repo.Reference(plumbing.NewBranchReferenceName(branch), false); err == nil {
+			// La rama ya existe (p. ej. al retomar una ejecución): conservar su trabajo
+			args = append(args, path, branch)
+		} else {
+			args = append(args, "-b", branch, path)
+		}
+		if output, err := m.RunCommand("git", args...); err != nil {
+			return nil, fmt.Errorf("failed to create worktree: %w: %s", err, strings.TrimSpace(output))
+		}
+	}
+
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open worktree: %w", err)
+	}
+
+	return &Manager{
+		repoPath:      path,
+		repo:          repo,
+		baseBranch:    m.baseBranch,
+		currentBranch: branch,
+		tmpDir:        m.tmpDir,
+		parent:        m,
+	}, nil
+}
+
+// Remove elimina el worktree aislado; la rama y sus commits se conservan
+func (m *Manager) Remove() error {
+	if m.parent == nil {
+		return fmt.Errorf("not an isolated worktree: %s", m.repoPath)
+	}
+	if output, err := m.parent.RunCommand("git", "worktree", "remove", "--force", m.repoPath); err != nil {
+		return fmt.Errorf("failed to remove worktree: %w: %s", err, strings.TrimSpace(output))
+	}
+	return nil
+}
+
+// RemoveWorktree elimina el worktree aislado <id> si existe (p. ej. uno
+// creado por otro proceso); la rama se conserva
+func (m *Manager) RemoveWorktree(id string) error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
+	path, err := filepath.Abs(filepath.Join(m.tmpDir, id))
+	if err != nil || id == "" || id != filepath.Base(id) {
+		return fmt.Errorf("invalid worktree id: %q", id)
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if output, err := m.RunCommand("git", "worktree", "remove", "--force", path); err != nil {
+		return fmt.Errorf("failed to remove worktree: %w: %s", err, strings.TrimSpace(output))
+	}
+	return nil
+}
+
+// IsWorktree indica si el Manager opera sobre un worktree aislado
+func (m *Manager) IsWorktree() bool {
+	return m.parent != nil
+}
+
 // CheckoutBranch crea y cambia a una nueva rama
 func (m *Manager) CheckoutBranch(branchName string) error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
 	worktree, err := m.repo.Worktree()
 	if err != nil {
 		return fmt.Errorf("failed to get worktree: %w", err)
@@ -57,6 +166,7 @@ func (m *Manager) CheckoutBranch(branchName string) error {
 		if err := worktree.Checkout(&git.CheckoutOptions{
 			Branch: branchRef,
 			Create: false,
+			Keep:   true, // no borrar archivos no rastreados (p. ej. .multi-agent/)
 		}); err != nil {
 			return fmt.Errorf("failed to checkout branch: %w", err)
 		}
@@ -75,6 +185,7 @@ func (m *Manager) CheckoutBranch(branchName string) error {
 		if err := worktree.Checkout(&git.CheckoutOptions{
 			Branch: branchRef,
 			Create: false,
+			Keep:   true, // no borrar archivos no rastreados (p. ej. .multi-agent/)
 		}); err != nil {
 			return fmt.Errorf("failed to checkout new branch: %w", err)
 		}
@@ -110,7 +221,7 @@ func (m *Manager) GetDiff() (string, error) {
 
 	diffOutput := ""
 	for file, fileStatus := range status {
-		diffOutput += fmt.Sprintf("%s %s\n", fileStatus.Staging, file)
+		diffOutput += fmt.Sprintf("%c %s\n", fileStatus.Staging, file)
 	}
 
 	return diffOutput, nil
@@ -118,6 +229,9 @@ func (m *Manager) GetDiff() (string, error) {
 
 // Commit crea un commit con los cambios actuales
 func (m *Manager) Commit(message string) error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
 	worktree, err := m.repo.Worktree()
 	if err != nil {
 		return fmt.Errorf("failed to get worktree: %w", err)
@@ -130,7 +244,7 @@ func (m *Manager) Commit(message string) error {
 
 	// Crear commit
 	_, err = worktree.Commit(message, &git.CommitOptions{
-		Author: &git.Signature{
+		Author: &object.Signature{
 			Name:  "Multi-Agent System",
 			Email: "agent@nanochip.dev",
 			When:  time.Now(),
@@ -146,10 +260,20 @@ func (m *Manager) Commit(message string) error {
 
 // RunCommand ejecuta un comando en el workspace
 func (m *Manager) RunCommand(cmd string, args ...string) (string, error) {
-	command := exec.Command(cmd, args...)
+	return m.RunCommandContext(context.Background(), cmd, args...)
+}
+
+// RunCommandContext ejecuta un comando en el workspace. Si ctx se cancela,
+// el comando y todos sus subprocesos (p. ej. los binarios de go test) se terminan.
+func (m *Manager) RunCommandContext(ctx context.Context, cmd string, args ...string) (string, error) {
+	command := exec.CommandContext(ctx, cmd, args...)
 	command.Dir = m.repoPath
+	killProcessGroup(command)
 
 	output, err := command.CombinedOutput()
+	if ctx.Err() != nil {
+		return string(output), fmt.Errorf("command %s interrupted: %w", cmd, ctx.Err())
+	}
 	if err != nil {
 		return string(output), fmt.Errorf("command failed: %w", err)
 	}
@@ -159,6 +283,16 @@ func (m *Manager) RunCommand(cmd string, args ...string) (string, error) {
 
 // Cleanup limpia recursos temporales
 func (m *Manager) Cleanup() error {
+	if m.readOnly {
+		return ErrReadOnly
+	}
+	if m.parent != nil {
+		return m.Remove()
+	}
+
+	// Olvidar worktrees aislados cuyo directorio ya no existe
+	m.RunCommand("git", "worktree", "prune")
+
 	// Volver a la rama base
 	worktree, err := m.repo.Worktree()
 	if err != nil {
@@ -167,6 +301,7 @@ func (m *Manager) Cleanup() error {
 
 	if err := worktree.Checkout(&git.CheckoutOptions{
 		Branch: plumbing.NewBranchReferenceName(m.baseBranch),
+		Keep:   true,
 	}); err != nil {
 		return err
 	}
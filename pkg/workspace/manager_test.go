@@ -0,0 +1,155 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// testRepo crea un repositorio con un commit y retorna su Manager
+func testRepo(t *testing.T) *Manager {
+	t.Helper()
+	repo := t.TempDir()
+	if _, err := git.PlainInit(repo, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "README.md"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Commit("initial commit"); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestWorktreeRoundTrip(t *testing.T) {
+	m := testRepo(t)
+
+	wt, err := m.NewWorktree("run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !wt.IsWorktree() || m.IsWorktree() || wt.GetCurrentBranch() != "agent/run-1" {
+		t.Errorf("worktree on %q (isolated %v), want an isolated agent/run-1", wt.GetCurrentBranch(), wt.IsWorktree())
+	}
+	if want := filepath.Join(m.GetRepoPath(), ".multi-agent", "branches", "run-1"); wt.GetRepoPath() != want {
+		t.Errorf("worktree path = %s, want %s", wt.GetRepoPath(), want)
+	}
+	if _, err := os.Stat(filepath.Join(wt.GetRepoPath(), "README.md")); err != nil {
+		t.Errorf("worktree does not have the repository files: %v", err)
+	}
+
+	// Un commit en el worktree no cambia el repositorio principal
+	if err := os.WriteFile(filepath.Join(wt.GetRepoPath(), "change.txt"), []byte("run-1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.Commit("work on run-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(m.GetRepoPath(), "change.txt")); !os.IsNotExist(err) {
+		t.Errorf("change.txt leaked into the main worktree: %v", err)
+	}
+
+	// Al reabrir el mismo id se reutiliza el worktree existente
+	again, err := m.NewWorktree("run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.GetRepoPath() != wt.GetRepoPath() {
+		t.Errorf("reopened worktree at %s, want %s", again.GetRepoPath(), wt.GetRepoPath())
+	}
+
+	if err := wt.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(wt.GetRepoPath()); !os.IsNotExist(err) {
+		t.Errorf("worktree directory still exists after Remove: %v", err)
+	}
+	if output, _ := m.RunCommand("git", "worktree", "list"); strings.Contains(output, "run-1") {
+		t.Errorf("git worktree list still shows run-1:\n%s", output)
+	}
+
+	// La rama y su commit se conservan, y al recrear el worktree se retoman
+	repo, err := git.PlainOpen(m.GetRepoPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Reference(plumbing.NewBranchReferenceName("agent/run-1"), false); err != nil {
+		t.Fatalf("branch agent/run-1 was removed with its worktree: %v", err)
+	}
+	resumed, err := m.NewWorktree("run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(resumed.GetRepoPath(), "change.txt")); err != nil {
+		t.Errorf("resumed worktree lost the branch commit: %v", err)
+	}
+
+	// RemoveWorktree elimina por id y no falla si ya no existe
+	if err := m.RemoveWorktree("run-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(resumed.GetRepoPath()); !os.IsNotExist(err) {
+		t.Errorf("worktree directory still exists after RemoveWorktree: %v", err)
+	}
+	if err := m.RemoveWorktree("run-1"); err != nil {
+		t.Errorf("RemoveWorktree() of a removed worktree = %v, want nil", err)
+	}
+}
+
+func TestWorktreeRejectsInvalidIDs(t *testing.T) {
+	m := testRepo(t)
+	for _, id := range []string{"", "..", ".hidden", "a/b", "../escape"} {
+		if _, err := m.NewWorktree(id); err == nil || !strings.Contains(err.Error(), "invalid worktree id") {
+			t.Errorf("NewWorktree(%q) = %v, want an invalid id error", id, err)
+		}
+	}
+	if err := m.Remove(); err == nil {
+		t.Error("Remove() on the main repository should fail")
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	repo := t.TempDir()
+	if _, err := git.PlainInit(repo, false); err != nil {
+		t.Fatal(err)
+	}
+	m, err := OpenReadOnly(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".multi-agent")); !os.IsNotExist(err) {
+		t.Errorf("OpenReadOnly() created .multi-agent: %v", err)
+	}
+
+	// Nada que cambie ramas, worktrees o commits está permitido
+	if _, err := m.NewWorktree("run-1"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("NewWorktree() = %v, want ErrReadOnly", err)
+	}
+	for name, fn := range map[string]func() error{
+		"RemoveWorktree": func() error { return m.RemoveWorktree("run-1") },
+		"CheckoutBranch": func() error { return m.CheckoutBranch("agent/run-1") },
+		"Commit":         func() error { return m.Commit("change") },
+		"Cleanup":        m.Cleanup,
+	} {
+		if err := fn(); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s() = %v, want ErrReadOnly", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".multi-agent")); !os.IsNotExist(err) {
+		t.Errorf("read-only workspace created .multi-agent: %v", err)
+	}
+
+	if _, err := OpenReadOnly(t.TempDir()); err == nil {
+		t.Error("OpenReadOnly() of a directory without a repository should fail")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package workspace
+
+import (
+	"os/exec"
+	"time"
+)
+
+// killProcessGroup solo puede terminar el proceso principal en esta plataforma
+func killProcessGroup(command *exec.Cmd) {
+	command.WaitDelay = 5 * time.Second
+}
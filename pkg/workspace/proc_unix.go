@@ -0,0 +1,20 @@
+//go:build unix
+
+package workspace
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// killProcessGroup ejecuta el comando en su propio grupo de procesos para
+// que al cancelarlo se terminen también sus hijos
+func killProcessGroup(command *exec.Cmd) {
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	command.Cancel = func() error {
+		return syscall.Kill(-command.Process.Pid, syscall.SIGKILL)
+	}
+	// No esperar indefinidamente a hijos que mantengan abierta la salida
+	command.WaitDelay = 5 * time.Second
+}
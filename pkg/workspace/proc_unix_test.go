@@ -0,0 +1,36 @@
+//go:build unix
+
+package workspace
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestRunCommandKillsProcessGroup(t *testing.T) {
+	repo := t.TempDir()
+	if _, err := git.PlainInit(repo, false); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// El hijo en segundo plano mantiene abierta la salida: si solo se
+	// terminara sh, el comando esperaría a WaitDelay
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = m.RunCommandContext(ctx, "sh", "-c", "sleep 30 & wait")
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("command returned after %v, want its process group killed on cancel", elapsed.Round(time.Millisecond))
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RunCommandContext() = %v, want an interrupted command", err)
+	}
+}